@@ -0,0 +1,131 @@
+package dtos
+
+import (
+	"errors"
+	"sight-reading/validations"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+)
+
+// Exercise is a piece in a school's library, the MusicXML itself is served
+// separately from /exercises/:id/xml
+type Exercise struct {
+	ID         int            `db:"id"          json:"id"`
+	SchoolID   int16          `db:"school_id"   json:"school_id"`
+	UploadedBy *int           `db:"uploaded_by" json:"uploaded_by"`
+	Title      string         `db:"title"       json:"title"`
+	Composer   string         `db:"composer"    json:"composer"`
+	Instrument string         `db:"instrument"  json:"instrument"`
+	Key        string         `db:"key"         json:"key"`
+	Difficulty float64        `db:"difficulty"  json:"difficulty"`
+	Tags       pq.StringArray `db:"tags"        json:"tags"`
+	Shared     bool           `db:"shared"      json:"shared"`
+	CreatedAt  time.Time      `db:"created_at"  json:"created_at"`
+}
+
+// ExerciseMetadata is what a teacher sets on an upload or edit, nil fields
+// are left alone on edit. On upload title, composer and instrument default
+// to what the MusicXML says
+type ExerciseMetadata struct {
+	Title      *string   `json:"title"      validate:"omitempty,min=1,len255"`
+	Composer   *string   `json:"composer"   validate:"omitempty,len255"`
+	Instrument *string   `json:"instrument" validate:"omitempty,len255"`
+	Tags       *[]string `json:"tags"       validate:"omitempty,max=20,dive,min=1,max=32"`
+	Shared     *bool     `json:"shared"`
+}
+
+// AssignmentRequest hands an exercise to students
+type AssignmentRequest struct {
+	StudentIDs []int `json:"student_ids" validate:"required,min=1,max=200,dive,min=1"`
+}
+
+// Assignment is an exercise handed to the signed in student
+type Assignment struct {
+	Exercise
+	AssignedBy *int      `db:"assigned_by" json:"assigned_by"`
+	AssignedAt time.Time `db:"assigned_at" json:"assigned_at"`
+}
+
+func (metadata *ExerciseMetadata) ValidateExerciseMetadata() error {
+	validate := validator.New()
+	validate.RegisterValidation("len255", validations.VarChar255Length)
+
+	if metadata.Tags != nil {
+		tags := NormalizeTags(*metadata.Tags)
+		metadata.Tags = &tags
+	}
+
+	err := validate.Struct(metadata)
+	if err != nil {
+		var errorMessage []string
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range errs {
+				switch fieldErr.StructField() {
+
+				case "Title":
+					errorMessage = append(errorMessage, "Title: must be between 1 and 255 characters")
+
+				case "Composer":
+					errorMessage = append(errorMessage, "Composer: must be shorter than 255 characters")
+
+				case "Instrument":
+					errorMessage = append(errorMessage, "Instrument: must be shorter than 255 characters")
+
+				case "Tags":
+					errorMessage = append(errorMessage, "Tags: at most 20 tags")
+
+				default:
+					// dive errors are reported on the element, Tags[3]
+					if strings.HasPrefix(fieldErr.Field(), "Tags[") {
+						errorMessage = append(errorMessage, "Tags: each tag must be 1 to 32 characters")
+					}
+				}
+			}
+		}
+		return errors.New(strings.Join(errorMessage, ", "))
+	}
+	return nil
+}
+
+func (req *AssignmentRequest) ValidateAssignment() error {
+	validate := validator.New()
+
+	if err := validate.Struct(req); err != nil {
+		return errors.New("StudentIDs: between 1 and 200 student ids are required")
+	}
+	return nil
+}
+
+// NormalizeTags lowercases, trims and dedupes tags, keeping their order. A
+// single form value is split on commas so "scales, major" is two tags
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, value := range tags {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// UniqueInts drops repeated ids, keeping the first of each
+func UniqueInts(ids []int) []int {
+	seen := map[int]bool{}
+	unique := []int{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,84 @@
+package dtos
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Instrument is how parts for an instrument are written. Transpose is the
+// interval from written to sounding pitch in the MusicXML convention, a B
+// flat clarinet sounds a major second below what it reads ("-M2"). The range
+// is in written pitches
+type Instrument struct {
+	ID        int    `db:"id"         json:"id"`
+	Slug      string `db:"slug"       json:"slug"`
+	Name      string `db:"name"       json:"name"`
+	Family    string `db:"family"     json:"family"`
+	Clef      string `db:"clef"       json:"clef"`
+	Transpose string `db:"transpose"  json:"transpose"`
+	RangeLow  string `db:"range_low"  json:"range_low"`
+	RangeHigh string `db:"range_high" json:"range_high"`
+	// Program is the General MIDI program, counted from 0
+	Program int `db:"program" json:"program"`
+}
+
+// UserInstrument is an instrument a user plays
+type UserInstrument struct {
+	Instrument
+	Primary bool `db:"is_primary" json:"primary"`
+}
+
+type InstrumentChoice struct {
+	Slug    string `json:"slug"    validate:"required,max=32"`
+	Primary bool   `json:"primary"`
+}
+
+// SetInstrumentsRequest replaces every instrument of a user
+type SetInstrumentsRequest struct {
+	Instruments []InstrumentChoice `json:"instruments" validate:"max=10,dive"`
+}
+
+func (req *SetInstrumentsRequest) ValidateSetInstruments() error {
+	validate := validator.New()
+
+	var errorMessage []string
+	if err := validate.Struct(req); err != nil {
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range errs {
+				switch fieldErr.StructField() {
+				case "Instruments":
+					errorMessage = append(errorMessage, "Instruments: at most 10 instruments")
+				case "Slug":
+					errorMessage = append(errorMessage, "Slug: every instrument needs a slug")
+				}
+			}
+		}
+	}
+
+	primaries := 0
+	seen := map[string]bool{}
+	for _, choice := range req.Instruments {
+		if choice.Primary {
+			primaries++
+		}
+		if seen[choice.Slug] {
+			errorMessage = append(errorMessage, "Instruments: "+choice.Slug+" is listed twice")
+		}
+		seen[choice.Slug] = true
+	}
+	if primaries > 1 {
+		errorMessage = append(errorMessage, "Instruments: only one instrument can be primary")
+	}
+	// music routes default to the primary one, with several there is no
+	// telling which
+	if primaries == 0 && len(req.Instruments) > 1 {
+		errorMessage = append(errorMessage, "Instruments: mark one instrument primary")
+	}
+
+	if len(errorMessage) > 0 {
+		return errors.New(strings.Join(errorMessage, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package dtos
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// NoteGameRequest is what the note game posts to /note-game. Only scale and
+// octave go to the python service, the rest are for the go generator
+type NoteGameRequest struct {
+	Scale  string `json:"scale"  validate:"required"`
+	Octave string `json:"octave" validate:"required"`
+
+	// Octaves is how many octaves up from Octave questions come from
+	Octaves int    `json:"octaves,omitempty"     validate:"omitempty,min=1,max=4"`
+	Clef    string `json:"clef,omitempty"        validate:"omitempty,oneof=treble bass alto tenor"`
+	// Accidentals asks for notes raised or lowered out of the key
+	Accidentals bool `json:"accidentals,omitempty"`
+	// MaxLedgerLines leaves out notes needing more ledger lines, nil is no
+	// limit
+	MaxLedgerLines *int `json:"maxLedgerLines,omitempty" validate:"omitempty,min=0,max=6"`
+	// RangeLow and RangeHigh bound the written pitches, "B-3", they default
+	// to the range of the student's instrument
+	RangeLow  string `json:"rangeLow,omitempty"  validate:"omitempty,max=8"`
+	RangeHigh string `json:"rangeHigh,omitempty" validate:"omitempty,max=8"`
+}
+
+// NeedsLocalGenerator reports whether the request uses options the python
+// service does not know about
+func (req *NoteGameRequest) NeedsLocalGenerator() bool {
+	return req.Octaves > 1 || req.Clef != "" || req.Accidentals || req.MaxLedgerLines != nil ||
+		req.RangeLow != "" || req.RangeHigh != ""
+}
+
+// NoteGame mirrors NoteGameDTO in frontend/src/models/models.tsx
+type NoteGame struct {
+	GeneratedXML string `json:"generatedXml"`
+	NoteName     string `json:"noteName"`
+	NoteOctave   string `json:"noteOctave"`
+}
+
+// MaryRequest is what /mary expects, tonic is a pitch name like "C" or "F#"
+type MaryRequest struct {
+	Tonic  string `json:"tonic"  validate:"required"`
+	Octave string `json:"octave" validate:"required"`
+}
+
+// RandomRequest is what /random expects, tonic includes the octave ("C4")
+type RandomRequest struct {
+	Tonic      string `json:"tonic"      validate:"required"`
+	RhythmType int    `json:"rhythmType" validate:"required"`
+	Rhythm     string `json:"rhythm"     validate:"required"`
+}
+
+func (req *NoteGameRequest) Validate() error {
+	return validateMusicRequest(req)
+}
+
+func (req *MaryRequest) Validate() error {
+	return validateMusicRequest(req)
+}
+
+func (req *RandomRequest) Validate() error {
+	return validateMusicRequest(req)
+}
+
+// the music requests share their few tags, so the messages are built from
+// the tag instead of listed per field
+func validateMusicRequest(req any) error {
+	validate := validator.New()
+
+	err := validate.Struct(req)
+	if err != nil {
+		var errorMessage []string
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range errs {
+				switch fieldErr.Tag() {
+				case "required":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": is required")
+				case "oneof":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be one of "+fieldErr.Param())
+				case "min":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be at least "+fieldErr.Param())
+				case "max":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be at most "+fieldErr.Param())
+				}
+			}
+		}
+		return errors.New(strings.Join(errorMessage, ", "))
+	}
+	return nil
+}
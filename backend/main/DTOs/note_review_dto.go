@@ -0,0 +1,98 @@
+package dtos
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"sight-reading/repetition"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// NoteAnswer is one question of a note game session. Pitch is the written
+// note asked, "F#4", Answer the note name the student chose, "F#", empty
+// when they ran out of time
+type NoteAnswer struct {
+	Pitch      string `json:"pitch"       validate:"required,max=8"`
+	Answer     string `json:"answer"      validate:"max=8"`
+	ResponseMS int    `json:"response_ms" validate:"min=0,max=600000"`
+}
+
+// NoteAnswersRequest is what the note game posts when a session ends. Scale
+// is the key the session was in, like the note game request, it rates key
+// signature reading
+type NoteAnswersRequest struct {
+	Clef    string       `json:"clef,omitempty"  validate:"omitempty,oneof=treble bass alto tenor"`
+	Scale   string       `json:"scale,omitempty" validate:"omitempty,max=8"`
+	Answers []NoteAnswer `json:"answers"         validate:"required,min=1,max=200,dive"`
+}
+
+// NoteReview is the schedule of one note for a student, see the repetition
+// package
+type NoteReview struct {
+	UserID int    `db:"user_id" json:"user_id"`
+	Pitch  string `db:"pitch"   json:"pitch"`
+	Clef   string `db:"clef"    json:"clef"`
+	repetition.Card
+	Reviews    int       `db:"reviews"     json:"reviews"`
+	ReviewedAt time.Time `db:"reviewed_at" json:"reviewed_at"`
+}
+
+// NoteSessionRequest asks for the next questions of the note game, the
+// note game options choose the notes the questions come from
+type NoteSessionRequest struct {
+	NoteGameRequest
+	// Count is how many questions, 10 when left out
+	Count int `json:"count,omitempty" validate:"omitempty,min=1,max=50"`
+}
+
+// NoteQuestion is a note game question and why it was picked: "due" for a
+// note the student is due to review, "new" for one they were never asked and
+// "ahead" for one reviewed before it is due
+type NoteQuestion struct {
+	NoteGame
+	Pitch  string     `json:"pitch"`
+	Reason string     `json:"reason"`
+	Due    *time.Time `json:"due,omitempty"`
+}
+
+type NoteSession struct {
+	Clef      string         `json:"clef"`
+	Questions []NoteQuestion `json:"questions"`
+}
+
+func (req *NoteSessionRequest) Validate() error {
+	return validateMusicRequest(req)
+}
+
+func (req *NoteAnswersRequest) ValidateNoteAnswers() error {
+	validate := validator.New()
+
+	err := validate.Struct(req)
+	if err != nil {
+		var errorMessage []string
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range errs {
+				switch fieldErr.StructField() {
+				case "Clef":
+					errorMessage = append(errorMessage, "Clef: must be one of treble bass alto tenor")
+				case "Scale":
+					errorMessage = append(errorMessage, "Scale: must be a pitch name like E-")
+				case "Answers":
+					errorMessage = append(errorMessage, "Answers: between 1 and 200 answers are required")
+				case "Pitch":
+					errorMessage = append(errorMessage, "Pitch: every answer needs the pitch asked, like F#4")
+				case "Answer":
+					errorMessage = append(errorMessage, "Answer: must be a note name like F#")
+				case "ResponseMS":
+					errorMessage = append(errorMessage, "ResponseMS: must be between 0 and 600000")
+				}
+			}
+		}
+		if len(errorMessage) > 0 {
+			return errors.New(strings.Join(errorMessage, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Performance is a graded recording of a student playing an exercise. The
+// scores are 0 to 1, Report is the whole grading with the missed, extra and
+// wrong notes
+type Performance struct {
+	ID             int             `db:"id"              json:"id"`
+	UserID         int             `db:"user_id"         json:"user_id"`
+	ExerciseID     int             `db:"exercise_id"     json:"exercise_id"`
+	Score          float64         `db:"score"           json:"score"`
+	PitchAccuracy  float64         `db:"pitch_accuracy"  json:"pitch_accuracy"`
+	RhythmAccuracy float64         `db:"rhythm_accuracy" json:"rhythm_accuracy"`
+	Tempo          float64         `db:"tempo"           json:"tempo"`
+	Report         json.RawMessage `db:"report"          json:"report"`
+	CreatedAt      time.Time       `db:"created_at"      json:"created_at"`
+}
@@ -0,0 +1,84 @@
+package dtos
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// PracticeGoal is a student's daily goal, either or both of the minutes and
+// questions are set
+type PracticeGoal struct {
+	UserID         int       `db:"user_id"         json:"user_id"`
+	DailyMinutes   *int      `db:"daily_minutes"   json:"daily_minutes"`
+	DailyQuestions *int      `db:"daily_questions" json:"daily_questions"`
+	SetBy          *int      `db:"set_by"          json:"set_by"`
+	UpdatedAt      time.Time `db:"updated_at"      json:"updated_at"`
+}
+
+type GoalRequest struct {
+	DailyMinutes   *int `json:"daily_minutes"   validate:"omitempty,min=1,max=600"`
+	DailyQuestions *int `json:"daily_questions" validate:"omitempty,min=1,max=2000"`
+}
+
+// Streak is how many days in a row a student practiced, in their school's
+// timezone. Today not practiced yet does not break the current streak, it
+// ends the day after the last practice. Dates are "2006-01-02"
+type Streak struct {
+	Current        int     `json:"current"`
+	Longest        int     `json:"longest"`
+	LastPracticed  *string `json:"last_practiced"`
+	PracticedToday bool    `json:"practiced_today"`
+	Today          string  `json:"today"`
+	Timezone       string  `json:"timezone"`
+}
+
+// DayProgress is one day of practice against the goal. Percent is of the
+// goal furthest from met, capped at 100
+type DayProgress struct {
+	Date      string  `json:"date"`
+	Minutes   float64 `json:"minutes"`
+	Questions int     `json:"questions"`
+	Percent   float64 `json:"percent"`
+	Met       bool    `json:"met"`
+}
+
+// GoalProgress is the latest days of practice, oldest first and today
+// last. Goal is nil when none is set
+type GoalProgress struct {
+	Goal     *PracticeGoal `json:"goal"`
+	Days     []DayProgress `json:"days"`
+	Timezone string        `json:"timezone"`
+}
+
+type TimezoneRequest struct {
+	Timezone string `json:"timezone" validate:"required,max=64"`
+}
+
+func (req *GoalRequest) ValidateGoal() error {
+	validate := validator.New()
+
+	var errorMessage []string
+	if err := validate.Struct(req); err != nil {
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range errs {
+				switch fieldErr.StructField() {
+				case "DailyMinutes":
+					errorMessage = append(errorMessage, "DailyMinutes: must be between 1 and 600")
+				case "DailyQuestions":
+					errorMessage = append(errorMessage, "DailyQuestions: must be between 1 and 2000")
+				}
+			}
+		}
+	}
+	if req.DailyMinutes == nil && req.DailyQuestions == nil {
+		errorMessage = append(errorMessage, "a goal needs daily_minutes, daily_questions or both")
+	}
+
+	if len(errorMessage) > 0 {
+		return errors.New(strings.Join(errorMessage, ", "))
+	}
+	return nil
+}
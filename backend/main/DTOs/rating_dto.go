@@ -0,0 +1,25 @@
+package dtos
+
+import "time"
+
+// SkillRating is a student's rating on one skill, see the rating package.
+// A skill never rated is at the initial rating with no sessions
+type SkillRating struct {
+	Dimension string     `db:"dimension"  json:"dimension"`
+	Rating    float64    `db:"rating"     json:"rating"`
+	Sessions  int        `db:"sessions"   json:"sessions"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// SkillRatingPoint is a rating after a session, for charting. Source is
+// "note-game" or "performance" and SourceID the first answer of the session
+// or the performance
+type SkillRatingPoint struct {
+	Dimension    string    `db:"dimension"    json:"dimension"`
+	Rating       float64   `db:"rating"       json:"rating"`
+	Delta        float64   `db:"delta"        json:"delta"`
+	Observations int       `db:"observations" json:"observations"`
+	Source       string    `db:"source"       json:"source"`
+	SourceID     int64     `db:"source_id"    json:"source_id"`
+	RatedAt      time.Time `db:"rated_at"     json:"rated_at"`
+}
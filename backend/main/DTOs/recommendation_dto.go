@@ -0,0 +1,99 @@
+package dtos
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// RecommendationRules is how a class is moved up and down, see the
+// recommend package. Accuracies are 0 to 1, lengths in seconds
+type RecommendationRules struct {
+	// Sessions is how many of the latest note game sessions are looked at,
+	// nothing changes with fewer than MinSessions of them
+	Sessions    int `json:"sessions"     validate:"min=1,max=50"`
+	MinSessions int `json:"min_sessions" validate:"min=1,max=50,ltefield=Sessions"`
+	// MinSeconds leaves out sessions too short to say anything
+	MinSeconds int `json:"min_seconds" validate:"min=0,max=3600"`
+	// at PromoteAccuracy a student moves up, at DemoteAccuracy or under
+	// they move down
+	PromoteAccuracy float64 `json:"promote_accuracy" validate:"min=0,max=1,gtfield=DemoteAccuracy"`
+	DemoteAccuracy  float64 `json:"demote_accuracy"  validate:"min=0,max=1"`
+	// TargetNPM is the reading speed at which an accurate student gets a
+	// harder key instead of a faster tempo
+	TargetNPM int `json:"target_npm" validate:"min=1,max=300"`
+	// TargetSeconds is the session length under which an accurate student
+	// gets more notes
+	TargetSeconds int `json:"target_seconds" validate:"min=0,max=3600"`
+
+	NoteCountStep int `json:"note_count_step" validate:"min=1,max=50"`
+	MaxNoteCount  int `json:"max_note_count"  validate:"min=1,max=200"`
+	TempoStep     int `json:"tempo_step"      validate:"min=1,max=60"`
+	MinTempo      int `json:"min_tempo"       validate:"min=20,max=300"`
+	MaxTempo      int `json:"max_tempo"       validate:"min=20,max=300,gtefield=MinTempo"`
+	MaxOctaves    int `json:"max_octaves"     validate:"min=1,max=4"`
+}
+
+// PracticeSettings are the note game settings a student practices with.
+// Scale is the tonic of the major key, "B-", Octaves how many octaves up
+// from Octave the notes come from and Tempo in beats per minute
+type PracticeSettings struct {
+	Scale     string `json:"scale"`
+	Octave    int    `json:"octave"`
+	Octaves   int    `json:"octaves"`
+	NoteCount int    `json:"note_count"`
+	Tempo     int    `json:"tempo"`
+}
+
+// SettingChange is what is recommended for one setting and why. Change is
+// "up", "down" or "keep"
+type SettingChange struct {
+	Setting string `json:"setting"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Change  string `json:"change"`
+	Reason  string `json:"reason"`
+}
+
+// Recommendation is the next settings of a student with the sessions they
+// were based on. RulesTeacherID is the teacher whose rule set applied, nil
+// for the defaults
+type Recommendation struct {
+	Current        PracticeSettings    `json:"current"`
+	Recommended    PracticeSettings    `json:"recommended"`
+	Changes        []SettingChange     `json:"changes"`
+	Sessions       int                 `json:"sessions"`
+	Accuracy       float64             `json:"accuracy"`
+	NPM            float64             `json:"npm"`
+	SessionSeconds float64             `json:"session_seconds"`
+	Rules          RecommendationRules `json:"rules"`
+	RulesTeacherID *int                `json:"rules_teacher_id"`
+}
+
+func (rules *RecommendationRules) ValidateRecommendationRules() error {
+	validate := validator.New()
+
+	err := validate.Struct(rules)
+	if err != nil {
+		var errorMessage []string
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range errs {
+				switch fieldErr.Tag() {
+				case "min":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be at least "+fieldErr.Param())
+				case "max":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be at most "+fieldErr.Param())
+				case "ltefield":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be at most "+fieldErr.Param())
+				case "gtfield":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be above "+fieldErr.Param())
+				case "gtefield":
+					errorMessage = append(errorMessage, fieldErr.StructField()+": must be at least "+fieldErr.Param())
+				}
+			}
+		}
+		return errors.New(strings.Join(errorMessage, ", "))
+	}
+	return nil
+}
@@ -20,6 +20,12 @@ type User struct {
 	SchoolID    int16          `db:"school_id" json:"school_id" validate:"required,number"`
 }
 
+// CreatedUser is the response body of POST /user
+type CreatedUser struct {
+	Body   User   `json:"body"`
+	Status string `json:"status"`
+}
+
 type Role string
 
 const (
@@ -66,7 +72,7 @@ func (user *User) ValidateUser() error {
 					case "required":
 						errorMessage = append(errorMessage, "Role: required when making a user")
 					case "role":
-						errorMessage = append(errorMessage, "Role: must be either STUDENT, TEACHER, or ADMIN")
+						errorMessage = append(errorMessage, "Role: must be either STUDENT, TEACHER, PARENT, or ADMIN")
 					}
 
 				case "Email":
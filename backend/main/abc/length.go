@@ -0,0 +1,134 @@
+package abc
+
+import "strconv"
+
+// frac is a note length as a fraction of a whole note, always reduced
+type frac struct {
+	num, den int
+}
+
+func newFrac(num, den int) frac {
+	if den < 0 {
+		num, den = -num, -den
+	}
+	divisor := gcd(abs(num), den)
+	if divisor == 0 {
+		return frac{0, 1}
+	}
+	return frac{num / divisor, den / divisor}
+}
+
+func (f frac) mul(g frac) frac {
+	return newFrac(f.num*g.num, f.den*g.den)
+}
+
+func (f frac) add(g frac) frac {
+	return newFrac(f.num*g.den+g.num*f.den, f.den*g.den)
+}
+
+func (f frac) sub(g frac) frac {
+	return f.add(frac{-g.num, g.den})
+}
+
+func (f frac) less(g frac) bool {
+	return f.num*g.den < g.num*f.den
+}
+
+func (f frac) positive() bool {
+	return f.num > 0
+}
+
+// String is the ABC length suffix of f in units of the unit note length:
+// "" for 1, "3" for 3, "/" for a half, "3/2"
+func (f frac) String() string {
+	switch {
+	case f.den == 1 && f.num == 1:
+		return ""
+	case f.den == 1:
+		return strconv.Itoa(f.num)
+	case f.num == 1 && f.den == 2:
+		return "/"
+	case f.num == 1:
+		return "/" + strconv.Itoa(f.den)
+	}
+	return strconv.Itoa(f.num) + "/" + strconv.Itoa(f.den)
+}
+
+var noteTypes = []struct {
+	name   string
+	length frac
+}{
+	{"breve", frac{2, 1}},
+	{"whole", frac{1, 1}},
+	{"half", frac{1, 2}},
+	{"quarter", frac{1, 4}},
+	{"eighth", frac{1, 8}},
+	{"16th", frac{1, 16}},
+	{"32nd", frac{1, 32}},
+	{"64th", frac{1, 64}},
+}
+
+// noteType is the MusicXML type and dots that show length, up to two dots
+func noteType(length frac) (string, int, bool) {
+	for _, noteType := range noteTypes {
+		dotted, dot := noteType.length, noteType.length
+		for dots := 0; dots <= 2; dots++ {
+			if dotted == length {
+				return noteType.name, dots, true
+			}
+			dot = dot.mul(frac{1, 2})
+			dotted = dotted.add(dot)
+		}
+	}
+	return "", 0, false
+}
+
+// maxPieces bounds the tied notes a length splits into
+const maxPieces = 16
+
+// split breaks a length no single note shows, like five eighths, into the
+// longest notes that do, to be tied together. It is not ok past maxPieces
+func split(length frac) ([]frac, bool) {
+	var pieces []frac
+	for length.positive() {
+		if len(pieces) == maxPieces {
+			return nil, false
+		}
+		if _, _, ok := noteType(length); ok {
+			return append(pieces, length), true
+		}
+
+		piece := frac{}
+		for _, noteType := range noteTypes {
+			if !length.less(noteType.length) {
+				piece = noteType.length
+				break
+			}
+		}
+		if !piece.positive() {
+			// shorter than a 64th, shown as one anyway
+			return append(pieces, length), true
+		}
+		pieces = append(pieces, piece)
+		length = length.sub(piece)
+	}
+	return pieces, true
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func lcm(a, b int) int {
+	return a / gcd(a, b) * b
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,1009 @@
+// Package abc reads and writes ABC notation, the text format most free folk
+// tune and sight reading collections use
+// (https://abcnotation.com/wiki/abc:standard:v2.1). A tune is read into the
+// musicxml model and written back from it. One tune with one voice is
+// supported, which covers the single line exercises the library holds.
+// Decorations, chord symbols, lyrics and slurs are skipped and first and
+// second endings are read as written, one after the other
+package abc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+)
+
+// maxDivisions bounds the divisions a tune needs, past it the note lengths
+// are not anything a student reads
+const maxDivisions = 10080
+
+// maxMultiplier bounds the numbers of a length, A64 or A/64, and of a
+// tuplet, and maxMeasureRests the measures of a Z rest. Past them a few bytes
+// of tune would be more music than any exercise
+const (
+	maxMultiplier   = 64
+	maxMeasureRests = 100
+)
+
+var ErrNoNotes = errors.New("the tune has no notes")
+
+// ParseError is a problem on a line of the tune, counted from 1
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+type written struct {
+	pitch theory.Pitch
+	// accidental is the MusicXML accidental when the tune writes one
+	accidental string
+}
+
+// event is a note, chord or rest of the tune, its length in whole notes
+type event struct {
+	rest        bool
+	measureRest bool
+	notes       []written
+	length      frac
+	tie         bool
+	// actual notes in the time of normal, zero outside a tuplet
+	actual, normal int
+}
+
+type measure struct {
+	key    *musicxml.Key
+	time   *musicxml.Time
+	clef   *musicxml.Clef
+	events []*event
+	// repeats and the style of the bar line closing the measure
+	forward, backward bool
+	style             string
+}
+
+type tuplet struct {
+	actual, normal, left int
+}
+
+type parser struct {
+	line int
+
+	title, composer string
+	tempo           float64
+	unit            frac
+	// meter is the length of a measure, zero without a meter
+	meter  frac
+	time   *musicxml.Time
+	key    musicxml.Key
+	clef   *musicxml.Clef
+	inBody bool
+	voice  string
+	// header is what the tune starts with, the body can change the key,
+	// meter and clef later on
+	header measure
+	// headerMeter is the measure length the header set
+	headerMeter frac
+
+	measures []*measure
+	current  *measure
+	// accidentals written so far in the bar, by step and octave
+	bar    map[string]int
+	last   *event
+	broken frac
+	tuplet tuplet
+}
+
+// Parse reads the first tune of text into a single part score
+func Parse(text string) (*musicxml.Score, error) {
+	p := &parser{
+		current: &measure{},
+		bar:     map[string]int{},
+		broken:  frac{1, 1},
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		p.line = i + 1
+		line = strings.TrimRight(line, " \t")
+		if strings.HasPrefix(line, "%") || line == "" {
+			// a blank line ends the tune
+			if line == "" && p.inBody && len(p.measures)+len(p.current.events) > 0 {
+				break
+			}
+			continue
+		}
+
+		if isField(line) {
+			done, err := p.field(line[0], strings.TrimSpace(line[2:]))
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+			continue
+		}
+		if !p.inBody {
+			return nil, p.errorf("the tune body starts before the K: field")
+		}
+		if err := p.body(line); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.inBody {
+		return nil, &ParseError{Line: p.line, Message: "no K: field, it ends the header of a tune"}
+	}
+	if len(p.current.events) > 0 {
+		p.closeMeasure("")
+	}
+	return p.score()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &ParseError{Line: p.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func isField(line string) bool {
+	return len(line) >= 2 && line[1] == ':' &&
+		(line[0] >= 'A' && line[0] <= 'Z' || line[0] >= 'a' && line[0] <= 'z') &&
+		// |: is a bar line
+		line[0] != '|'
+}
+
+// field applies a header or body field line, done is a second tune
+func (p *parser) field(name byte, value string) (bool, error) {
+	switch name {
+	case 'X':
+		return p.inBody, nil
+	case 'T':
+		if p.title == "" {
+			p.title = value
+		}
+	case 'C':
+		if p.composer == "" {
+			p.composer = value
+		}
+	case 'M':
+		return false, p.meterField(value)
+	case 'L':
+		unit, err := parseFraction(value)
+		if err != nil {
+			return false, p.errorf("L: %v", err)
+		}
+		p.unit = unit
+	case 'Q':
+		tempo, err := parseTempo(value, p.unitOrDefault())
+		if err != nil {
+			return false, p.errorf("Q: %v", err)
+		}
+		p.tempo = tempo
+	case 'K':
+		return false, p.keyField(value)
+	case 'V':
+		id, _, _ := strings.Cut(value, " ")
+		if p.voice != "" && p.voice != id {
+			return false, p.errorf("only one voice is supported")
+		}
+		p.voice = id
+	}
+	// the other fields (lyrics, notes, sources) do not change the music
+	return false, nil
+}
+
+func (p *parser) meterField(value string) error {
+	var meter frac
+	var time *musicxml.Time
+
+	switch value {
+	case "none", "":
+	case "C":
+		meter, time = frac{1, 1}, &musicxml.Time{Beats: "4", BeatType: "4"}
+	case "C|":
+		meter, time = frac{1, 1}, &musicxml.Time{Beats: "2", BeatType: "2"}
+	default:
+		beats, beatType, ok := strings.Cut(value, "/")
+		denominator, err := strconv.Atoi(beatType)
+		if !ok || err != nil || denominator <= 0 {
+			return p.errorf("M: %q is not a meter", value)
+		}
+		numerator := 0
+		for _, part := range strings.Split(strings.Trim(beats, "()"), "+") {
+			n, err := strconv.Atoi(part)
+			if err != nil || n <= 0 {
+				return p.errorf("M: %q is not a meter", value)
+			}
+			numerator += n
+		}
+		meter, time = newFrac(numerator, denominator), &musicxml.Time{Beats: strings.Trim(beats, "()"), BeatType: beatType}
+	}
+
+	p.meter, p.time = meter, time
+	if p.inBody {
+		p.change().time = time
+	}
+	return nil
+}
+
+func (p *parser) keyField(value string) error {
+	key, clef, err := parseKey(value)
+	if err != nil {
+		return p.errorf("K: %v", err)
+	}
+
+	if !p.inBody {
+		p.inBody = true
+		p.key, p.clef = key, clef
+		p.header = measure{key: &key, time: p.time, clef: clef}
+		p.headerMeter = p.meter
+		if !p.unit.positive() {
+			p.unit = p.unitOrDefault()
+		}
+		return nil
+	}
+
+	change := p.change()
+	p.key = key
+	change.key = &key
+	if clef != nil {
+		p.clef = clef
+		change.clef = clef
+	}
+	return nil
+}
+
+// unitOrDefault is L:, or without one an eighth, or a sixteenth in meters
+// shorter than 3/4
+func (p *parser) unitOrDefault() frac {
+	if p.unit.positive() {
+		return p.unit
+	}
+	if p.meter.positive() && p.meter.less(frac{3, 4}) {
+		return frac{1, 16}
+	}
+	return frac{1, 8}
+}
+
+// change is the measure a key, meter or clef change lands on: the current
+// one before its first note, the next one after
+func (p *parser) change() *measure {
+	if len(p.current.events) == 0 {
+		return p.current
+	}
+	p.closeMeasure("")
+	return p.current
+}
+
+// body reads one line of music
+func (p *parser) body(line string) error {
+	for i := 0; i < len(line); {
+		c := line[i]
+		next := byte(0)
+		if i+1 < len(line) {
+			next = line[i+1]
+		}
+
+		switch {
+		case c == '%':
+			return nil
+		case c == ' ' || c == '\t' || c == '`' || c == 'y' || c == '\\' || c == ')':
+			i++
+		case c == '"' || c == '!' || c == '+' || c == '{':
+			// chord symbols, annotations, decorations and grace notes
+			closing := map[byte]byte{'"': '"', '!': '!', '+': '+', '{': '}'}[c]
+			end := strings.IndexByte(line[i+1:], closing)
+			if end < 0 {
+				return p.errorf("%q is never closed", c)
+			}
+			i += end + 2
+		case strings.IndexByte(".~HLMOPSTuv", c) >= 0:
+			i++
+		case c == '(' && next >= '2' && next <= '9':
+			var err error
+			if i, err = p.tupletMarker(line, i); err != nil {
+				return err
+			}
+		case c == '(':
+			// a slur
+			i++
+		case c == '|' || c == ':' || c == '[' && next == '|':
+			i = p.barline(line, i)
+		case c == '[' && next >= '1' && next <= '9':
+			// a first or second ending
+			i++
+			for i < len(line) && strings.IndexByte("0123456789,-", line[i]) >= 0 {
+				i++
+			}
+		case c == '[' && i+2 < len(line) && isField(line[i+1:]):
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				return p.errorf("inline field is never closed")
+			}
+			if _, err := p.field(next, strings.TrimSpace(line[i+3:i+end])); err != nil {
+				return err
+			}
+			i += end + 1
+		case c == '[':
+			var err error
+			if i, err = p.chord(line, i); err != nil {
+				return err
+			}
+		case c == '-':
+			if p.last == nil {
+				return p.errorf("a tie needs a note before it")
+			}
+			p.last.tie = true
+			i++
+		case c == '>' || c == '<':
+			i = p.brokenRhythm(line, i)
+		case c == 'z' || c == 'x':
+			length, end, err := p.parseLength(line, i+1)
+			if err != nil {
+				return err
+			}
+			p.add(&event{rest: true, length: p.unit.mul(length)})
+			i = end
+		case c == 'Z' || c == 'X':
+			var err error
+			if i, err = p.measureRests(line, i); err != nil {
+				return err
+			}
+		case isNoteStart(c):
+			note, length, end, err := p.note(line, i)
+			if err != nil {
+				return err
+			}
+			p.add(&event{notes: []written{note}, length: p.unit.mul(length)})
+			i = end
+		default:
+			return p.errorf("unexpected %q", c)
+		}
+	}
+	return nil
+}
+
+func isNoteStart(c byte) bool {
+	return c >= 'A' && c <= 'G' || c >= 'a' && c <= 'g' || c == '^' || c == '_' || c == '='
+}
+
+// note reads an accidental, a letter, octave marks and a length
+func (p *parser) note(line string, i int) (written, frac, int, error) {
+	var note written
+
+	alter, explicit := 0, false
+	for i < len(line) && strings.IndexByte("^_=", line[i]) >= 0 {
+		explicit = true
+		switch line[i] {
+		case '^':
+			alter++
+		case '_':
+			alter--
+		}
+		i++
+	}
+	if i >= len(line) || !(line[i] >= 'A' && line[i] <= 'G' || line[i] >= 'a' && line[i] <= 'g') {
+		return note, frac{}, i, p.errorf("an accidental needs a note after it")
+	}
+
+	letter := line[i]
+	octave := 4
+	if letter >= 'a' {
+		letter -= 'a' - 'A'
+		octave = 5
+	}
+	for i++; i < len(line) && (line[i] == '\'' || line[i] == ','); i++ {
+		if line[i] == '\'' {
+			octave++
+		} else {
+			octave--
+		}
+	}
+	if octave < 0 || octave > 9 {
+		return note, frac{}, i, p.errorf("%c is out of range", letter)
+	}
+
+	// an accidental holds for that pitch to the end of the bar
+	position := string(letter) + strconv.Itoa(octave)
+	if explicit {
+		if alter < -2 || alter > 2 {
+			return note, frac{}, i, p.errorf("too many accidentals on %c", letter)
+		}
+		p.bar[position] = alter
+		note.accidental = map[int]string{-2: "flat-flat", -1: "flat", 0: "natural", 1: "sharp", 2: "double-sharp"}[alter]
+	} else if held, ok := p.bar[position]; ok {
+		alter = held
+	} else {
+		alter = theory.KeyAlter(p.key.Fifths, letter)
+	}
+	note.pitch = theory.Pitch{Step: letter, Alter: alter, Octave: octave}
+
+	length, end, err := p.parseLength(line, i)
+	return note, length, end, err
+}
+
+// chord reads [CEG] and its length, the first note's own length counts
+func (p *parser) chord(line string, i int) (int, error) {
+	chord := &event{}
+	var length frac
+
+	for i++; i < len(line) && line[i] != ']'; {
+		switch c := line[i]; {
+		case c == '-':
+			chord.tie = true
+			i++
+		case c == ' ' || strings.IndexByte(".~HLMOPSTuv", c) >= 0:
+			i++
+		case isNoteStart(c):
+			note, noteLength, end, err := p.note(line, i)
+			if err != nil {
+				return end, err
+			}
+			if len(chord.notes) == 0 {
+				length = noteLength
+			}
+			chord.notes = append(chord.notes, note)
+			i = end
+		default:
+			return i, p.errorf("unexpected %q in a chord", c)
+		}
+	}
+	if i >= len(line) {
+		return i, p.errorf("chord is never closed")
+	}
+	if len(chord.notes) == 0 {
+		return i, p.errorf("empty chord")
+	}
+
+	multiplier, end, err := p.parseLength(line, i+1)
+	if err != nil {
+		return end, err
+	}
+	chord.length = p.unit.mul(length).mul(multiplier)
+	p.add(chord)
+	return end, nil
+}
+
+// tupletMarker reads (p:q:r, p notes in the time of q for the next r notes
+func (p *parser) tupletMarker(line string, i int) (int, error) {
+	numbers := []int{}
+	for i++; i < len(line); {
+		start := i
+		for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+			i++
+		}
+		n := 0
+		if i > start {
+			var err error
+			if n, err = strconv.Atoi(line[start:i]); err != nil || n > maxMultiplier {
+				return i, p.errorf("a tuplet of %s notes is too many", line[start:i])
+			}
+		}
+		numbers = append(numbers, n)
+		if i >= len(line) || line[i] != ':' || len(numbers) == 3 {
+			break
+		}
+		i++
+	}
+
+	actual := numbers[0]
+	normal := map[int]int{2: 3, 3: 2, 4: 3, 6: 2, 8: 3}[actual]
+	if normal == 0 {
+		normal = 2
+	}
+	if len(numbers) > 1 && numbers[1] > 0 {
+		normal = numbers[1]
+	}
+	left := actual
+	if len(numbers) > 2 && numbers[2] > 0 {
+		left = numbers[2]
+	}
+
+	p.tuplet = tuplet{actual: actual, normal: normal, left: left}
+	return i, nil
+}
+
+// brokenRhythm dots the note before > and halves the one after, < the other
+// way around. >> and >>> double and triple dot
+func (p *parser) brokenRhythm(line string, i int) int {
+	c, count := line[i], 0
+	for ; i < len(line) && line[i] == c && count < 3; i++ {
+		count++
+	}
+
+	short := frac{1, 1 << count}
+	long := frac{2, 1}.sub(short)
+	if c == '<' {
+		long, short = short, long
+	}
+	if p.last != nil {
+		p.last.length = p.last.length.mul(long)
+	}
+	p.broken = short
+	return i
+}
+
+// measureRests reads Z4, four measures of rest
+func (p *parser) measureRests(line string, i int) (int, error) {
+	start := i + 1
+	for i = start; i < len(line) && line[i] >= '0' && line[i] <= '9'; i++ {
+	}
+	count := 1
+	if i > start {
+		n, err := strconv.Atoi(line[start:i])
+		if err != nil || n > maxMeasureRests {
+			return i, p.errorf("%c%s is more than %d measures of rest", line[start-1], line[start:i], maxMeasureRests)
+		}
+		count = max(n, 1)
+	}
+
+	length := p.meter
+	if !length.positive() {
+		length = frac{1, 1}
+	}
+	for n := 0; n < count; n++ {
+		if n > 0 {
+			p.closeMeasure("")
+		}
+		p.add(&event{rest: true, measureRest: true, length: length})
+	}
+	return i, nil
+}
+
+// barline reads any bar line, | || |] [| |: :| :: and the ending number
+// that can follow one, and closes the measure
+func (p *parser) barline(line string, i int) int {
+	start := i
+	for i < len(line) && (strings.IndexByte("|:", line[i]) >= 0 ||
+		line[i] == '[' && i == start ||
+		line[i] == ']' && i > start && line[i-1] == '|') {
+		i++
+	}
+	bar := line[start:i]
+	for i < len(line) && line[i] >= '1' && line[i] <= '9' {
+		i++
+	}
+
+	firstBar, lastBar := strings.IndexByte(bar, '|'), strings.LastIndexByte(bar, '|')
+	backward := strings.HasPrefix(bar, ":") || firstBar < 0 && strings.Contains(bar, "::")
+	forward := strings.HasSuffix(bar, ":") && (lastBar >= 0 || strings.Contains(bar, "::"))
+
+	style := ""
+	switch {
+	case backward || strings.HasSuffix(bar, "|]"):
+		style = "light-heavy"
+	case strings.HasPrefix(bar, "[|"):
+		style = "heavy-light"
+	case strings.Contains(bar, "||"):
+		style = "light-light"
+	}
+
+	if len(p.current.events) > 0 {
+		p.current.backward = backward
+		p.closeMeasure(style)
+	} else if backward && len(p.measures) > 0 {
+		previous := p.measures[len(p.measures)-1]
+		previous.backward, previous.style = true, "light-heavy"
+	}
+	if forward {
+		p.current.forward = true
+	}
+	return i
+}
+
+func (p *parser) closeMeasure(style string) {
+	p.current.style = style
+	p.measures = append(p.measures, p.current)
+	p.current = &measure{}
+	p.bar = map[string]int{}
+}
+
+// add puts an event in the current measure, applying a pending broken
+// rhythm and tuplet
+func (p *parser) add(e *event) {
+	e.length = e.length.mul(p.broken)
+	p.broken = frac{1, 1}
+
+	if p.tuplet.left > 0 && !e.measureRest {
+		e.actual, e.normal = p.tuplet.actual, p.tuplet.normal
+		e.length = e.length.mul(newFrac(p.tuplet.normal, p.tuplet.actual))
+		p.tuplet.left--
+	}
+
+	p.current.events = append(p.current.events, e)
+	p.last = e
+}
+
+// parseLength reads a length multiplier: 2, 3/2, /, //, /4. Neither side
+// goes past maxMultiplier
+func (p *parser) parseLength(line string, i int) (frac, int, error) {
+	num, den := 1, 1
+	start := i
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	if i > start {
+		n, err := strconv.Atoi(line[start:i])
+		if err != nil || n > maxMultiplier {
+			return frac{}, i, p.errorf("a length of %s is longer than %d units", line[start:i], maxMultiplier)
+		}
+		num = max(n, 1)
+	}
+	for i < len(line) && line[i] == '/' {
+		i++
+		start = i
+		for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+			i++
+		}
+		d := 2
+		if i > start {
+			n, err := strconv.Atoi(line[start:i])
+			if err != nil {
+				return frac{}, i, p.errorf("a length of /%s is shorter than 1/%d of a unit", line[start:i], maxMultiplier)
+			}
+			d = max(n, 1)
+		}
+		if den *= d; den > maxMultiplier {
+			return frac{}, i, p.errorf("a length is shorter than 1/%d of a unit", maxMultiplier)
+		}
+	}
+	return newFrac(num, den), i, nil
+}
+
+func parseFraction(value string) (frac, error) {
+	num, den, ok := strings.Cut(strings.TrimSpace(value), "/")
+	n, numErr := strconv.Atoi(num)
+	d, denErr := strconv.Atoi(den)
+	if !ok || numErr != nil || denErr != nil || n <= 0 || d <= 0 {
+		return frac{}, fmt.Errorf("%q is not a fraction like 1/8", value)
+	}
+	return newFrac(n, d), nil
+}
+
+// parseTempo reads "1/4=120", "3/8=60", "120" (in unit notes) or any of them
+// with a quoted text, as quarter notes per minute
+func parseTempo(value string, unit frac) (float64, error) {
+	// the text of "Allegro" 1/4=120 is not the tempo
+	for strings.Count(value, `"`) >= 2 {
+		start := strings.IndexByte(value, '"')
+		end := strings.IndexByte(value[start+1:], '"') + start + 1
+		value = value[:start] + value[end+1:]
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	beat := unit
+	if left, right, ok := strings.Cut(value, "="); ok {
+		value = right
+		if left != "C" && left != "" {
+			var err error
+			if beat, err = parseFraction(left); err != nil {
+				return 0, err
+			}
+		}
+	}
+	perMinute, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || perMinute <= 0 {
+		return 0, fmt.Errorf("%q is not a tempo", value)
+	}
+	return perMinute * float64(beat.num) * 4 / float64(beat.den), nil
+}
+
+// modes are the ABC mode names, by their first three letters, and how many
+// fifths they sit from the major key on the same tonic
+var modes = map[string]struct {
+	name   string
+	fifths int
+}{
+	"":    {"major", 0},
+	"maj": {"major", 0},
+	"ion": {"major", 0},
+	"m":   {"minor", -3},
+	"min": {"minor", -3},
+	"aeo": {"minor", -3},
+	"dor": {"dorian", -2},
+	"phr": {"phrygian", -4},
+	"lyd": {"lydian", 1},
+	"mix": {"mixolydian", -1},
+	"loc": {"locrian", -5},
+}
+
+// parseKey reads "G", "Bb", "F#m", "D dor", "none" and a clef, "clef=bass"
+// or just "bass"
+func parseKey(value string) (musicxml.Key, *musicxml.Clef, error) {
+	var key musicxml.Key
+	var clef *musicxml.Clef
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 || fields[0] == "none" {
+		return key, nil, nil
+	}
+
+	tonic, rest := fields[0], fields[1:]
+	if len(tonic) == 0 || tonic[0] < 'A' || tonic[0] > 'G' {
+		// a key of only a clef
+		if _, err := theory.ParseClef(strings.TrimPrefix(tonic, "clef=")); err == nil {
+			tonic, rest = "C", fields
+		} else {
+			return key, nil, fmt.Errorf("%q is not a key", value)
+		}
+	}
+
+	pitch := theory.Pitch{Step: tonic[0], Octave: 4}
+	mode := strings.ToLower(tonic[1:])
+	switch {
+	case strings.HasPrefix(mode, "#"):
+		pitch.Alter, mode = 1, mode[1:]
+	case strings.HasPrefix(mode, "b"):
+		pitch.Alter, mode = -1, mode[1:]
+	}
+	if mode == "" && len(rest) > 0 {
+		if _, ok := modes[strings.ToLower(rest[0][:min(3, len(rest[0]))])]; ok && !strings.Contains(rest[0], "=") {
+			mode, rest = strings.ToLower(rest[0]), rest[1:]
+		}
+	}
+	if mode != "m" {
+		mode = mode[:min(3, len(mode))]
+	}
+	found, ok := modes[mode]
+	if !ok {
+		return key, nil, fmt.Errorf("unknown mode %q", mode)
+	}
+
+	fifths, err := theory.KeyFifths(pitch)
+	if err != nil {
+		return key, nil, err
+	}
+	key.Fifths = fifths + found.fifths
+	if key.Fifths < -7 || key.Fifths > 7 {
+		return key, nil, fmt.Errorf("%s has no key signature", value)
+	}
+	if found.name != "major" {
+		key.Mode = found.name
+	}
+
+	for _, field := range rest {
+		name := strings.TrimPrefix(field, "clef=")
+		if parsed, err := theory.ParseClef(name); err == nil {
+			musicXML := parsed.MusicXML()
+			clef = &musicXML
+		}
+		// octave=, middle= and explicit accidentals are not supported
+	}
+	return key, clef, nil
+}
+
+// score turns the measures read into a single part MusicXML score
+func (p *parser) score() (*musicxml.Score, error) {
+	divisions := 1
+	notes := 0
+	for _, measure := range p.measures {
+		for _, e := range measure.events {
+			quarters := e.length.mul(frac{4, 1})
+			if divisions = lcm(divisions, quarters.den); divisions > maxDivisions {
+				return nil, &ParseError{Line: p.line, Message: "the note lengths are too fine to write as MusicXML"}
+			}
+			if !e.rest {
+				notes++
+			}
+		}
+	}
+	if notes == 0 {
+		return nil, ErrNoNotes
+	}
+	duration := func(length frac) int {
+		return length.num * 4 * divisions / length.den
+	}
+
+	// a first measure shorter than the meter is a pickup, numbered 0
+	number := 1
+	if len(p.measures) > 1 && p.headerMeter.positive() && measureLength(p.measures[0]).less(p.headerMeter) {
+		number = 0
+	}
+
+	var measures []musicxml.Measure
+	tied := map[int]bool{}
+	for i, m := range p.measures {
+		out := musicxml.Measure{Number: strconv.Itoa(number + i)}
+		if i == 0 && number == 0 {
+			out.Implicit = "yes"
+		}
+
+		if i == 0 {
+			// the header is what the first measure starts with, unless the
+			// body changed it before the first note
+			start := *m
+			if start.key == nil {
+				start.key = p.header.key
+			}
+			if start.time == nil {
+				start.time = p.header.time
+			}
+			if start.clef == nil {
+				start.clef = p.header.clef
+			}
+			m = &start
+		}
+
+		if m.key != nil || m.time != nil || m.clef != nil {
+			attributes := &musicxml.Attributes{}
+			if i == 0 {
+				attributes.Divisions = divisions
+			}
+			if m.key != nil {
+				attributes.Keys = []musicxml.Key{*m.key}
+			}
+			if m.time != nil {
+				attributes.Times = []musicxml.Time{*m.time}
+			}
+			if m.clef != nil {
+				attributes.Clefs = []musicxml.Clef{*m.clef}
+			}
+			out.Elements = append(out.Elements, attributes)
+		}
+		if i == 0 && p.tempo > 0 {
+			out.Elements = append(out.Elements, tempoDirection(p.tempo))
+		}
+
+		if m.forward {
+			out.Elements = append(out.Elements, &musicxml.Barline{Location: "left", Style: "heavy-light", Inner: []musicxml.Raw{repeat("forward")}})
+		}
+
+		for _, e := range m.events {
+			elements, ok := notesFor(e, duration, tied)
+			if !ok {
+				return nil, &ParseError{Line: p.line, Message: "a note is too long to write as tied notes"}
+			}
+			out.Elements = append(out.Elements, elements...)
+		}
+
+		style := m.style
+		if i == len(p.measures)-1 && style == "" {
+			style = "light-heavy"
+		}
+		if style != "" || m.backward {
+			barline := &musicxml.Barline{Location: "right", Style: style}
+			if m.backward {
+				barline.Inner = []musicxml.Raw{repeat("backward")}
+			}
+			out.Elements = append(out.Elements, barline)
+		}
+		measures = append(measures, out)
+	}
+
+	score := &musicxml.Score{
+		Version: "3.1",
+		PartList: musicxml.PartList{
+			ScoreParts: []musicxml.ScorePart{{ID: "P1"}},
+		},
+		Parts: []musicxml.Part{{ID: "P1", Measures: measures}},
+	}
+	if p.title != "" {
+		score.Work = &musicxml.Work{Title: p.title}
+	}
+	if p.composer != "" {
+		score.Identification = &musicxml.Identification{
+			Creators: []musicxml.Creator{{Type: "composer", Name: p.composer}},
+		}
+	}
+	return score, nil
+}
+
+func measureLength(m *measure) frac {
+	length := frac{}
+	for _, e := range m.events {
+		length = length.add(e.length)
+	}
+	return length
+}
+
+// notesFor writes an event as MusicXML notes, a length no single note shows
+// is split into tied ones, not ok when it takes too many. tied holds the
+// pitches tied into this event
+func notesFor(e *event, duration func(frac) int, tied map[int]bool) ([]musicxml.Element, bool) {
+	if e.measureRest {
+		return []musicxml.Element{&musicxml.Note{Rest: &musicxml.Rest{Measure: "yes"}, Duration: duration(e.length)}}, true
+	}
+
+	// the length shown is before the tuplet shortened it
+	shown := e.length
+	if e.actual > 0 {
+		shown = shown.mul(newFrac(e.actual, e.normal))
+	}
+	pieces, ok := split(shown)
+	if !ok {
+		return nil, false
+	}
+
+	var elements []musicxml.Element
+	for i, piece := range pieces {
+		played := piece
+		if e.actual > 0 {
+			played = piece.mul(newFrac(e.normal, e.actual))
+		}
+		noteType, dots, _ := noteType(piece)
+
+		chord := e.notes
+		if e.rest {
+			chord = []written{{}}
+		}
+		for j, w := range chord {
+			note := &musicxml.Note{
+				Duration: duration(played),
+				Type:     noteType,
+				Dots:     make([]musicxml.Empty, dots),
+			}
+			if e.actual > 0 {
+				note.TimeModification = &musicxml.TimeModification{ActualNotes: e.actual, NormalNotes: e.normal}
+			}
+			if j > 0 {
+				note.Chord = &musicxml.Empty{}
+			}
+			if e.rest {
+				note.Rest = &musicxml.Rest{}
+				elements = append(elements, note)
+				continue
+			}
+
+			note.Pitch = w.pitch.MusicXML()
+			if i == 0 {
+				note.Accidental = w.accidental
+			}
+
+			midi := w.pitch.MIDI()
+			stops := i > 0 || tied[midi]
+			starts := i < len(pieces)-1 || e.tie
+			tie(note, stops, starts)
+			elements = append(elements, note)
+		}
+	}
+
+	for midi := range tied {
+		delete(tied, midi)
+	}
+	if e.tie {
+		for _, w := range e.notes {
+			tied[w.pitch.MIDI()] = true
+		}
+	}
+	return elements, true
+}
+
+func tie(note *musicxml.Note, stops, starts bool) {
+	inner := ""
+	if stops {
+		note.Ties = append(note.Ties, musicxml.Tie{Type: "stop"})
+		inner += `<tied type="stop"/>`
+	}
+	if starts {
+		note.Ties = append(note.Ties, musicxml.Tie{Type: "start"})
+		inner += `<tied type="start"/>`
+	}
+	if inner != "" {
+		note.Notations = append(note.Notations, musicxml.Raw{XMLName: xml.Name{Local: "notations"}, Inner: inner})
+	}
+}
+
+func repeat(direction string) musicxml.Raw {
+	return musicxml.Raw{
+		XMLName: xml.Name{Local: "repeat"},
+		Attrs:   []xml.Attr{{Name: xml.Name{Local: "direction"}, Value: direction}},
+	}
+}
+
+func tempoDirection(tempo float64) *musicxml.Direction {
+	perMinute := strconv.FormatFloat(tempo, 'f', -1, 64)
+	return &musicxml.Direction{
+		Placement: "above",
+		Inner: `<direction-type><metronome><beat-unit>quarter</beat-unit><per-minute>` + perMinute +
+			`</per-minute></metronome></direction-type><sound tempo="` + perMinute + `"/>`,
+	}
+}
@@ -0,0 +1,366 @@
+package abc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+)
+
+// measuresPerLine is how many measures go on a line of the tune
+const measuresPerLine = 4
+
+var ErrNoParts = errors.New("the score has no parts to write")
+
+// unit is the L: of written tunes, an eighth note
+var unit = frac{1, 8}
+
+// Encode writes the first voice of the first part of score as one ABC tune.
+// Unpitched notes are written as rests, everything after a backup in a
+// measure belongs to another voice and is left out
+func Encode(w io.Writer, score *musicxml.Score) error {
+	if len(score.Parts) == 0 {
+		return ErrNoParts
+	}
+	part := &score.Parts[0]
+
+	var tune strings.Builder
+	tune.WriteString("X:1\n")
+	if title := title(score); title != "" {
+		tune.WriteString("T:" + title + "\n")
+	}
+	if composer := composer(score); composer != "" {
+		tune.WriteString("C:" + composer + "\n")
+	}
+
+	writer := &tuneWriter{divisions: 1}
+	if first := part.Measures; len(first) > 0 {
+		if attributes := first[0].Attributes(); attributes != nil {
+			writer.attributes(attributes)
+		}
+	}
+	if writer.time != "" {
+		tune.WriteString("M:" + writer.time + "\n")
+	} else {
+		tune.WriteString("M:none\n")
+	}
+	tune.WriteString("L:" + strconv.Itoa(unit.num) + "/" + strconv.Itoa(unit.den) + "\n")
+	if tempo, ok := part.Tempo(); ok {
+		tune.WriteString("Q:1/4=" + strconv.FormatFloat(tempo, 'f', -1, 64) + "\n")
+	}
+	tune.WriteString("K:" + writer.keyField() + "\n")
+
+	for i := range part.Measures {
+		writer.measure(&part.Measures[i], i == 0, i == len(part.Measures)-1)
+		if (i+1)%measuresPerLine == 0 || i == len(part.Measures)-1 {
+			writer.body.WriteString("\n")
+		}
+	}
+	if writer.err != nil {
+		return writer.err
+	}
+	body := strings.ReplaceAll(writer.body.String(), " \n", "\n")
+	tune.WriteString(strings.TrimLeft(body, " "))
+
+	_, err := io.WriteString(w, tune.String())
+	return err
+}
+
+func Marshal(score *musicxml.Score) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, score); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// modeSuffixes are the ABC names of the MusicXML modes, major has none
+var modeSuffixes = map[string]string{
+	"minor":      "m",
+	"aeolian":    "m",
+	"dorian":     "dor",
+	"phrygian":   "phr",
+	"lydian":     "lyd",
+	"mixolydian": "mix",
+	"locrian":    "loc",
+}
+
+type tuneWriter struct {
+	body      strings.Builder
+	divisions int
+	fifths    int
+	mode      string
+	time      string
+	clef      string
+	// accidentals in effect in the bar, by step and octave
+	bar map[string]int
+	// graces are grace notes waiting for the note they lead to
+	graces []string
+	// left is how many notes of the current tuplet are still to come
+	left int
+	// forward is a forward repeat the next bar line opens
+	forward bool
+	// err is the first pitch that could not be written
+	err error
+}
+
+// attributes takes the divisions, key, meter and clef in effect and reports
+// whether the key, meter or clef changed
+func (w *tuneWriter) attributes(attributes *musicxml.Attributes) bool {
+	changed := false
+	if attributes.Divisions > 0 {
+		w.divisions = attributes.Divisions
+	}
+	if len(attributes.Keys) > 0 {
+		w.fifths, w.mode = attributes.Keys[0].Fifths, attributes.Keys[0].Mode
+		changed = true
+	}
+	if len(attributes.Times) > 0 {
+		w.time = attributes.Times[0].Beats + "/" + attributes.Times[0].BeatType
+		changed = true
+	}
+	for _, clef := range attributes.Clefs {
+		if clef.Number > 1 {
+			continue
+		}
+		w.clef = map[string]string{"G2": "", "F4": "bass", "C3": "alto", "C4": "tenor"}[clef.Sign+strconv.Itoa(clef.Line)]
+		changed = true
+	}
+	return changed
+}
+
+// keyField is the K: value, "Bb", "F#m", "Ddor", with a clef other than
+// treble
+func (w *tuneWriter) keyField() string {
+	suffix := modeSuffixes[w.mode]
+	offset := modes[suffix].fifths
+
+	c := theory.Pitch{Step: 'C', Octave: 4}
+	fifths := w.fifths - offset
+	tonic := c.Transpose(4*fifths, 7*fifths)
+	field := string(tonic.Step) + map[int]string{-1: "b", 1: "#"}[tonic.Alter] + suffix
+	if w.clef != "" {
+		field += " clef=" + w.clef
+	}
+	return field
+}
+
+// measure writes one measure and the bar line after it
+func (w *tuneWriter) measure(measure *musicxml.Measure, first, last bool) {
+	w.bar = map[string]int{}
+
+	var chord []*musicxml.Note
+	flush := func() {
+		if len(chord) > 0 {
+			w.notes(chord)
+			chord = nil
+		}
+	}
+
+	backward, style := false, ""
+	for _, element := range measure.Elements {
+		switch element := element.(type) {
+		case *musicxml.Attributes:
+			flush()
+			if w.attributes(element) && !first {
+				w.body.WriteString("[K:" + w.keyField() + "]")
+				if len(element.Times) > 0 {
+					w.body.WriteString("[M:" + w.time + "]")
+				}
+			}
+		case *musicxml.Backup:
+			// the rest of the measure is another voice
+			flush()
+			w.close(backward, style, last)
+			return
+		case *musicxml.Forward:
+			flush()
+			w.body.WriteString("x" + w.length(element.Duration, nil))
+		case *musicxml.Barline:
+			for _, inner := range element.Inner {
+				if inner.XMLName.Local != "repeat" {
+					continue
+				}
+				for _, attr := range inner.Attrs {
+					if attr.Name.Local == "direction" && attr.Value == "forward" {
+						w.forward = true
+					}
+					if attr.Name.Local == "direction" && attr.Value == "backward" {
+						backward = true
+					}
+				}
+			}
+			if element.Location != "left" {
+				style = element.Style
+			}
+		case *musicxml.Note:
+			if element.IsGrace() {
+				flush()
+				if element.Pitch != nil {
+					w.graces = append(w.graces, w.pitch(*element.Pitch, element.Accidental))
+				}
+				continue
+			}
+			if !element.IsChord() {
+				flush()
+			}
+			chord = append(chord, element)
+		}
+	}
+	flush()
+	w.close(backward, style, last)
+}
+
+func (w *tuneWriter) close(backward bool, style string, last bool) {
+	bar := "|"
+	switch {
+	case backward:
+		bar = ":|"
+	case style == "light-light":
+		bar = "||"
+	case style == "light-heavy" || last:
+		bar = "|]"
+	}
+	w.body.WriteString(bar + " ")
+}
+
+// notes writes a note, a rest or a chord, notes[1:] are the chord notes
+func (w *tuneWriter) notes(notes []*musicxml.Note) {
+	first := notes[0]
+
+	if w.forward {
+		// the forward repeat opens at the first note after its bar line
+		body := strings.TrimRight(w.body.String(), " ")
+		w.body.Reset()
+		if strings.HasSuffix(body, ":|") {
+			w.body.WriteString(strings.TrimSuffix(body, ":|") + ":: ")
+		} else {
+			w.body.WriteString(strings.TrimSuffix(body, "|") + "|: ")
+		}
+		w.forward = false
+	}
+
+	if len(w.graces) > 0 {
+		w.body.WriteString("{" + strings.Join(w.graces, "") + "}")
+		w.graces = nil
+	}
+
+	if modification := first.TimeModification; modification != nil && modification.ActualNotes > 0 {
+		if w.left == 0 {
+			w.left = modification.ActualNotes
+			w.body.WriteString("(" + strconv.Itoa(modification.ActualNotes))
+			if modification.NormalNotes != map[int]int{2: 3, 3: 2, 4: 3, 6: 2, 8: 3}[modification.ActualNotes] {
+				w.body.WriteString(":" + strconv.Itoa(modification.NormalNotes))
+			}
+		}
+		w.left--
+	} else {
+		w.left = 0
+	}
+
+	length := w.length(first.Duration, first.TimeModification)
+	if first.IsRest() || first.Pitch == nil {
+		w.body.WriteString("z" + length)
+		return
+	}
+
+	var written []string
+	for _, note := range notes {
+		if note.Pitch == nil {
+			continue
+		}
+		pitch := w.pitch(*note.Pitch, note.Accidental)
+		for _, tie := range note.Ties {
+			if tie.Type == "start" {
+				pitch += "-"
+			}
+		}
+		written = append(written, pitch)
+	}
+
+	if len(written) == 1 {
+		// a tie goes after the length of a single note
+		pitch, tied := strings.CutSuffix(written[0], "-")
+		w.body.WriteString(pitch + length)
+		if tied {
+			w.body.WriteString("-")
+		}
+		return
+	}
+	w.body.WriteString("[" + strings.Join(written, "") + "]" + length)
+}
+
+// pitch writes the accidental the bar needs, or the one the score shows, and
+// the pitch in ABC octaves: C is C4, c C5, c' C6 and C, C3. A pitch that is
+// not one is kept in err and written as nothing
+func (w *tuneWriter) pitch(written musicxml.Pitch, shown string) string {
+	pitch, err := theory.FromMusicXML(written)
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return ""
+	}
+	position := string(pitch.Step) + strconv.Itoa(pitch.Octave)
+	expected, ok := w.bar[position]
+	if !ok {
+		expected = theory.KeyAlter(w.fifths, pitch.Step)
+	}
+
+	accidental := ""
+	if pitch.Alter != expected || shown != "" {
+		switch {
+		case pitch.Alter > 0:
+			accidental = strings.Repeat("^", pitch.Alter)
+		case pitch.Alter < 0:
+			accidental = strings.Repeat("_", -pitch.Alter)
+		default:
+			accidental = "="
+		}
+		w.bar[position] = pitch.Alter
+	}
+
+	letter := string(pitch.Step)
+	octaves := ""
+	switch {
+	case pitch.Octave >= 5:
+		letter = strings.ToLower(letter)
+		octaves = strings.Repeat("'", pitch.Octave-5)
+	case pitch.Octave < 4:
+		octaves = strings.Repeat(",", 4-pitch.Octave)
+	}
+	return accidental + letter + octaves
+}
+
+// length is the ABC length of a duration in divisions, in unit notes and as
+// written, before a tuplet shortens it
+func (w *tuneWriter) length(duration int, modification *musicxml.TimeModification) string {
+	length := newFrac(duration, 4*w.divisions).mul(frac{unit.den, unit.num})
+	if modification != nil && modification.ActualNotes > 0 && modification.NormalNotes > 0 {
+		length = length.mul(newFrac(modification.ActualNotes, modification.NormalNotes))
+	}
+	return length.String()
+}
+
+func title(score *musicxml.Score) string {
+	if score.Work != nil && score.Work.Title != "" {
+		return score.Work.Title
+	}
+	return score.MovementTitle
+}
+
+func composer(score *musicxml.Score) string {
+	if score.Identification == nil {
+		return ""
+	}
+	for _, creator := range score.Identification.Creators {
+		if creator.Type == "composer" {
+			return creator.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package apperrors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+type Kind string
+
+const (
+	KindNotFound     Kind = "not-found"
+	KindValidation   Kind = "validation"
+	KindConflict     Kind = "conflict"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindRateLimit    Kind = "rate-limited"
+	KindUnavailable  Kind = "unavailable"
+	KindInternal     Kind = "internal"
+)
+
+// Error is what services hand to gin with c.Error, the error middleware turns
+// it into a problem response. Message is shown to the client, Err never is
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Kind, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Status() int {
+	switch e.Kind {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindRateLimit:
+		return http.StatusTooManyRequests
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func Validation(message string) *Error {
+	return &Error{Kind: KindValidation, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+func RateLimited(message string) *Error {
+	return &Error{Kind: KindRateLimit, Message: message}
+}
+
+// Unavailable is for a dependency that is down, err is kept for the logs
+func Unavailable(message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Message: message, Err: err}
+}
+
+// Internal wraps an unexpected error, the client only ever sees a generic
+// message, the wrapped error goes to the logs
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Message: "an unexpected error occurred", Err: err}
+}
+
+// postgres error codes we map to something other than a 500
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqCheckViolation      = "23514"
+)
+
+// FromDB classifies an error coming back from sqlx. resource is the thing
+// being looked up, it is only used for the not found message
+func FromDB(err error, resource string) *Error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Kind: KindNotFound, Message: resource + " not found", Err: err}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			return &Error{Kind: KindConflict, Message: resource + " already exists", Err: err}
+		case pqForeignKeyViolation:
+			return &Error{Kind: KindValidation, Message: "a referenced record does not exist", Err: err}
+		case pqCheckViolation:
+			return &Error{Kind: KindValidation, Message: resource + " is not valid", Err: err}
+		}
+	}
+
+	return Internal(err)
+}
+
+// As returns err as an *Error, anything that is not one is treated as internal
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
@@ -0,0 +1,82 @@
+package apperrors
+
+import (
+	"net/http"
+	"sight-reading/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body every error response uses
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func NewProblem(c *gin.Context, appErr *Error) Problem {
+	status := appErr.Status()
+	return Problem{
+		Type:      "urn:tremolo:problem:" + string(appErr.Kind),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Instance:  c.Request.URL.Path,
+		RequestID: logging.RequestIDFromContext(c),
+	}
+}
+
+// Abort writes the problem response straight away, for middleware that has to
+// stop the chain before any handler runs
+func Abort(c *gin.Context, appErr *Error) {
+	_ = c.Error(appErr)
+	Write(c, appErr)
+}
+
+func Write(c *gin.Context, appErr *Error) {
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(appErr.Status(), NewProblem(c, appErr))
+}
+
+// Middleware renders the last error a handler attached with c.Error. Handlers
+// should not write error bodies themselves, they call c.Error and return
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := As(c.Errors.Last().Err)
+		if appErr.Err != nil {
+			logger := logging.FromContext(c)
+			if appErr.Kind == KindInternal {
+				logger.Error("request failed", "error", appErr.Err)
+			} else {
+				logger.Warn("request failed", "kind", appErr.Kind, "error", appErr.Err)
+			}
+		}
+
+		Write(c, appErr)
+	}
+}
+
+// Recovery turns a panic into an internal problem response instead of gins
+// plain text 500
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		logging.FromContext(c).Error("panic recovered", "panic", recovered)
+		Write(c, Internal(nil))
+	})
+}
+
+// NoRoute answers unknown routes with the same problem body as everything else
+func NoRoute(c *gin.Context) {
+	Write(c, NotFound("route not found"))
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"sight-reading/apperrors"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+	"slices"
+	"strings"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const claimsKey = "claims"
+
+// Middleware reads the bearer token when there is one. It does not require
+// one, routes that do use Require
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			c.Next()
+			return
+		}
+
+		claims, err := Parse(token)
+		if err != nil {
+			metrics.AuthFailures.WithLabelValues("invalid").Inc()
+			apperrors.Abort(c, apperrors.Unauthorized(err.Error()))
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Set(logging.UserIDKey, claims.UserID)
+		c.Next()
+	}
+}
+
+// Require lets the request through only with a valid token of one of roles,
+// no roles means any signed in user
+func Require(roles ...dtos.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := FromContext(c)
+		if !ok {
+			metrics.AuthFailures.WithLabelValues("missing").Inc()
+			apperrors.Abort(c, apperrors.Unauthorized("sign in to use this route"))
+			return
+		}
+		if len(roles) > 0 && !slices.Contains(roles, claims.Role) {
+			apperrors.Abort(c, apperrors.Forbidden("this route is not available to "+strings.ToLower(string(claims.Role))+"s"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func FromContext(c *gin.Context) (Claims, bool) {
+	claims, ok := c.Get(claimsKey)
+	if !ok {
+		return Claims{}, false
+	}
+	return claims.(Claims), true
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sight-reading/config"
+	"sight-reading/database"
+	"sight-reading/logging"
+	"strings"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(config config.Config, args []string) error
+}
+
+var commands = []command{
+	{"serve", "run the http api (default)", runServe},
+	{"migrate", "apply or revert database migrations: up, down, status", runMigrate},
+	{"seed", "fill the database with generated fake data", runSeed},
+	{"create-admin", "create an ADMIN user", runCreateAdmin},
+	{"import-roster", "import students or teachers from a csv roster", runImportRoster},
+	{"export", "export the users or entries of a school as csv or json", runExport},
+	{"purge-school", "delete a school and everything that belongs to it", runPurgeSchool},
+	{"issue-token", "print a bearer token for a user", runIssueToken},
+	{"recalculate-ratings", "rebuild skill ratings from every recorded session", runRecalculateRatings},
+}
+
+// Execute runs the subcommand named by args[0]. No subcommand, or flags
+// straight away (the old `main -fake-it`), means serve
+func Execute(args []string) error {
+	cfg := config.Load()
+	logging.Init(cfg.LogLevel)
+
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(os.Stdout)
+		return nil
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(cfg, args)
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: sight-reading <command> [flags]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run a command with -h for its flags")
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+func connect(cfg config.Config) {
+	database.InitializeDBConnection(cfg.DatabaseURL)
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"sight-reading/config"
+	"sight-reading/database"
+
+	dtos "sight-reading/DTOs"
+)
+
+func runCreateAdmin(cfg config.Config, args []string) error {
+	fs := newFlagSet("create-admin")
+	firstName := fs.String("first", "", "first name")
+	lastName := fs.String("last", "", "last name")
+	email := fs.String("email", "", "email")
+	schoolId := fs.Int("school", 0, "id of the school the admin belongs to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	admin := dtos.User{
+		FirstName: *firstName,
+		LastName:  *lastName,
+		Email:     *email,
+		Role:      dtos.Admin,
+		SchoolID:  int16(*schoolId),
+	}
+	if err := admin.ValidateUser(); err != nil {
+		return err
+	}
+
+	connect(cfg)
+
+	id, err := insertUser(database.DBClient, admin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created admin %s %s <%s> with id %d\n", admin.FirstName, admin.LastName, admin.Email, id)
+	return nil
+}
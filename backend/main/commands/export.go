@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sight-reading/config"
+	"sight-reading/database"
+	"strconv"
+)
+
+type exportedUser struct {
+	ID          int    `db:"id"           json:"id"`
+	FirstName   string `db:"first_name"   json:"first_name"`
+	LastName    string `db:"last_name"    json:"last_name"`
+	Email       string `db:"email"        json:"email"`
+	Role        string `db:"role"         json:"role"`
+	CreatedDate string `db:"created_date" json:"created_date"`
+}
+
+type exportedEntry struct {
+	ID               int    `db:"id"                json:"id"`
+	UserID           int    `db:"user_id"           json:"user_id"`
+	TimeLength       string `db:"time_length"       json:"time_length"`
+	TotalQuestions   int    `db:"total_questions"   json:"total_questions"`
+	CorrectQuestions int    `db:"correct_questions" json:"correct_questions"`
+	NPM              int    `db:"notes_per_minute"  json:"notes_per_minute"`
+	CreatedDate      string `db:"created_date"      json:"created_date"`
+	CreatedTime      string `db:"created_time"      json:"created_time"`
+}
+
+func runExport(cfg config.Config, args []string) error {
+	fs := newFlagSet("export")
+	schoolId := fs.Int("school", 0, "school to export")
+	what := fs.String("what", "users", "users or entries")
+	format := fs.String("format", "csv", "csv or json")
+	out := fs.String("out", "", "file to write to, stdout when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *schoolId == 0 {
+		return fmt.Errorf("export needs -school")
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q, use csv or json", *format)
+	}
+
+	connect(cfg)
+
+	var header []string
+	var records [][]string
+	var data any
+
+	switch *what {
+	case "users":
+		var users []exportedUser
+		err := database.DBClient.Select(&users, `
+    SELECT id, first_name, last_name, coalesce(email, '') AS email,
+      coalesce(role, '') AS role, coalesce(created_date::text, '') AS created_date
+    FROM users
+    WHERE school_id = $1
+    ORDER BY id
+    `, *schoolId)
+		if err != nil {
+			return err
+		}
+		data = users
+		header = []string{"id", "first_name", "last_name", "email", "role", "created_date"}
+		for _, u := range users {
+			records = append(records, []string{strconv.Itoa(u.ID), u.FirstName, u.LastName, u.Email, u.Role, u.CreatedDate})
+		}
+
+	case "entries":
+		var entries []exportedEntry
+		err := database.DBClient.Select(&entries, `
+    SELECT e.id, e.user_id, e.time_length::text AS time_length, e.total_questions,
+      e.correct_questions, e.notes_per_minute,
+      coalesce(e.created_date::text, '') AS created_date,
+      coalesce(e.created_time::text, '') AS created_time
+    FROM note_game_entries e
+    JOIN users u ON u.id = e.user_id
+    WHERE u.school_id = $1
+    ORDER BY e.user_id, e.created_date, e.created_time
+    `, *schoolId)
+		if err != nil {
+			return err
+		}
+		data = entries
+		header = []string{"id", "user_id", "time_length", "total_questions", "correct_questions", "notes_per_minute", "created_date", "created_time"}
+		for _, e := range entries {
+			records = append(records, []string{
+				strconv.Itoa(e.ID), strconv.Itoa(e.UserID), e.TimeLength,
+				strconv.Itoa(e.TotalQuestions), strconv.Itoa(e.CorrectQuestions), strconv.Itoa(e.NPM),
+				e.CreatedDate, e.CreatedTime,
+			})
+		}
+
+	default:
+		return fmt.Errorf("unknown export %q, use users or entries", *what)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
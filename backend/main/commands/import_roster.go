@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sight-reading/config"
+	"sight-reading/database"
+	"strings"
+
+	dtos "sight-reading/DTOs"
+)
+
+func runImportRoster(cfg config.Config, args []string) error {
+	fs := newFlagSet("import-roster")
+	file := fs.String("file", "", "csv with a first_name,last_name,email[,role] header")
+	schoolId := fs.Int("school", 0, "school every user in the roster belongs to")
+	teacherId := fs.Int("teacher", 0, "optional teacher the imported students are assigned to")
+	dryRun := fs.Bool("dry-run", false, "validate the roster without importing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" || *schoolId == 0 {
+		return fmt.Errorf("import-roster needs -file and -school")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users, err := parseRoster(f, int16(*schoolId))
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("%d users are valid, nothing was imported\n", len(users))
+		return nil
+	}
+
+	connect(cfg)
+
+	exists, err := schoolExists(database.DBClient, *schoolId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("school %d does not exist", *schoolId)
+	}
+
+	// all or nothing, a roster half imported is worse than none
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	linked := 0
+	for _, user := range users {
+		id, err := insertUser(tx, user)
+		if err != nil {
+			return fmt.Errorf("importing %s: %w", user.Email, err)
+		}
+
+		if *teacherId != 0 && user.Role == dtos.Student {
+			_, err := tx.Exec(`
+      INSERT INTO teacher_to_student (teacher_id, student_id)
+      VALUES ($1, $2)
+      `, *teacherId, id)
+			if err != nil {
+				return fmt.Errorf("assigning %s to teacher %d: %w", user.Email, *teacherId, err)
+			}
+			linked++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("imported %d users into school %d", len(users), *schoolId)
+	if *teacherId != 0 {
+		fmt.Printf(", %d assigned to teacher %d", linked, *teacherId)
+	}
+	fmt.Println()
+	return nil
+}
+
+// parseRoster reads and validates every row, all problems are reported
+// together with their line so the csv can be fixed in one go
+func parseRoster(r io.Reader, schoolId int16) ([]dtos.User, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the roster header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"first_name", "last_name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the roster is missing the %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var users []dtos.User
+	var problems []string
+	line := 1
+	for {
+		record, err := reader.Read()
+		line++
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		role := dtos.Role(strings.ToUpper(field(record, "role")))
+		if role == "" {
+			role = dtos.Student
+		}
+
+		user := dtos.User{
+			FirstName: field(record, "first_name"),
+			LastName:  field(record, "last_name"),
+			Email:     field(record, "email"),
+			Role:      role,
+			SchoolID:  schoolId,
+		}
+		if err := user.ValidateUser(); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %s", line, strings.ReplaceAll(err.Error(), ",\n", ", ")))
+			continue
+		}
+		users = append(users, user)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New("the roster has invalid rows:\n" + strings.Join(problems, "\n"))
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("the roster has no rows")
+	}
+	return users, nil
+}
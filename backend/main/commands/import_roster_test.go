@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	dtos "sight-reading/DTOs"
+)
+
+// NOTE: Happy path
+func TestParseRoster(t *testing.T) {
+	roster := `first_name,last_name,email,role
+Noe,Trevino,noe.trevino@mail.com,teacher
+Ana,Lopez,ana.lopez@mail.com,
+`
+	users, err := parseRoster(strings.NewReader(roster), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+	if users[0].Role != dtos.Teacher || users[1].Role != dtos.Student {
+		t.Fatalf("unexpected roles %s and %s", users[0].Role, users[1].Role)
+	}
+	if users[1].SchoolID != 4 {
+		t.Fatalf("expected the school id to be applied, got %d", users[1].SchoolID)
+	}
+}
+
+// NOTE: Sad path
+func TestParseRosterReportsEveryBadLine(t *testing.T) {
+	roster := `first_name,last_name,email
+Noe,Trevino,not-an-email
+Ana,,ana.lopez@mail.com
+`
+	_, err := parseRoster(strings.NewReader(roster), 4)
+	if err == nil {
+		t.Fatal("expected the roster to be rejected")
+	}
+	if !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("expected both bad lines to be reported, got: %v", err)
+	}
+}
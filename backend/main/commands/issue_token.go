@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sight-reading/auth"
+	"sight-reading/config"
+	"sight-reading/database"
+	"time"
+
+	dtos "sight-reading/DTOs"
+)
+
+// there is no login yet, until there is tokens are handed out by hand
+func runIssueToken(cfg config.Config, args []string) error {
+	fs := newFlagSet("issue-token")
+	userId := fs.Int("user", 0, "user the token is for")
+	ttl := fs.Duration("ttl", 24*time.Hour, "how long the token is valid")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *userId == 0 {
+		return fmt.Errorf("issue-token needs -user")
+	}
+	if cfg.AuthSecret == "" {
+		return fmt.Errorf("AUTH_SECRET is not set")
+	}
+	auth.SetSecret(cfg.AuthSecret)
+
+	connect(cfg)
+
+	var user struct {
+		Role     dtos.Role `db:"role"`
+		SchoolID int16     `db:"school_id"`
+	}
+	err := database.DBClient.Get(&user, `SELECT role, school_id FROM users WHERE id = $1`, *userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user %d does not exist", *userId)
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.Issue(auth.Claims{UserID: *userId, Role: user.Role, SchoolID: user.SchoolID}, *ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+	return nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"fmt"
+	"sight-reading/config"
+	"sight-reading/database"
+	"time"
+)
+
+func runMigrate(cfg config.Config, args []string) error {
+	fs := newFlagSet("migrate")
+	steps := fs.Int("steps", 1, "how many migrations down reverts")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: sight-reading migrate [-steps n] up|down|status")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("migrate needs exactly one of up, down or status")
+	}
+
+	connect(cfg)
+
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := database.MigrateUp()
+		for _, migration := range applied {
+			fmt.Printf("applied  %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("nothing to apply, the database is up to date")
+		}
+		return err
+
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("steps must be at least 1")
+		}
+		reverted, err := database.MigrateDown(*steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := database.MigrationsStatus()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown migrate direction %q", fs.Arg(0))
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"sight-reading/config"
+	"sight-reading/database"
+)
+
+// the order matters, rows are deleted before the rows they reference. Tables
+// added by later migrations reference users with on delete cascade
+var purgeStatements = []struct {
+	table string
+	query string
+}{
+	{"note_game_entries", `DELETE FROM note_game_entries WHERE user_id IN (SELECT id FROM users WHERE school_id = $1)`},
+	{"teacher_to_student", `DELETE FROM teacher_to_student WHERE teacher_id IN (SELECT id FROM users WHERE school_id = $1) OR student_id IN (SELECT id FROM users WHERE school_id = $1)`},
+	{"teacher_to_parent", `DELETE FROM teacher_to_parent WHERE teacher_id IN (SELECT id FROM users WHERE school_id = $1) OR parent_id IN (SELECT id FROM users WHERE school_id = $1)`},
+	{"parent_to_child", `DELETE FROM parent_to_child WHERE parent_id IN (SELECT id FROM users WHERE school_id = $1) OR child_id IN (SELECT id FROM users WHERE school_id = $1)`},
+	{"users", `DELETE FROM users WHERE school_id = $1`},
+	{"schools", `DELETE FROM schools WHERE id = $1`},
+}
+
+func runPurgeSchool(cfg config.Config, args []string) error {
+	fs := newFlagSet("purge-school")
+	schoolId := fs.Int("school", 0, "school to delete")
+	yes := fs.Bool("yes", false, "actually delete, without it the command only reports what would go")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *schoolId == 0 {
+		return fmt.Errorf("purge-school needs -school")
+	}
+
+	connect(cfg)
+
+	exists, err := schoolExists(database.DBClient, *schoolId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("school %d does not exist", *schoolId)
+	}
+
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range purgeStatements {
+		result, err := tx.Exec(statement.query, *schoolId)
+		if err != nil {
+			return fmt.Errorf("purging %s: %w", statement.table, err)
+		}
+		deleted, _ := result.RowsAffected()
+		fmt.Printf("%-20s %d rows\n", statement.table, deleted)
+	}
+
+	if !*yes {
+		fmt.Println("dry run, nothing was deleted. Pass -yes to purge the school")
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("school %d purged\n", *schoolId)
+	return nil
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"sight-reading/config"
+	"sight-reading/database"
+	"sight-reading/grading"
+	"sight-reading/rating"
+	"sight-reading/theory"
+	"sort"
+	"time"
+)
+
+type ratedAnswer struct {
+	ID         int64     `db:"id"`
+	Pitch      string    `db:"pitch"`
+	Clef       string    `db:"clef"`
+	Correct    bool      `db:"correct"`
+	Fifths     *int      `db:"fifths"`
+	AnsweredAt time.Time `db:"answered_at"`
+}
+
+type ratedPerformance struct {
+	ID         int64     `db:"id"`
+	Difficulty float64   `db:"difficulty"`
+	Report     []byte    `db:"report"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// ratedSession is a note game session or a performance, replayed in the
+// order they happened
+type ratedSession struct {
+	source       string
+	sourceID     int64
+	at           time.Time
+	observations []rating.Observation
+}
+
+// runRecalculateRatings rebuilds skill ratings and their history from every
+// recorded note game answer and performance, after the rating model changes
+// or for sessions recorded while rating failed
+func runRecalculateRatings(cfg config.Config, args []string) error {
+	fs := newFlagSet("recalculate-ratings")
+	schoolId := fs.Int("school", 0, "recalculate every student of the school")
+	userId := fs.Int("user", 0, "recalculate one user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*schoolId == 0) == (*userId == 0) {
+		return fmt.Errorf("recalculate-ratings needs -school or -user")
+	}
+
+	connect(cfg)
+
+	var users []int
+	if *userId != 0 {
+		users = []int{*userId}
+	} else {
+		err := database.DBClient.Select(&users, `SELECT id FROM users WHERE school_id = $1 ORDER BY id`, *schoolId)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, user := range users {
+		sessions, err := ratedSessions(user)
+		if err != nil {
+			return fmt.Errorf("user %d: %w", user, err)
+		}
+		if err := replaySessions(user, sessions); err != nil {
+			return fmt.Errorf("user %d: %w", user, err)
+		}
+		if len(sessions) > 0 {
+			fmt.Printf("user %d: %d sessions rated\n", user, len(sessions))
+		}
+	}
+	return nil
+}
+
+// ratedSessions is every session of user, oldest first. The answers of a
+// note game session were recorded in one transaction and share its time
+func ratedSessions(user int) ([]ratedSession, error) {
+	var answers []ratedAnswer
+	err := database.DBClient.Select(&answers, `
+    SELECT id, pitch, clef, correct, fifths, answered_at
+    FROM note_game_answers
+    WHERE user_id = $1
+    ORDER BY answered_at, id
+    `, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []ratedSession
+	var batch []rating.NoteAnswer
+	for i, answer := range answers {
+		pitch, err := theory.ParsePitch(answer.Pitch)
+		if err != nil {
+			return nil, fmt.Errorf("answer %d: %w", answer.ID, err)
+		}
+		clef, err := theory.ParseClef(answer.Clef)
+		if err != nil {
+			return nil, fmt.Errorf("answer %d: %w", answer.ID, err)
+		}
+		if i == 0 || !answer.AnsweredAt.Equal(answers[i-1].AnsweredAt) {
+			sessions = append(sessions, ratedSession{source: rating.SourceNoteGame, sourceID: answer.ID, at: answer.AnsweredAt})
+			batch = nil
+		}
+		batch = append(batch, rating.NoteAnswer{Pitch: pitch, Clef: clef, Fifths: answer.Fifths, Correct: answer.Correct})
+		sessions[len(sessions)-1].observations = rating.NoteObservations(batch)
+	}
+
+	var performances []ratedPerformance
+	err = database.DBClient.Select(&performances, `
+    SELECT p.id, e.difficulty, p.report, p.created_at
+    FROM performances p
+    JOIN exercises e ON e.id = p.exercise_id
+    WHERE p.user_id = $1
+    ORDER BY p.created_at, p.id
+    `, user)
+	if err != nil {
+		return nil, err
+	}
+	for _, performance := range performances {
+		var result grading.Result
+		if err := json.Unmarshal(performance.Report, &result); err != nil {
+			return nil, fmt.Errorf("performance %d: %w", performance.ID, err)
+		}
+		sessions = append(sessions, ratedSession{
+			source:       rating.SourcePerformance,
+			sourceID:     performance.ID,
+			at:           performance.CreatedAt,
+			observations: rating.PerformanceObservations(performance.Difficulty, result),
+		})
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].at.Before(sessions[j].at)
+	})
+	return sessions, nil
+}
+
+// replaySessions replaces the ratings of user with sessions rated in order,
+// all or nothing
+func replaySessions(user int, sessions []ratedSession) error {
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM skill_rating_history WHERE user_id = $1`, user); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM skill_ratings WHERE user_id = $1`, user); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if _, err := rating.Apply(tx, user, session.source, session.sourceID, session.at, session.observations); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
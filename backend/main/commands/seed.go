@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"fmt"
+	"sight-reading/config"
+	"sight-reading/generation"
+)
+
+func runSeed(cfg config.Config, args []string) error {
+	fs := newFlagSet("seed")
+	opts := generation.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	connect(cfg)
+
+	summary, err := generation.GenerateData(*opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("seed %d: %d schools, %d teachers, %d students, %d parents, %d entries\n",
+		summary.Seed, summary.Schools, summary.Teachers, summary.Students, summary.Parents, summary.Entries)
+	return nil
+}
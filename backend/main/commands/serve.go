@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/config"
+	"sight-reading/controllers"
+	"sight-reading/database"
+	"sight-reading/generation"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+	"sight-reading/music"
+	"sight-reading/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func runServe(cfg config.Config, args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", cfg.Addr, "address to listen on")
+	// kept from before the subcommands, `seed` is the better way
+	runPackage := fs.Bool("fake-it", false, "generate data before serving")
+	generationOptions := generation.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	connect(cfg)
+	metrics.RegisterDBStats(database.DBClient.DB)
+	auth.SetSecret(cfg.AuthSecret)
+	if err := music.Init(cfg); err != nil {
+		return err
+	}
+
+	if *runPackage {
+		_, err := generation.GenerateData(*generationOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return err
+	}
+	router.Use(
+		logging.RequestID(),
+		logging.Middleware(),
+		apperrors.Recovery(),
+		metrics.Middleware(),
+		apperrors.Middleware(),
+		ratelimit.Failures(ratelimit.Auth),
+		auth.Middleware(),
+		ratelimit.For(ratelimit.Default),
+	)
+
+	router.NoRoute(apperrors.NoRoute)
+
+	controllers.SetupRoutes(router)
+
+	return router.Run(*addr)
+}
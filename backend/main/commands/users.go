@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"fmt"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertUser works on the db or inside a transaction
+func insertUser(db sqlx.Ext, user dtos.User) (int, error) {
+	query := `
+  INSERT INTO users (
+    first_name,
+    last_name,
+    email,
+    school_id,
+    role
+  )
+  VALUES (
+    :first_name,
+    :last_name,
+    :email,
+    :school_id,
+    :role
+  )
+  RETURNING
+    id
+  `
+	rows, err := sqlx.NamedQuery(db, query, user)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var id int
+	if !rows.Next() {
+		return 0, fmt.Errorf("inserting %s %s returned no id", user.FirstName, user.LastName)
+	}
+	err = rows.Scan(&id)
+	return id, err
+}
+
+func schoolExists(db sqlx.Queryer, schoolId int) (bool, error) {
+	var exists bool
+	err := sqlx.Get(db, &exists, `SELECT EXISTS (SELECT 1 FROM schools WHERE id = $1)`, schoolId)
+	return exists, err
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is everything the binary reads from the environment, every
+// subcommand loads the same one
+type Config struct {
+	DatabaseURL string
+	Addr        string
+	LogLevel    slog.Level
+
+	// AuthSecret signs the bearer tokens, see the auth package
+	AuthSecret string
+
+	// the django music generation service
+	MusicServiceURL     string
+	MusicServiceToken   string
+	MusicServiceTimeout time.Duration
+
+	// MusicCache is where generated exercises are cached: memory, postgres,
+	// disk or off. MusicCacheDir is only used by disk, MusicCacheSize (bytes)
+	// only by memory. MusicCacheTTL is how long postgres and disk keep an
+	// exercise, 0 keeps them forever
+	MusicCache         string
+	MusicCacheDir      string
+	MusicCacheSize     int
+	MusicCacheVariants int
+	MusicCacheTTL      time.Duration
+
+	// TrustedProxies are the proxies whose X-Forwarded-For gives the client
+	// ip the rate limits count, none by default so a client cannot pick its
+	// own ip
+	TrustedProxies []string
+}
+
+func Load() Config {
+	config := Config{
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+		Addr:        ":5001",
+		LogLevel:    slog.LevelInfo,
+
+		AuthSecret: os.Getenv("AUTH_SECRET"),
+
+		MusicServiceURL:     "http://localhost:8000",
+		MusicServiceToken:   os.Getenv("MUSIC_SERVICE_TOKEN"),
+		MusicServiceTimeout: 10 * time.Second,
+
+		MusicCache:         "memory",
+		MusicCacheDir:      "music-cache",
+		MusicCacheSize:     64 << 20,
+		MusicCacheVariants: 12,
+		MusicCacheTTL:      30 * 24 * time.Hour,
+	}
+
+	if url := os.Getenv("MUSIC_SERVICE_URL"); url != "" {
+		config.MusicServiceURL = url
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("MUSIC_SERVICE_TIMEOUT")); err == nil {
+		config.MusicServiceTimeout = timeout
+	}
+
+	if cache := os.Getenv("MUSIC_CACHE"); cache != "" {
+		config.MusicCache = strings.ToLower(cache)
+	}
+	if dir := os.Getenv("MUSIC_CACHE_DIR"); dir != "" {
+		config.MusicCacheDir = dir
+	}
+	if size, err := strconv.Atoi(os.Getenv("MUSIC_CACHE_SIZE")); err == nil {
+		config.MusicCacheSize = size
+	}
+	if variants, err := strconv.Atoi(os.Getenv("MUSIC_CACHE_VARIANTS")); err == nil {
+		config.MusicCacheVariants = variants
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("MUSIC_CACHE_TTL")); err == nil && ttl >= 0 {
+		config.MusicCacheTTL = ttl
+	}
+
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			config.TrustedProxies = append(config.TrustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+
+	if addr := os.Getenv("ADDR"); addr != "" {
+		config.Addr = addr
+	}
+
+	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
+	case "debug":
+		config.LogLevel = slog.LevelDebug
+	case "warn":
+		config.LogLevel = slog.LevelWarn
+	case "error":
+		config.LogLevel = slog.LevelError
+	}
+
+	return config
+}
@@ -1,15 +1,116 @@
 package controllers
 
 import (
+	dtos "sight-reading/DTOs"
+	"sight-reading/auth"
+	"sight-reading/metrics"
+	"sight-reading/ratelimit"
 	"sight-reading/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// SetupRoutes registers every route of the api, new groups go here and in
+// DocumentedRoutes
+func SetupRoutes(router *gin.Engine) {
+	SetupTeacherRoutes(router)
+	SetupMusicRoutes(router)
+	SetupExerciseRoutes(router)
+	SetupInstrumentRoutes(router)
+	SetupRatingRoutes(router)
+	SetupRecommendationRoutes(router)
+	SetupPracticeRoutes(router)
+	SetupMetricsRoutes(router)
+	SetupDocsRoutes(router)
+}
+
 func SetupTeacherRoutes(router *gin.Engine) {
 	router.GET("/teachers", services.GetTeachers)
 	router.GET("/teacher/:id", services.GetTeacher)
 	router.GET("/students", services.GetStudents)
 	router.GET("/student/:id", services.GetStudent)
-	router.POST("/user", services.CreateUser)
+	router.POST("/user", ratelimit.For(ratelimit.Signup), services.CreateUser)
+}
+
+// SetupMusicRoutes proxies the django music service, every route needs a
+// signed in user so served exercises can be tied to them, and each user has
+// their own rate limit
+func SetupMusicRoutes(router *gin.Engine) {
+	group := router.Group("/music", auth.Require(dtos.Student, dtos.Teacher, dtos.Admin), ratelimit.For(ratelimit.Music))
+	group.POST("/mary", services.GenerateMary)
+	group.POST("/random", services.GenerateRandom)
+	group.POST("/note-game", services.GenerateNoteGame)
+	group.POST("/note-game/next", services.NextNoteQuestions)
+	group.POST("/note-game/answers", services.RecordNoteAnswers)
+	group.POST("/difficulty", services.RateDifficulty)
+	group.POST("/midi", services.ConvertToMIDI)
+	group.POST("/abc", services.ConvertToABC)
+	group.POST("/transpose", services.Transpose)
+	group.GET("/instruments", services.GetInstruments)
+}
+
+// SetupExerciseRoutes is the exercise library, teachers and admins manage it
+// and students read what they were assigned
+func SetupExerciseRoutes(router *gin.Engine) {
+	staff := auth.Require(dtos.Teacher, dtos.Admin)
+	signedIn := auth.Require(dtos.Student, dtos.Teacher, dtos.Admin)
+
+	router.POST("/exercises", staff, services.UploadExercise)
+	router.GET("/exercises", staff, services.SearchExercises)
+	router.GET("/exercises/:id", signedIn, services.GetExercise)
+	router.GET("/exercises/:id/xml", signedIn, services.GetExerciseXML)
+	router.GET("/exercises/:id/midi", signedIn, services.GetExerciseMIDI)
+	router.GET("/exercises/:id/abc", signedIn, services.GetExerciseABC)
+	router.PATCH("/exercises/:id", staff, services.UpdateExercise)
+	router.DELETE("/exercises/:id", staff, services.DeleteExercise)
+	router.POST("/exercises/:id/assignments", staff, services.AssignExercise)
+	router.POST("/exercises/:id/performances", signedIn, services.GradePerformance)
+	router.GET("/exercises/:id/performances", signedIn, services.GetPerformances)
+	router.GET("/assignments", auth.Require(dtos.Student), services.GetAssignments)
+}
+
+// SetupInstrumentRoutes is what each user plays, music routes default to the
+// primary instrument
+func SetupInstrumentRoutes(router *gin.Engine) {
+	signedIn := auth.Require(dtos.Student, dtos.Teacher, dtos.Admin)
+
+	router.GET("/users/:id/instruments", signedIn, services.GetUserInstruments)
+	router.PUT("/users/:id/instruments", signedIn, services.SetUserInstruments)
+}
+
+// SetupRatingRoutes is how well each student reads per skill, note game
+// sessions and graded performances move the ratings
+func SetupRatingRoutes(router *gin.Engine) {
+	signedIn := auth.Require(dtos.Student, dtos.Teacher, dtos.Admin)
+
+	router.GET("/users/:id/ratings", signedIn, services.GetSkillRatings)
+	router.GET("/users/:id/ratings/history", signedIn, services.GetSkillRatingHistory)
+}
+
+// SetupRecommendationRoutes nudges students to harder note game settings, a
+// teacher's students are their class and share its rule set
+func SetupRecommendationRoutes(router *gin.Engine) {
+	staff := auth.Require(dtos.Teacher, dtos.Admin)
+	signedIn := auth.Require(dtos.Student, dtos.Teacher, dtos.Admin)
+
+	router.GET("/users/:id/recommendation", signedIn, services.GetRecommendation)
+	router.GET("/teachers/:id/recommendation-rules", staff, services.GetRecommendationRules)
+	router.PUT("/teachers/:id/recommendation-rules", staff, services.SetRecommendationRules)
+}
+
+// SetupPracticeRoutes is streaks and daily goals, days are counted in the
+// school's timezone
+func SetupPracticeRoutes(router *gin.Engine) {
+	signedIn := auth.Require(dtos.Student, dtos.Teacher, dtos.Admin)
+
+	router.GET("/users/:id/streak", signedIn, services.GetStreak)
+	router.GET("/users/:id/goal", signedIn, services.GetGoal)
+	router.PUT("/users/:id/goal", signedIn, services.SetGoal)
+	router.DELETE("/users/:id/goal", signedIn, services.DeleteGoal)
+	router.GET("/users/:id/goal/progress", signedIn, services.GetGoalProgress)
+	router.PUT("/schools/:id/timezone", auth.Require(dtos.Admin), services.SetSchoolTimezone)
+}
+
+func SetupMetricsRoutes(router *gin.Engine) {
+	router.GET("/metrics", metrics.Handler())
 }
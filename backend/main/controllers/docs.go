@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"net/http"
+	"sight-reading/apperrors"
+	"sight-reading/difficulty"
+	"sight-reading/openapi"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// every route registered in this package has to be listed here,
+// tests/openapi_test.go fails when the two drift apart
+func DocumentedRoutes() []openapi.Route {
+	return []openapi.Route{
+		// teachers and students
+		{Method: "GET", Path: "/teachers", Summary: "List all teachers", Tags: []string{"users"}, Response: []dtos.User{}},
+		{Method: "GET", Path: "/teacher/:id", Summary: "Get a teacher", Tags: []string{"users"}, Response: dtos.User{}},
+		{Method: "GET", Path: "/students", Summary: "List all students", Tags: []string{"users"}, Response: []dtos.User{}},
+		{Method: "GET", Path: "/student/:id", Summary: "Get a student", Tags: []string{"users"}, Response: dtos.User{}},
+		{Method: "POST", Path: "/user", Summary: "Create a user", Tags: []string{"users"}, Request: dtos.User{}, Response: dtos.CreatedUser{}, Status: http.StatusCreated},
+
+		// music, proxied to the generation service
+		{Method: "POST", Path: "/music/mary", Auth: true, Summary: "Generate Mary had a little lamb in a key", Tags: []string{"music"}, Request: dtos.MaryRequest{}, Response: "", ContentType: "application/xml", Query: instrumentParams},
+		{Method: "POST", Path: "/music/random", Auth: true, Summary: "Generate a random melody", Tags: []string{"music"}, Request: dtos.RandomRequest{}, Response: "", ContentType: "application/xml", Query: instrumentParams},
+		{Method: "POST", Path: "/music/note-game", Auth: true, Summary: "Generate a note game question", Tags: []string{"music"}, Request: dtos.NoteGameRequest{}, Response: dtos.NoteGame{}, Query: instrumentParams},
+		{Method: "POST", Path: "/music/note-game/next", Auth: true, Summary: "The next note game questions, the notes the student is due to review first", Tags: []string{"music"}, Request: dtos.NoteSessionRequest{}, Response: dtos.NoteSession{}, Query: instrumentParams},
+		{Method: "POST", Path: "/music/note-game/answers", Auth: true, Summary: "Record the answers of a note game session and reschedule the notes asked", Tags: []string{"music"}, Request: dtos.NoteAnswersRequest{}, Response: []dtos.NoteReview{}, Status: http.StatusCreated},
+		{Method: "POST", Path: "/music/midi", Auth: true, Summary: "Convert a MusicXML exercise to a midi file", Tags: []string{"music"}, Request: "", RequestContentType: "application/xml", Response: "", ContentType: "audio/midi", Query: midiParams},
+		{Method: "POST", Path: "/music/abc", Auth: true, Summary: "Convert a MusicXML exercise to an ABC tune", Tags: []string{"music"}, Request: "", RequestContentType: "application/xml", Response: "", ContentType: "text/vnd.abc"},
+		{Method: "POST", Path: "/music/transpose", Auth: true, Summary: "Transpose a MusicXML exercise for an instrument or by an interval", Tags: []string{"music"}, Request: "", RequestContentType: "application/xml", Response: "", ContentType: "application/xml", Query: []openapi.Param{
+			{Name: "instrument", Description: "an instrument slug from /music/instruments", Type: "string"},
+			{Name: "interval", Description: `quality and number, "M2", "-P5"`, Type: "string"},
+		}},
+		{Method: "GET", Path: "/music/instruments", Auth: true, Summary: "Instruments exercises can be transposed for", Tags: []string{"music"}, Response: []dtos.Instrument{}},
+		{Method: "POST", Path: "/music/difficulty", Auth: true, Summary: "Rate how hard a MusicXML exercise is to sight read", Tags: []string{"music"}, Request: "", RequestContentType: "application/xml", Response: difficulty.Report{}},
+
+		// exercise library
+		{Method: "POST", Path: "/exercises", Auth: true, Summary: "Upload a MusicXML or ABC exercise (multipart: file or abc, title, composer, instrument, tags, shared)", Tags: []string{"exercises"}, Request: exerciseUpload{}, RequestContentType: "multipart/form-data", Response: dtos.Exercise{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/exercises", Auth: true, Summary: "Search the school's library", Tags: []string{"exercises"}, Response: []dtos.Exercise{}, Query: []openapi.Param{
+			{Name: "q", Description: "matches title and composer", Type: "string"},
+			{Name: "tag", Type: "string"},
+			{Name: "instrument", Type: "string"},
+			{Name: "key", Description: `eg "E- major"`, Type: "string"},
+			{Name: "min_difficulty", Type: "number"},
+			{Name: "max_difficulty", Type: "number"},
+			{Name: "mine", Description: "only my uploads", Type: "boolean"},
+			{Name: "limit", Description: "default 50, at most 200", Type: "integer"},
+			{Name: "offset", Type: "integer"},
+		}},
+		{Method: "GET", Path: "/exercises/:id", Auth: true, Summary: "Get an exercise", Tags: []string{"exercises"}, Response: dtos.Exercise{}},
+		{Method: "GET", Path: "/exercises/:id/xml", Auth: true, Summary: "Download the MusicXML of an exercise", Tags: []string{"exercises"}, Response: "", ContentType: "application/xml", Query: []openapi.Param{
+			{Name: "instrument", Description: "transpose for an instrument slug from /music/instruments, defaults to the user's primary instrument", Type: "string"},
+		}},
+		{Method: "GET", Path: "/exercises/:id/abc", Auth: true, Summary: "Download an exercise as an ABC tune", Tags: []string{"exercises"}, Response: "", ContentType: "text/vnd.abc", Query: instrumentParams},
+		{Method: "GET", Path: "/exercises/:id/midi", Auth: true, Summary: "Download an exercise as a midi file", Tags: []string{"exercises"}, Response: "", ContentType: "audio/midi", Query: midiParams},
+		{Method: "PATCH", Path: "/exercises/:id", Auth: true, Summary: "Edit the metadata of an exercise", Tags: []string{"exercises"}, Request: dtos.ExerciseMetadata{}, Response: dtos.Exercise{}},
+		{Method: "DELETE", Path: "/exercises/:id", Auth: true, Summary: "Delete an exercise", Tags: []string{"exercises"}, Status: http.StatusNoContent},
+		{Method: "POST", Path: "/exercises/:id/assignments", Auth: true, Summary: "Assign an exercise to students", Tags: []string{"exercises"}, Request: dtos.AssignmentRequest{}, Response: map[string]int{}, Status: http.StatusCreated},
+		{Method: "POST", Path: "/exercises/:id/performances", Auth: true, Summary: "Grade a midi recording of the signed in user playing an exercise", Tags: []string{"exercises"}, Request: "", RequestContentType: "audio/midi", Response: dtos.Performance{}, Status: http.StatusCreated, Query: []openapi.Param{
+			{Name: "part", Description: "the part id to grade against, defaults to the first part", Type: "string"},
+			{Name: "tolerance", Description: "how far off in quarter notes an onset is still in time, default 0.25", Type: "number"},
+		}},
+		{Method: "GET", Path: "/exercises/:id/performances", Auth: true, Summary: "Graded performances of an exercise, newest first", Tags: []string{"exercises"}, Response: []dtos.Performance{}, Query: []openapi.Param{
+			{Name: "limit", Description: "default 50, at most 200", Type: "integer"},
+			{Name: "offset", Type: "integer"},
+		}},
+		{Method: "GET", Path: "/assignments", Auth: true, Summary: "Exercises assigned to the signed in student", Tags: []string{"exercises"}, Response: []dtos.Assignment{}},
+
+		// instruments
+		{Method: "GET", Path: "/users/:id/instruments", Auth: true, Summary: "Instruments a user plays, primary first", Tags: []string{"instruments"}, Response: []dtos.UserInstrument{}},
+		{Method: "PUT", Path: "/users/:id/instruments", Auth: true, Summary: "Replace the instruments a user plays", Tags: []string{"instruments"}, Request: dtos.SetInstrumentsRequest{}, Status: http.StatusNoContent},
+
+		// skill ratings
+		{Method: "GET", Path: "/users/:id/ratings", Auth: true, Summary: "A user's rating on every skill", Tags: []string{"ratings"}, Response: []dtos.SkillRating{}},
+		{Method: "GET", Path: "/users/:id/ratings/history", Auth: true, Summary: "How a user's ratings moved, oldest first", Tags: []string{"ratings"}, Response: []dtos.SkillRatingPoint{}, Query: []openapi.Param{
+			{Name: "dimension", Description: "treble_notes, bass_notes, key_signatures or rhythm", Type: "string"},
+			{Name: "since", Description: "a date or RFC 3339 time", Type: "string"},
+			{Name: "limit", Description: "default 50, at most 200", Type: "integer"},
+			{Name: "offset", Type: "integer"},
+		}},
+
+		// recommendations
+		{Method: "GET", Path: "/users/:id/recommendation", Auth: true, Summary: "Recommend a student's next note game settings from their latest sessions", Tags: []string{"recommendations"}, Response: dtos.Recommendation{}, Query: []openapi.Param{
+			{Name: "scale", Description: "the current major key tonic, default C", Type: "string"},
+			{Name: "octave", Description: "default 4", Type: "integer"},
+			{Name: "octaves", Description: "default 1", Type: "integer"},
+			{Name: "note_count", Description: "default the latest session's", Type: "integer"},
+			{Name: "tempo", Description: "beats per minute, default 60", Type: "integer"},
+		}},
+		{Method: "GET", Path: "/teachers/:id/recommendation-rules", Auth: true, Summary: "The rule set of a teacher's class", Tags: []string{"recommendations"}, Response: dtos.RecommendationRules{}},
+		{Method: "PUT", Path: "/teachers/:id/recommendation-rules", Auth: true, Summary: "Replace the rule set of a teacher's class, left out fields take the defaults", Tags: []string{"recommendations"}, Request: dtos.RecommendationRules{}, Response: dtos.RecommendationRules{}},
+
+		// streaks and goals
+		{Method: "GET", Path: "/users/:id/streak", Auth: true, Summary: "A student's current and longest streak of practice days", Tags: []string{"practice"}, Response: dtos.Streak{}},
+		{Method: "GET", Path: "/users/:id/goal", Auth: true, Summary: "A student's daily goal", Tags: []string{"practice"}, Response: dtos.PracticeGoal{}},
+		{Method: "PUT", Path: "/users/:id/goal", Auth: true, Summary: "Set a student's daily goal in minutes, questions or both", Tags: []string{"practice"}, Request: dtos.GoalRequest{}, Response: dtos.PracticeGoal{}},
+		{Method: "DELETE", Path: "/users/:id/goal", Auth: true, Summary: "Remove a student's daily goal", Tags: []string{"practice"}, Status: http.StatusNoContent},
+		{Method: "GET", Path: "/users/:id/goal/progress", Auth: true, Summary: "A student's practice of the latest days against their goal", Tags: []string{"practice"}, Response: dtos.GoalProgress{}, Query: []openapi.Param{
+			{Name: "days", Description: "default 1, today only, at most 31", Type: "integer"},
+		}},
+		{Method: "PUT", Path: "/schools/:id/timezone", Auth: true, Summary: "Set the timezone a school's practice days are counted in", Tags: []string{"practice"}, Request: dtos.TimezoneRequest{}, Status: http.StatusNoContent},
+
+		// operations
+		{Method: "GET", Path: "/metrics", Summary: "Prometheus metrics", Tags: []string{"operations"}, Response: "", ContentType: "text/plain"},
+		{Method: "GET", Path: "/openapi.json", Summary: "This document", Tags: []string{"operations"}, Response: map[string]any{}},
+		{Method: "GET", Path: "/docs", Summary: "Swagger ui for this document", Tags: []string{"operations"}, Response: "", ContentType: "text/html"},
+	}
+}
+
+var instrumentParams = []openapi.Param{
+	{Name: "instrument", Description: "an instrument slug from /music/instruments, defaults to the user's primary instrument", Type: "string"},
+}
+
+var midiParams = []openapi.Param{
+	{Name: "tempo", Description: "quarter notes per minute, defaults to the score's or 100", Type: "number"},
+	{Name: "program", Description: "General MIDI program for every part, 0 is piano", Type: "integer"},
+}
+
+// exerciseUpload documents the multipart form of POST /exercises
+type exerciseUpload struct {
+	// File is MusicXML, or ABC when its name ends in .abc
+	File string `json:"file"`
+	// ABC is a tune pasted instead of a file
+	ABC        string   `json:"abc"`
+	Title      string   `json:"title"`
+	Composer   string   `json:"composer"`
+	Instrument string   `json:"instrument"`
+	Tags       []string `json:"tags"`
+	Shared     bool     `json:"shared"`
+}
+
+// the custom validate tags from the validations package
+var validationRules = map[string]openapi.TagRule{
+	"len255": func(s *openapi.Schema, _ string) {
+		maxLength := 255
+		s.MaxLength = &maxLength
+	},
+	"time": func(s *openapi.Schema, _ string) {
+		s.Pattern = "^([01][0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9]$"
+	},
+	"role": func(s *openapi.Schema, _ string) {
+		s.Enum = []any{dtos.Teacher, dtos.Student, dtos.Parent, dtos.Admin}
+	},
+}
+
+func OpenAPIDocument() *openapi.Document {
+	return openapi.Generate(openapi.Config{
+		Info: openapi.Info{
+			Title:   "Tremolo",
+			Version: "0.1.0",
+		},
+		Routes:  DocumentedRoutes(),
+		Rules:   validationRules,
+		Problem: apperrors.Problem{},
+		Bearer: &openapi.SecurityScheme{
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "<payload>.<signature> token signed with AUTH_SECRET, issue one with the issue-token command",
+		},
+		Models: []any{
+			dtos.School{},
+			dtos.Entry{},
+			dtos.TeacherStudents{},
+			dtos.NoteGame{},
+			dtos.NoteGameRequest{},
+			dtos.MaryRequest{},
+			dtos.RandomRequest{},
+		},
+	})
+}
+
+func SetupDocsRoutes(router *gin.Engine) {
+	router.GET("/openapi.json", openapi.Handler(OpenAPIDocument()))
+	router.GET("/docs", openapi.DocsHandler("Tremolo API", "/openapi.json"))
+}
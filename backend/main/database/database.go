@@ -1,8 +1,7 @@
 package database
 
 import (
-	"os"
-	"strings"
+	"sight-reading/logging"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -10,16 +9,10 @@ import (
 
 var DBClient *sqlx.DB
 
-func InitializeDBConnection() {
-	DBConnectionString := os.Getenv("DATABASE_URL")
-
+func InitializeDBConnection(DBConnectionString string) {
 	db, err := sqlx.Open("postgres", DBConnectionString)
 	if err != nil {
 		panic(err.Error())
-	} else {
-		println(strings.Repeat("------------------------------", 2))
-		println("\nConnected to database successfully\n")
-		println(strings.Repeat("------------------------------", 2))
 	}
 
 	err = db.Ping()
@@ -27,5 +20,7 @@ func InitializeDBConnection() {
 		panic(err.Error())
 	}
 
+	logging.Logger.Info("connected to database successfully")
+
 	DBClient = db
 }
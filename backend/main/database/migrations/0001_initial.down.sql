@@ -0,0 +1,6 @@
+drop table if exists parent_to_child;
+drop table if exists teacher_to_student;
+drop table if exists teacher_to_parent;
+drop table if exists note_game_entries;
+drop table if exists users;
+drop table if exists schools;
@@ -1,4 +1,4 @@
-create table schools (
+create table if not exists schools (
     id serial primary key,
     title varchar(255) not null,
     city varchar(255) not null,
@@ -9,7 +9,7 @@ create table schools (
     created_time time default current_time
 );
 
-create table users (
+create table if not exists users (
     id serial primary key,
     first_name varchar(255) not null,
     last_name varchar(255) not null,
@@ -20,7 +20,7 @@ create table users (
     created_time time default current_time
 );
 
-create table note_game_entries (
+create table if not exists note_game_entries (
     id serial primary key,
     user_id int not null references users (id),
     time_length time not null,
@@ -31,19 +31,19 @@ create table note_game_entries (
     created_time time default current_time
 );
 
-create table teacher_to_parent (
+create table if not exists teacher_to_parent (
     teacher_id int not null references users (id),
     parent_id int not null references users (id),
     primary key (teacher_id, parent_id)
 );
 
-create table teacher_to_student (
+create table if not exists teacher_to_student (
     teacher_id int not null references users (id),
     student_id int not null references users (id),
     primary key (teacher_id, student_id)
 );
 
-create table parent_to_child (
+create table if not exists parent_to_child (
     parent_id int not null references users (id),
     child_id int not null references users (id),
     primary key (parent_id, child_id)
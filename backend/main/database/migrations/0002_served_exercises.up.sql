@@ -0,0 +1,10 @@
+create table served_exercises (
+    id serial primary key,
+    user_id int not null references users (id) on delete cascade,
+    kind varchar(32) not null,
+    params jsonb not null,
+    note_name varchar(8),
+    served_at timestamptz not null default now()
+);
+
+create index served_exercises_user_id_idx on served_exercises (user_id, served_at);
@@ -0,0 +1,5 @@
+create table generated_exercises (
+    key char(64) primary key,
+    body bytea not null,
+    created_at timestamptz not null default now()
+);
@@ -0,0 +1,2 @@
+drop table if exists exercise_assignments;
+drop table if exists exercises;
@@ -0,0 +1,27 @@
+create table exercises (
+    id serial primary key,
+    school_id int not null references schools (id) on delete cascade,
+    uploaded_by int references users (id) on delete set null,
+    title varchar(255) not null,
+    composer varchar(255) not null default '',
+    instrument varchar(255) not null default '',
+    key varchar(16) not null default '',
+    difficulty numeric(4, 2) not null,
+    tags text[] not null default '{}',
+    shared boolean not null default false,
+    checksum char(64) not null,
+    body bytea not null,
+    created_at timestamptz not null default now(),
+    unique (school_id, checksum)
+);
+
+create index exercises_school_id_idx on exercises (school_id);
+create index exercises_tags_idx on exercises using gin (tags);
+
+create table exercise_assignments (
+    exercise_id int not null references exercises (id) on delete cascade,
+    student_id int not null references users (id) on delete cascade,
+    assigned_by int references users (id) on delete set null,
+    assigned_at timestamptz not null default now(),
+    primary key (exercise_id, student_id)
+);
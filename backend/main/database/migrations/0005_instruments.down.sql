@@ -0,0 +1,2 @@
+drop table if exists user_instruments;
+drop table if exists instruments;
@@ -0,0 +1,42 @@
+-- transpose is the interval from written to sounding pitch (see theory
+-- ParseInterval), the range is written pitch. An empty clef keeps whatever
+-- clef the exercise has
+create table instruments (
+    id serial primary key,
+    slug varchar(32) not null unique,
+    name varchar(255) not null,
+    family varchar(32) not null,
+    clef varchar(16) not null default '',
+    transpose varchar(8) not null default 'P1',
+    range_low varchar(8) not null,
+    range_high varchar(8) not null,
+    program int not null default 0
+);
+
+insert into instruments (slug, name, family, clef, transpose, range_low, range_high, program) values
+    ('concert', 'Concert pitch', 'concert', '', 'P1', 'C2', 'C7', 0),
+    ('piano', 'Piano', 'keyboard', 'treble', 'P1', 'A0', 'C8', 0),
+    ('flute', 'Flute', 'woodwind', 'treble', 'P1', 'C4', 'C7', 73),
+    ('oboe', 'Oboe', 'woodwind', 'treble', 'P1', 'B-3', 'A6', 68),
+    ('bassoon', 'Bassoon', 'woodwind', 'bass', 'P1', 'B-1', 'E-5', 70),
+    ('bb-clarinet', 'Clarinet in B♭', 'woodwind', 'treble', '-M2', 'E3', 'C7', 71),
+    ('eb-alto-sax', 'Alto Saxophone', 'woodwind', 'treble', '-M6', 'B-3', 'F6', 65),
+    ('bb-tenor-sax', 'Tenor Saxophone', 'woodwind', 'treble', '-M9', 'B-3', 'F6', 66),
+    ('eb-bari-sax', 'Baritone Saxophone', 'woodwind', 'treble', '-M13', 'B-3', 'F6', 67),
+    ('bb-trumpet', 'Trumpet in B♭', 'brass', 'treble', '-M2', 'F#3', 'D6', 56),
+    ('f-horn', 'Horn in F', 'brass', 'treble', '-P5', 'F#3', 'C6', 60),
+    ('trombone', 'Trombone', 'brass', 'bass', 'P1', 'E2', 'F5', 57),
+    ('tuba', 'Tuba', 'brass', 'bass', 'P1', 'D1', 'F4', 58),
+    ('violin', 'Violin', 'strings', 'treble', 'P1', 'G3', 'E7', 40),
+    ('viola', 'Viola', 'strings', 'alto', 'P1', 'C3', 'E6', 41),
+    ('cello', 'Cello', 'strings', 'bass', 'P1', 'C2', 'C6', 42);
+
+create table user_instruments (
+    user_id int not null references users (id) on delete cascade,
+    instrument_id int not null references instruments (id) on delete cascade,
+    is_primary boolean not null default false,
+    primary key (user_id, instrument_id)
+);
+
+-- at most one primary instrument per user
+create unique index user_instruments_primary_idx on user_instruments (user_id) where is_primary;
@@ -0,0 +1,15 @@
+create table performances (
+    id serial primary key,
+    user_id int not null references users (id) on delete cascade,
+    exercise_id int not null references exercises (id) on delete cascade,
+    score numeric(4, 3) not null,
+    pitch_accuracy numeric(4, 3) not null,
+    rhythm_accuracy numeric(4, 3) not null,
+    tempo numeric(6, 1) not null,
+    -- the full grading, missed, extra and wrong notes included
+    report jsonb not null,
+    created_at timestamptz not null default now()
+);
+
+create index performances_user_id_idx on performances (user_id, exercise_id, created_at);
+create index performances_exercise_id_idx on performances (exercise_id);
@@ -0,0 +1,2 @@
+drop table if exists note_reviews;
+drop table if exists note_game_answers;
@@ -0,0 +1,32 @@
+-- every note game answer, pitch and answer are written pitch ("F#4") on the
+-- clef the question was shown in
+create table note_game_answers (
+    id bigserial primary key,
+    user_id int not null references users (id) on delete cascade,
+    pitch varchar(8) not null,
+    clef varchar(8) not null default 'treble',
+    answer varchar(8) not null default '',
+    correct boolean not null,
+    response_ms int not null,
+    answered_at timestamptz not null default now()
+);
+
+create index note_game_answers_user_id_idx on note_game_answers (user_id, answered_at);
+
+-- the SM-2 schedule of each note a student has been asked, see the
+-- repetition package
+create table note_reviews (
+    user_id int not null references users (id) on delete cascade,
+    pitch varchar(8) not null,
+    clef varchar(8) not null,
+    ease numeric(4, 2) not null default 2.5,
+    interval_days numeric(8, 3) not null default 0,
+    repetitions int not null default 0,
+    lapses int not null default 0,
+    reviews int not null default 0,
+    due_at timestamptz not null,
+    reviewed_at timestamptz not null default now(),
+    primary key (user_id, pitch, clef)
+);
+
+create index note_reviews_due_at_idx on note_reviews (user_id, clef, due_at);
@@ -0,0 +1,3 @@
+drop table if exists skill_rating_history;
+drop table if exists skill_ratings;
+alter table note_game_answers drop column if exists fifths;
@@ -0,0 +1,29 @@
+-- the key of the note game session an answer was in, in fifths, null when
+-- the game did not say
+alter table note_game_answers add column fifths int;
+
+-- the current rating of each student per skill, see the rating package
+create table skill_ratings (
+    user_id int not null references users (id) on delete cascade,
+    dimension varchar(32) not null,
+    rating numeric(7, 2) not null,
+    sessions int not null default 0,
+    updated_at timestamptz not null default now(),
+    primary key (user_id, dimension)
+);
+
+-- every change of a rating, source_id is the performance or the first
+-- answer of the note game session that moved it
+create table skill_rating_history (
+    id bigserial primary key,
+    user_id int not null references users (id) on delete cascade,
+    dimension varchar(32) not null,
+    rating numeric(7, 2) not null,
+    delta numeric(7, 2) not null,
+    observations int not null,
+    source varchar(16) not null,
+    source_id bigint not null,
+    rated_at timestamptz not null
+);
+
+create index skill_rating_history_user_id_idx on skill_rating_history (user_id, dimension, rated_at);
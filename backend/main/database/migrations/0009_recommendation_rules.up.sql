@@ -0,0 +1,8 @@
+-- the rule set a teacher's class is recommended settings with, a teacher's
+-- students are their class. Fields left out of rules take the defaults of
+-- the recommend package
+create table recommendation_rules (
+    teacher_id int primary key references users (id) on delete cascade,
+    rules jsonb not null,
+    updated_at timestamptz not null default now()
+);
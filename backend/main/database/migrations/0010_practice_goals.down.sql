@@ -0,0 +1,3 @@
+drop index if exists note_game_entries_user_id_idx;
+drop table if exists practice_goals;
+alter table schools drop column if exists timezone;
@@ -0,0 +1,15 @@
+-- practice days are counted in the school's timezone, an IANA name
+alter table schools add column timezone varchar(64) not null default 'UTC';
+
+-- a student's daily goal, in minutes of note game practice, questions
+-- answered or both. set_by is the student or the teacher who set it
+create table practice_goals (
+    user_id int primary key references users (id) on delete cascade,
+    daily_minutes int,
+    daily_questions int,
+    set_by int references users (id) on delete set null,
+    updated_at timestamptz not null default now(),
+    check (daily_minutes is not null or daily_questions is not null)
+);
+
+create index note_game_entries_user_id_idx on note_game_entries (user_id, created_date);
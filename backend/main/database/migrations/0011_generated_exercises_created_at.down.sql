@@ -0,0 +1 @@
+drop index if exists generated_exercises_created_at_idx;
@@ -0,0 +1,2 @@
+-- the music cache sweep deletes exercises by age
+create index generated_exercises_created_at_idx on generated_exercises (created_at);
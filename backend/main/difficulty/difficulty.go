@@ -0,0 +1,234 @@
+// Package difficulty rates how hard a MusicXML exercise is to sight read.
+// Each feature is measured, normalized to 0 (trivial) to 1 (as hard as the
+// scale goes) between an easy and a hard anchor, and the weighted mean maps
+// to a level from 1 to 10
+package difficulty
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+)
+
+// DefaultTempo is assumed when the score does not say, in quarter notes per
+// minute
+const DefaultTempo = 100
+
+type Feature struct {
+	Name string `json:"name"`
+	// Value is the raw measurement, in Unit
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+	// Score is Value normalized to 0 - 1
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+}
+
+type Report struct {
+	Level    float64   `json:"level"`
+	Band     string    `json:"band"`
+	Notes    int       `json:"notes"`
+	Features []Feature `json:"features"`
+}
+
+// feature anchors, Value at easy scores 0 and at hard scores 1. They are
+// tuned on the generated exercises: mary in C is a 1, a two octave
+// sixteenth note melody in Db with accidentals is a 10
+var anchors = []struct {
+	name       string
+	unit       string
+	easy, hard float64
+	weight     float64
+}{
+	{"range", "semitones", 5, 24, 1},
+	{"leaps", "mean semitones", 1, 7, 1.5},
+	{"largest_leap", "semitones", 4, 12, 0.5},
+	{"rhythmic_density", "notes per beat", 1, 4, 1.5},
+	{"rhythmic_variety", "distinct durations", 1, 6, 1},
+	{"key_signature", "sharps or flats", 0, 6, 1},
+	{"accidentals", "share of notes", 0, 0.3, 1},
+	{"tempo", "notes per second", 1, 6, 1.5},
+}
+
+var ErrNoNotes = errors.New("the score has no notes to rate")
+
+// Rate scores the first part of score, exercises are single part
+func Rate(score *musicxml.Score) (Report, error) {
+	var report Report
+	if len(score.Parts) == 0 {
+		return report, ErrNoNotes
+	}
+
+	m, err := measure(&score.Parts[0])
+	if err != nil {
+		return report, err
+	}
+	if m.notes == 0 {
+		return report, ErrNoNotes
+	}
+
+	values := map[string]float64{
+		"range":            m.rangeSemitones,
+		"leaps":            m.meanLeap,
+		"largest_leap":     m.largestLeap,
+		"rhythmic_density": m.notesPerBeat,
+		"rhythmic_variety": float64(m.durations),
+		"key_signature":    float64(m.keyFifths),
+		"accidentals":      m.accidentalShare,
+		"tempo":            m.notesPerBeat * m.tempo / 60,
+	}
+
+	var weighted, totalWeight float64
+	for _, anchor := range anchors {
+		value := values[anchor.name]
+		normalized := clamp((value-anchor.easy)/(anchor.hard-anchor.easy), 0, 1)
+
+		report.Features = append(report.Features, Feature{
+			Name:   anchor.name,
+			Value:  round(value),
+			Unit:   anchor.unit,
+			Score:  round(normalized),
+			Weight: anchor.weight,
+		})
+		weighted += normalized * anchor.weight
+		totalWeight += anchor.weight
+	}
+
+	report.Notes = m.notes
+	report.Level = round(1 + 9*weighted/totalWeight)
+	report.Band = Band(report.Level)
+	return report, nil
+}
+
+// Band puts a level in school terms
+func Band(level float64) string {
+	switch {
+	case level < 3:
+		return "beginner (grades 4 to 6)"
+	case level < 5:
+		return "intermediate (grades 6 to 8)"
+	case level < 7:
+		return "advanced (grades 9 to 10)"
+	}
+	return "expert (grades 11 to 12 and up)"
+}
+
+type measurements struct {
+	notes           int
+	rangeSemitones  float64
+	meanLeap        float64
+	largestLeap     float64
+	notesPerBeat    float64
+	durations       int
+	keyFifths       int
+	accidentalShare float64
+	tempo           float64
+}
+
+func measure(part *musicxml.Part) (measurements, error) {
+	m := measurements{tempo: DefaultTempo}
+
+	timeline := part.Timeline()
+	keys, firstKey := keySignatures(part)
+
+	lowest, highest := math.MaxInt, math.MinInt
+	previous := -1
+	leaps, transitions, accidentals := 0.0, 0, 0
+	durations := map[float64]bool{}
+	onsets := map[float64]bool{}
+	length := 0.0
+
+	for _, timed := range timeline {
+		note := timed.Note
+		length = max(length, timed.Onset+timed.Duration)
+		if note.IsGrace() {
+			continue
+		}
+		onsets[timed.Onset] = true
+		durations[timed.Duration] = true
+		if note.Pitch == nil {
+			continue
+		}
+
+		m.notes++
+		pitch, err := theory.FromMusicXML(*note.Pitch)
+		if err != nil {
+			return m, fmt.Errorf("measure %s: %w", timed.Measure, err)
+		}
+		midi := pitch.MIDI()
+		lowest, highest = min(lowest, midi), max(highest, midi)
+
+		if pitch.Alter != theory.KeyAlter(keys[timed.Measure], pitch.Step) {
+			accidentals++
+		}
+
+		// leaps follow the melody, chord tones are not leaps
+		if !note.IsChord() {
+			if previous >= 0 {
+				leap := math.Abs(float64(midi - previous))
+				leaps += leap
+				transitions++
+				m.largestLeap = max(m.largestLeap, leap)
+			}
+			previous = midi
+		}
+	}
+
+	if m.notes == 0 {
+		return m, nil
+	}
+
+	m.rangeSemitones = float64(highest - lowest)
+	if transitions > 0 {
+		m.meanLeap = leaps / float64(transitions)
+	}
+	if length > 0 {
+		m.notesPerBeat = float64(len(onsets)) / length
+	}
+	m.durations = len(durations)
+	m.accidentalShare = float64(accidentals) / float64(m.notes)
+	m.keyFifths = abs(firstKey)
+
+	if tempo, ok := part.Tempo(); ok {
+		m.tempo = tempo
+	}
+	return m, nil
+}
+
+// keySignatures is the key signature in effect at the start of each measure,
+// by measure number, and the first one of the part. A number used twice
+// keeps its first measure
+func keySignatures(part *musicxml.Part) (map[string]int, int) {
+	keys := make(map[string]int, len(part.Measures))
+	fifths, first, found := 0, 0, false
+	for _, measure := range part.Measures {
+		if attributes := measure.Attributes(); attributes != nil && len(attributes.Keys) > 0 {
+			fifths = attributes.Keys[0].Fifths
+			if !found {
+				first, found = fifths, true
+			}
+		}
+		if _, ok := keys[measure.Number]; !ok {
+			keys[measure.Number] = fifths
+		}
+	}
+	return keys, first
+}
+
+func clamp(value, low, high float64) float64 {
+	return math.Max(low, math.Min(high, value))
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,196 @@
+package generation
+
+import (
+	"fmt"
+	dtos "sight-reading/DTOs"
+	"sight-reading/database"
+	"sight-reading/logging"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// copyFakeSchoolData loads a planned school with COPY inside one transaction.
+// COPY cannot return ids, so the user ids are reserved from the sequence up
+// front and written explicitly
+func copyFakeSchoolData(school fakeSchoolData) error {
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var users, teacherToStudent, parentToChild, teacherToParent, entries [][]any
+
+	ids, err := reserveUserIds(tx, school.users())
+	if err != nil {
+		return err
+	}
+	nextId := func() int {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	}
+
+	teacherIds := make([]int, len(school.teachers))
+	studentIds := make([][]int, len(school.teachers))
+
+	for t, teacher := range school.teachers {
+		teacherId := nextId()
+		teacherIds[t] = teacherId
+		users = append(users, userRow(teacherId, teacher.user))
+
+		for _, student := range teacher.students {
+			studentId := nextId()
+			studentIds[t] = append(studentIds[t], studentId)
+			users = append(users, userRow(studentId, student.user))
+			teacherToStudent = append(teacherToStudent, []any{teacherId, studentId})
+
+			for _, entry := range student.entries {
+				entries = append(entries, []any{
+					studentId,
+					entry.TimeLength,
+					entry.TotalQuestions,
+					entry.CorrectQuestions,
+					entry.NPM,
+					entry.CreatedDate,
+					entry.CreatedTime,
+				})
+			}
+		}
+	}
+
+	var parentIds []int
+	for _, family := range school.families {
+		for _, parent := range family.parents {
+			parentId := nextId()
+			parentIds = append(parentIds, parentId)
+			users = append(users, userRow(parentId, parent))
+
+			for _, child := range family.children {
+				parentToChild = append(parentToChild, []any{parentId, studentIds[child.teacher][child.student]})
+			}
+		}
+	}
+
+	for _, pair := range school.teacherParents() {
+		teacherToParent = append(teacherToParent, []any{teacherIds[pair[0]], parentIds[pair[1]]})
+	}
+
+	// users first, everything else references them
+	copies := []struct {
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"users", []string{"id", "first_name", "last_name", "email", "school_id", "role", "created_date", "created_time"}, users},
+		{"teacher_to_student", []string{"teacher_id", "student_id"}, teacherToStudent},
+		{"parent_to_child", []string{"parent_id", "child_id"}, parentToChild},
+		{"teacher_to_parent", []string{"teacher_id", "parent_id"}, teacherToParent},
+		{"note_game_entries", []string{"user_id", "time_length", "total_questions", "correct_questions", "notes_per_minute", "created_date", "created_time"}, entries},
+	}
+	for _, c := range copies {
+		err := copyRows(tx, c.table, c.columns, c.rows)
+		if err != nil {
+			return fmt.Errorf("copying into %s: %w", c.table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func copyRows(tx *sqlx.Tx, table string, columns []string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		_, err := stmt.Exec(row...)
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+
+	// an Exec without arguments flushes the buffered rows
+	_, err = stmt.Exec()
+	if err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+func reserveUserIds(tx *sqlx.Tx, count int) ([]int, error) {
+	ids := make([]int, 0, count)
+	err := tx.Select(&ids, `
+  SELECT nextval(pg_get_serial_sequence('users', 'id'))
+  FROM generate_series(1, $1)
+  `, count)
+	if err != nil {
+		return nil, fmt.Errorf("reserving user ids: %w", err)
+	}
+	return ids, nil
+}
+
+func userRow(id int, user dtos.User) []any {
+	return []any{
+		id,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.SchoolID,
+		string(user.Role),
+		user.CreatedDate,
+		user.CreatedTime,
+	}
+}
+
+// progress logs how far a run is, at most once every interval so a million
+// row run does not drown the logs. The row count of a school is only known
+// once it is planned, so the percentage goes by schools
+type progress struct {
+	schools     int
+	schoolsDone int
+	rows        int
+	started     time.Time
+	lastLog     time.Time
+	interval    time.Duration
+}
+
+func newProgress(schools int) *progress {
+	now := time.Now()
+	return &progress{schools: schools, started: now, lastLog: now, interval: 2 * time.Second}
+}
+
+// add records one loaded school of rows rows
+func (p *progress) add(rows int) {
+	p.schoolsDone++
+	p.rows += rows
+
+	now := time.Now()
+	if now.Sub(p.lastLog) < p.interval && p.schoolsDone < p.schools {
+		return
+	}
+	p.lastLog = now
+
+	elapsed := now.Sub(p.started).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.rows) / elapsed
+	}
+
+	logging.Logger.Info("generation progress",
+		"schools", p.schoolsDone,
+		"total_schools", p.schools,
+		"percent", fmt.Sprintf("%.1f", float64(p.schoolsDone)/float64(p.schools)*100),
+		"rows", p.rows,
+		"rows_per_second", int(rate),
+	)
+}
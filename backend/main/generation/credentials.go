@@ -0,0 +1,114 @@
+package generation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	dtos "sight-reading/DTOs"
+	"sight-reading/logging"
+	"strconv"
+	"strings"
+)
+
+const credentialsSampleSize = 5
+
+// credentialsWriter keeps track of the generated accounts so the parent facing
+// features can be demoed. Users have no passwords yet, an account is its email
+type credentialsWriter struct {
+	path   string
+	file   *os.File
+	csv    *csv.Writer
+	sample [][]string
+}
+
+func newCredentialsWriter(path string) (*credentialsWriter, error) {
+	writer := &credentialsWriter{path: path}
+	if path == "" {
+		return writer, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating the credentials file: %w", err)
+	}
+	writer.file = file
+	writer.csv = csv.NewWriter(file)
+
+	err = writer.csv.Write([]string{"role", "first_name", "last_name", "email", "school_id", "linked_to"})
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func accountRow(user dtos.User, linked []string) []string {
+	return []string{
+		string(user.Role),
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		strconv.Itoa(int(user.SchoolID)),
+		strings.Join(linked, ";"),
+	}
+}
+
+// write adds every account of the school, teachers are linked to nobody,
+// students to their teacher and parents to their children
+func (w *credentialsWriter) write(school fakeSchoolData) error {
+	var rows [][]string
+
+	for _, teacher := range school.teachers {
+		rows = append(rows, accountRow(teacher.user, nil))
+		for _, student := range teacher.students {
+			rows = append(rows, accountRow(student.user, []string{teacher.user.Email}))
+		}
+	}
+
+	for _, family := range school.families {
+		var children []string
+		for _, child := range family.children {
+			children = append(children, school.teachers[child.teacher].students[child.student].user.Email)
+		}
+		for _, parent := range family.parents {
+			row := accountRow(parent, children)
+			rows = append(rows, row)
+
+			// families with siblings make the better demo accounts
+			if len(w.sample) < credentialsSampleSize && len(children) > 1 {
+				w.sample = append(w.sample, row)
+			}
+		}
+	}
+
+	if w.csv == nil {
+		return nil
+	}
+	return w.csv.WriteAll(rows)
+}
+
+func (w *credentialsWriter) logSample() {
+	for _, row := range w.sample {
+		logging.Logger.Info("demo parent account",
+			"email", row[3],
+			"school_id", row[4],
+			"children", row[5],
+		)
+	}
+	if w.path != "" {
+		logging.Logger.Info("generated accounts written", "file", w.path)
+	}
+}
+
+// Close is safe to call more than once
+func (w *credentialsWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	w.csv.Flush()
+	err := w.csv.Error()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	return err
+}
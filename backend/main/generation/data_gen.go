@@ -1,18 +1,165 @@
 package generation
 
 import (
+	"flag"
 	"fmt"
-	"strings"
+	"sight-reading/logging"
+	"time"
 )
 
-func GenerateData() {
-	initFaker()
+// Options controls how much data GenerateData creates. The same Seed with the
+// same counts always generates the same data
+type Options struct {
+	Seed               uint64
+	Schools            int
+	TeachersPerSchool  int
+	StudentsPerTeacher int
+	// every family of siblings gets ParentsPerFamily parents, a student
+	// joins an existing family with SiblingRate chance
+	ParentsPerFamily  int
+	SiblingRate       float64
+	EntriesPerStudent int
+	// EndDate is the day of the most recent entry, defaults to today
+	EndDate time.Time
+	// Bulk loads with COPY in one transaction per school instead of one
+	// INSERT per row
+	Bulk bool
+	// CredentialsFile, when set, gets a csv of every generated account
+	CredentialsFile string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Schools:            10,
+		TeachersPerSchool:  1,
+		StudentsPerTeacher: 20,
+		ParentsPerFamily:   0,
+		SiblingRate:        0.15,
+		EntriesPerStudent:  50,
+		Bulk:               true,
+	}
+}
+
+// RegisterFlags adds the generator flags to fs, the returned options are
+// filled in once fs is parsed
+func RegisterFlags(fs *flag.FlagSet) *Options {
+	opts := DefaultOptions()
+	fs.Uint64Var(&opts.Seed, "seed", 0, "seed for the generator, 0 picks one from the clock")
+	fs.IntVar(&opts.Schools, "schools", opts.Schools, "number of schools to generate")
+	fs.IntVar(&opts.TeachersPerSchool, "teachers", opts.TeachersPerSchool, "teachers per school")
+	fs.IntVar(&opts.StudentsPerTeacher, "students", opts.StudentsPerTeacher, "students per teacher")
+	fs.IntVar(&opts.ParentsPerFamily, "parents", opts.ParentsPerFamily, "parents per family, siblings share them")
+	fs.Float64Var(&opts.SiblingRate, "sibling-rate", opts.SiblingRate, "chance a student is the sibling of another student in the school")
+	fs.StringVar(&opts.CredentialsFile, "credentials", "", "write a csv of the generated accounts to this file")
+	fs.IntVar(&opts.EntriesPerStudent, "entries", opts.EntriesPerStudent, "note game entries per student")
+	fs.BoolVar(&opts.Bulk, "bulk", true, "load with COPY, turn off to insert row by row")
+	fs.Func("end-date", "date of the most recent entry (2006-01-02), defaults to today", func(value string) error {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return err
+		}
+		opts.EndDate = date
+		return nil
+	})
+	return &opts
+}
+
+func (opts Options) Validate() error {
+	if opts.Schools < 1 {
+		return fmt.Errorf("schools must be at least 1")
+	}
+	if opts.TeachersPerSchool < 0 || opts.StudentsPerTeacher < 0 ||
+		opts.ParentsPerFamily < 0 || opts.EntriesPerStudent < 0 {
+		return fmt.Errorf("teachers, students, parents and entries cannot be negative")
+	}
+	if opts.SiblingRate < 0 || opts.SiblingRate > 1 {
+		return fmt.Errorf("sibling rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// Summary counts what a run inserted
+type Summary struct {
+	Seed     uint64
+	Schools  int
+	Teachers int
+	Students int
+	Parents  int
+	Entries  int
+}
+
+func GenerateData(opts Options) (Summary, error) {
+	if err := opts.Validate(); err != nil {
+		return Summary{}, err
+	}
+
+	if opts.Seed == 0 {
+		opts.Seed = uint64(time.Now().UnixNano())
+	}
+	if opts.EndDate.IsZero() {
+		opts.EndDate = time.Now().UTC()
+	}
+	summary := Summary{Seed: opts.Seed}
+
+	g, err := newGenerator(opts.Seed)
+	if err != nil {
+		return summary, err
+	}
+
+	// log the seed first so a failed run can still be reproduced
+	logging.Logger.Info("generating data",
+		"seed", opts.Seed,
+		"end_date", opts.EndDate.Format(time.DateOnly),
+	)
+
+	schoolIds, err := g.insertFakeSchools(opts.Schools)
+	if err != nil {
+		return summary, err
+	}
+	summary.Schools = len(schoolIds)
+
+	load := insertFakeSchoolData
+	if opts.Bulk {
+		load = copyFakeSchoolData
+	}
+
+	credentials, err := newCredentialsWriter(opts.CredentialsFile)
+	if err != nil {
+		return summary, err
+	}
+	defer credentials.Close()
+
+	progress := newProgress(len(schoolIds))
+	for _, schoolId := range schoolIds {
+		school := g.planSchool(schoolId, opts)
+
+		err := load(school)
+		if err != nil {
+			return summary, err
+		}
+
+		err = credentials.write(school)
+		if err != nil {
+			return summary, err
+		}
+
+		school.count(&summary)
+		progress.add(school.rows())
+	}
 
-	println(strings.Repeat("------------------------------", 2))
-	println("\nGenerating data...\n")
-	println(strings.Repeat("------------------------------", 2))
+	err = credentials.Close()
+	if err != nil {
+		return summary, err
+	}
+	credentials.logSample()
 
-	// testing
-	fmt.Println(insertFakeSchools())
-	fmt.Println(insertFakeTeacherWithStudents())
+	logging.Logger.Info("data generated",
+		"seed", summary.Seed,
+		"schools", summary.Schools,
+		"teachers", summary.Teachers,
+		"students", summary.Students,
+		"parents", summary.Parents,
+		"entries", summary.Entries,
+	)
+	return summary, nil
 }
@@ -3,7 +3,7 @@ package generation
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	mathrand "math/rand"
 	"math/rand/v2"
 	dtos "sight-reading/DTOs"
 
@@ -25,21 +25,31 @@ type fakeTeacherToStudent struct {
 	StudentID int `db:"student_id"`
 }
 
-var fake *faker.Faker
+// generator owns every source of randomness, so the same seed always produces
+// the same schools, users and entries
+type generator struct {
+	rng  *rand.Rand
+	fake *faker.Faker
+}
 
-func initFaker() {
-	var err error
-	fake, err = faker.New("en")
+func newGenerator(seed uint64) (*generator, error) {
+	fake, err := faker.New("en")
 	if err != nil {
-		log.Fatal("Did not instantiate faker")
-		panic(err)
+		return nil, fmt.Errorf("did not instantiate faker: %w", err)
 	}
+	// faker is still on math/rand v1
+	fake.Rand = mathrand.New(mathrand.NewSource(int64(seed)))
+
+	return &generator{
+		rng:  rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		fake: fake,
+	}, nil
 }
 
-func generateFakeSchool() dtos.School {
-	num := rand.IntN(3)
+func (g *generator) generateFakeSchool() dtos.School {
+	num := g.rng.IntN(3)
 
-	prefix := fake.FirstName()
+	prefix := g.fake.FirstName()
 
 	suffix := " Middle School"
 
@@ -48,23 +58,23 @@ func generateFakeSchool() dtos.School {
 	}
 
 	if num < 2 {
-		prefix += " " + fake.LastName()
+		prefix += " " + g.fake.LastName()
 	}
 
 	return dtos.School{
 		Title:       prefix + suffix,
-		City:        fake.City(),
-		County:      fake.City(),
-		State:       fake.State(),
-		Country:     fake.Country(),
-		CreatedDate: generateFakeDateCreated(),
-		CreatedTime: generateFakeTimeCreated(),
+		City:        g.fake.City(),
+		County:      g.fake.City(),
+		State:       g.fake.State(),
+		Country:     g.fake.Country(),
+		CreatedDate: g.generateFakeDateCreated(),
+		CreatedTime: g.generateFakeTimeCreated(),
 	}
 }
 
-func generateFakeUser(role dtos.Role, schoolId int16) dtos.User {
-	fakeFirstName := fake.FirstName()
-	fakeLastName := fake.LastName()
+func (g *generator) generateFakeUser(role dtos.Role, schoolId int16) dtos.User {
+	fakeFirstName := g.fake.FirstName()
+	fakeLastName := g.fake.LastName()
 	fakeEmail := fakeFirstName + "." + fakeLastName + "@email.com"
 	user := dtos.User{
 		FirstName:   fakeFirstName,
@@ -72,8 +82,8 @@ func generateFakeUser(role dtos.Role, schoolId int16) dtos.User {
 		Email:       fakeEmail,
 		Role:        role,
 		SchoolID:    schoolId,
-		CreatedDate: generateFakeDateCreated(),
-		CreatedTime: generateFakeTimeCreated(),
+		CreatedDate: g.generateFakeDateCreated(),
+		CreatedTime: g.generateFakeTimeCreated(),
 	}
 
 	user.ValidateUser()
@@ -81,20 +91,20 @@ func generateFakeUser(role dtos.Role, schoolId int16) dtos.User {
 	return user
 }
 
-func generateFakeEntryTimeLength() string {
-	hourAmount := rand.IntN(1)
-	minutes := rand.IntN(60)
-	seconds := rand.IntN(60)
+func (g *generator) generateFakeEntryTimeLength() string {
+	hourAmount := g.rng.IntN(1)
+	minutes := g.rng.IntN(60)
+	seconds := g.rng.IntN(60)
 
 	timeFormat := fmt.Sprintf("%02d:%02d:%02d", hourAmount, minutes, seconds)
 
 	return timeFormat
 }
 
-func generateFakeDateCreated() sql.NullString {
-	year := rand.IntN(2) + 2022
-	month := rand.IntN(11) + 1
-	day := rand.IntN(26) + 1
+func (g *generator) generateFakeDateCreated() sql.NullString {
+	year := g.rng.IntN(2) + 2022
+	month := g.rng.IntN(11) + 1
+	day := g.rng.IntN(26) + 1
 
 	timeFormat := fmt.Sprintf("%04d-%02d-%02d", year, month, day)
 
@@ -104,10 +114,10 @@ func generateFakeDateCreated() sql.NullString {
 	}
 }
 
-func generateFakeTimeCreated() sql.NullString {
-	hourAmount := rand.IntN(24)
-	minutes := rand.IntN(60)
-	seconds := rand.IntN(60)
+func (g *generator) generateFakeTimeCreated() sql.NullString {
+	hourAmount := g.rng.IntN(24)
+	minutes := g.rng.IntN(60)
+	seconds := g.rng.IntN(60)
 
 	timeFormat := fmt.Sprintf("%02d:%02d:%02d", hourAmount, minutes, seconds)
 
@@ -2,13 +2,35 @@ package generation
 
 import (
 	"fmt"
-	"log"
-	"math/rand/v2"
 	dtos "sight-reading/DTOs"
 	"sight-reading/database"
+	"sight-reading/logging"
 )
 
-func insertFakeEntry(userId int16) {
+const insertUserQuery = `
+  INSERT INTO users (
+    first_name,
+    last_name,
+    email,
+    school_id,
+    role,
+    created_date,
+    created_time
+  )
+  VALUES (
+    :first_name,
+    :last_name,
+    :email,
+    :school_id,
+    :role,
+    :created_date,
+    :created_time
+  )
+  RETURNING
+    id
+  `
+
+func insertFakeEntry(entry dtos.Entry) error {
 	insertEntryQuery := `
   INSERT INTO note_game_entries (
     user_id,
@@ -31,31 +53,16 @@ func insertFakeEntry(userId int16) {
   RETURNING
     id
   `
-	correctQuestions := int16(rand.IntN(100))
-	totalQuestions := correctQuestions + int16(rand.IntN(100))
-	timeLength := generateFakeEntryTimeLength()
-
-	entry := dtos.Entry{
-		TimeLength:       timeLength,
-		TotalQuestions:   totalQuestions,
-		CorrectQuestions: correctQuestions,
-		NPM:              int8(rand.IntN(100)),
-		UserID:           userId,
-		CreatedDate:      generateFakeDateCreated(),
-		CreatedTime:      generateFakeTimeCreated(),
-	}
-	entry.ValidateEntry()
-	result, err := database.DBClient.NamedExec(insertEntryQuery, entry)
+	_, err := database.DBClient.NamedExec(insertEntryQuery, entry)
 	if err != nil {
-		log.Panicf(
-			"an error ocurred inserting the entry to the database. Error: %v, \n Sql result: %v",
-			err.Error(), result,
-		)
+		return fmt.Errorf("an error ocurred inserting the entry to the database: %w", err)
 	}
+	return nil
 }
 
-// Adds fake schools to the data base
-func insertFakeSchools() string {
+// Adds fake schools to the data base, returns their ids so users are only ever
+// attached to schools that exist
+func (g *generator) insertFakeSchools(count int) ([]int16, error) {
 	insertSchoolQuery := `
   INSERT INTO schools (
     title,
@@ -78,73 +85,58 @@ func insertFakeSchools() string {
   RETURNING
     id
   `
-	for i := 0; i < 1000; i++ {
-		fakeSchool := generateFakeSchool()
-		result, err := database.DBClient.NamedExec(insertSchoolQuery, fakeSchool)
+	schoolIds := make([]int16, 0, count)
+	for i := 0; i < count; i++ {
+		fakeSchool := g.generateFakeSchool()
+		var schoolId int16
+		err := namedGet(&schoolId, insertSchoolQuery, fakeSchool)
 		if err != nil {
-			log.Panicf(
-				"an error ocurred inserting the school to the database. Error: %v, \n Sql result: %v",
-				err.Error(), result,
-			)
+			return nil, fmt.Errorf("an error ocurred inserting the school to the database: %w", err)
 		}
+		schoolIds = append(schoolIds, schoolId)
 	}
-	return "school inserted successfully"
+	return schoolIds, nil
 }
 
-func insertFakeTeacherWithStudents() dtos.User {
-	insertUserQuery := `
-  INSERT INTO users (
-    first_name,
-    last_name,
-    school_id,
-    role
-  )
-  VALUES (
-    :first_name,
-    :last_name,
-    :school_id,
-    :role
-  )
-  RETURNING
-    id
-  `
-
-	schoolId := int16(rand.IntN(1000))
-	teacher := generateFakeUser("TEACHER", schoolId)
-
-	rows, err := database.DBClient.NamedQuery(insertUserQuery, teacher)
+func insertFakeUser(user dtos.User) (int, error) {
+	var userId int
+	err := namedGet(&userId, insertUserQuery, user)
 	if err != nil {
-		log.Panic("teacher was not added to the db", err.Error())
+		return 0, fmt.Errorf("%s was not added to the db: %w", user.Role, err)
 	}
+	return userId, nil
+}
 
-	var teacherId int
-	if rows.Next() {
-		err := rows.Scan(&teacherId)
-		if err != nil {
-			log.Panic("teacher id was not extracted properly", err.Error())
-		}
-	}
+// insertFakeSchoolData is the row by row loader, one INSERT per user, entry
+// and association. Fine for a demo database, see copyFakeSchoolData for
+// anything bigger
+func insertFakeSchoolData(school fakeSchoolData) error {
+	teacherIds := make([]int, len(school.teachers))
+	studentIds := make([][]int, len(school.teachers))
 
-	for i := 0; i < 20; i++ {
-		student := generateFakeUser("STUDENT", schoolId)
-		rows, err := database.DBClient.NamedQuery(insertUserQuery, student)
+	for t, teacher := range school.teachers {
+		teacherId, err := insertFakeUser(teacher.user)
 		if err != nil {
-			log.Panic("student was not added to the db", err.Error())
+			return err
 		}
+		teacherIds[t] = teacherId
 
-		var studentId int
-		if rows.Next() {
-			err := rows.Scan(&studentId)
+		for _, student := range teacher.students {
+			studentId, err := insertFakeUser(student.user)
 			if err != nil {
-				log.Panic("student id was not extracted properly", err.Error())
+				return err
 			}
-		}
+			studentIds[t] = append(studentIds[t], studentId)
 
-		for i := 0; i < 10+rand.IntN(200); i++ {
-			insertFakeEntry(int16(studentId))
-		}
+			for _, entry := range student.entries {
+				entry.UserID = studentId
+				err := insertFakeEntry(entry)
+				if err != nil {
+					return err
+				}
+			}
 
-		associationQuery := `
+			associationQuery := `
       INSERT INTO teacher_to_student (
         teacher_id,
         student_id
@@ -154,17 +146,87 @@ func insertFakeTeacherWithStudents() dtos.User {
         :student_id
       )
     `
-		associationIds := fakeTeacherToStudent{
-			TeacherID: teacherId,
-			StudentID: studentId,
+			associationIds := fakeTeacherToStudent{
+				TeacherID: teacherId,
+				StudentID: studentId,
+			}
+
+			_, err = database.DBClient.NamedExec(associationQuery, associationIds)
+			if err != nil {
+				return fmt.Errorf("association from teacher to student was not added to db: %w", err)
+			}
+
+			logging.Logger.Debug("student inserted", "teacher_id", teacherId, "student_id", studentId)
+		}
+	}
+
+	var parentIds []int
+	for _, family := range school.families {
+		for _, parent := range family.parents {
+			parentId, err := insertFakeUser(parent)
+			if err != nil {
+				return err
+			}
+			parentIds = append(parentIds, parentId)
+
+			for _, child := range family.children {
+				associationQuery := `
+        INSERT INTO parent_to_child (
+          parent_id,
+          child_id
+        )
+        VALUES (
+          :parent_id,
+          :child_id
+        )
+      `
+				_, err = database.DBClient.NamedExec(associationQuery, fakeParentToChildAssociation{
+					ParentID: parentId,
+					ChildID:  studentIds[child.teacher][child.student],
+				})
+				if err != nil {
+					return fmt.Errorf("association from parent to child was not added to db: %w", err)
+				}
+			}
 		}
+	}
 
-		result, err := database.DBClient.NamedExec(associationQuery, associationIds)
+	for _, pair := range school.teacherParents() {
+		associationQuery := `
+    INSERT INTO teacher_to_parent (
+      teacher_id,
+      parent_id
+    )
+    VALUES (
+      :teacher_id,
+      :parent_id
+    )
+  `
+		_, err := database.DBClient.NamedExec(associationQuery, fakeTeacherToParent{
+			TeacherID: teacherIds[pair[0]],
+			ParentID:  parentIds[pair[1]],
+		})
 		if err != nil {
-			log.Panic("association from teacher to student was not added to db", err.Error())
+			return fmt.Errorf("association from teacher to parent was not added to db: %w", err)
 		}
-		fmt.Println(result.RowsAffected())
 	}
 
-	return teacher
+	return nil
+}
+
+// sqlx has no NamedGet, this is NamedQuery for a single RETURNING column
+func namedGet(dest any, query string, arg any) error {
+	rows, err := database.DBClient.NamedQuery(query, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("query returned no rows")
+	}
+	return rows.Scan(dest)
 }
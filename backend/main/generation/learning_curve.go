@@ -0,0 +1,179 @@
+package generation
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	dtos "sight-reading/DTOs"
+	"slices"
+	"time"
+)
+
+// studentProfile is the hidden skill trajectory of one fake student. Every
+// entry of the student is sampled from it, so accuracy and notes per minute
+// trend upwards the way a real students would instead of being noise
+type studentProfile struct {
+	// accuracy and npm move from start to ceiling along an exponential curve
+	startAccuracy   float64
+	ceilingAccuracy float64
+	startNPM        float64
+	ceilingNPM      float64
+	// how much of the remaining gap one session closes
+	learningRate float64
+
+	// sessions in [plateauStart, plateauStart+plateauLength) do not improve
+	plateauStart  int
+	plateauLength int
+
+	// chance of practicing on a weekday and on the weekend
+	weekdayPractice float64
+	weekendPractice float64
+	// minutes of a typical session
+	sessionMinutes float64
+}
+
+const (
+	// chance per practice day that the student stops for a while
+	gapChance     = 0.03
+	minGapDays    = 5
+	maxGapDays    = 21
+	weekendDip    = 0.04
+	accuracyNoise = 0.05
+	npmNoise      = 3.0
+)
+
+func (g *generator) newStudentProfile() studentProfile {
+	startAccuracy := 0.35 + g.rng.Float64()*0.25
+	startNPM := 8 + g.rng.Float64()*10
+
+	return studentProfile{
+		startAccuracy:   startAccuracy,
+		ceilingAccuracy: math.Min(0.98, startAccuracy+0.3+g.rng.Float64()*0.3),
+		startNPM:        startNPM,
+		ceilingNPM:      startNPM + 20 + g.rng.Float64()*40,
+		learningRate:    0.02 + g.rng.Float64()*0.06,
+		plateauStart:    10 + g.rng.IntN(30),
+		plateauLength:   g.rng.IntN(20),
+		weekdayPractice: 0.4 + g.rng.Float64()*0.5,
+		weekendPractice: 0.1 + g.rng.Float64()*0.3,
+		sessionMinutes:  4 + g.rng.Float64()*12,
+	}
+}
+
+// progress is how far along the curve the student is after n effective
+// sessions, 0 at the start and approaching 1
+func (p studentProfile) progress(sessions float64) float64 {
+	return 1 - math.Exp(-p.learningRate*sessions)
+}
+
+// effectiveSessions is the number of sessions that counted towards learning,
+// the plateau ones do not
+func (p studentProfile) effectiveSessions(session int) float64 {
+	switch {
+	case session < p.plateauStart:
+		return float64(session)
+	case session < p.plateauStart+p.plateauLength:
+		return float64(p.plateauStart)
+	default:
+		return float64(session - p.plateauLength)
+	}
+}
+
+// simulateHistory plays out count sessions of a student that end on end, the
+// entries come back in chronological order
+func (g *generator) simulateHistory(userId int, count int, end time.Time) []dtos.Entry {
+	if count == 0 {
+		return nil
+	}
+
+	profile := g.newStudentProfile()
+
+	// walk backwards from end day by day, deciding whether the student
+	// practiced, so the last session lands on or just before end
+	days := make([]time.Time, 0, count)
+	rust := make([]float64, 0, count)
+	date := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	for len(days) < count {
+		chance := profile.weekdayPractice
+		if isWeekend(date) {
+			chance = profile.weekendPractice
+		}
+
+		if g.rng.Float64() < chance {
+			days = append(days, date)
+			rust = append(rust, 0)
+
+			if g.rng.Float64() < gapChance {
+				gap := minGapDays + g.rng.IntN(maxGapDays-minGapDays)
+				date = date.AddDate(0, 0, -gap)
+				// coming back from a long break costs a few sessions worth
+				// of progress
+				rust[len(rust)-1] = float64(gap) / 3
+			}
+		}
+
+		date = date.AddDate(0, 0, -1)
+	}
+	slices.Reverse(days)
+	slices.Reverse(rust)
+
+	entries := make([]dtos.Entry, 0, count)
+	lost := 0.0
+	for session, date := range days {
+		lost += rust[session]
+
+		effective := math.Max(0, profile.effectiveSessions(session)-lost)
+		progress := profile.progress(effective)
+
+		accuracy := profile.startAccuracy + (profile.ceilingAccuracy-profile.startAccuracy)*progress
+		npm := profile.startNPM + (profile.ceilingNPM-profile.startNPM)*progress
+		if isWeekend(date) {
+			accuracy -= weekendDip
+			npm *= 1 - weekendDip
+		}
+		accuracy = clamp(accuracy+g.rng.NormFloat64()*accuracyNoise, 0.05, 1)
+		npm = clamp(npm+g.rng.NormFloat64()*npmNoise, 1, 127)
+
+		minutes := clamp(profile.sessionMinutes+g.rng.NormFloat64()*2, 1, 59)
+		seconds := int(minutes * 60)
+		total, correct := questionCounts(npm, minutes, accuracy)
+
+		entries = append(entries, dtos.Entry{
+			UserID:           userId,
+			TimeLength:       fmt.Sprintf("00:%02d:%02d", seconds/60, seconds%60),
+			TotalQuestions:   total,
+			CorrectQuestions: correct,
+			NPM:              int8(math.Round(npm)),
+			CreatedDate:      sql.NullString{String: date.Format(time.DateOnly), Valid: true},
+			CreatedTime:      g.generatePracticeTime(),
+		})
+	}
+
+	return entries
+}
+
+// questionCounts is how many questions a session of minutes at npm asks and
+// how many of them are right. Both are at least 1, an entry needs a correct
+// answer to validate so a rough session still gets one
+func questionCounts(npm, minutes, accuracy float64) (int16, int16) {
+	total := math.Max(1, math.Round(npm*minutes))
+	correct := math.Max(1, math.Round(total*accuracy))
+	return int16(total), int16(correct)
+}
+
+// students practice after school and in the evening
+func (g *generator) generatePracticeTime() sql.NullString {
+	hour := 15 + g.rng.IntN(7)
+	return sql.NullString{
+		String: fmt.Sprintf("%02d:%02d:%02d", hour, g.rng.IntN(60), g.rng.IntN(60)),
+		Valid:  true,
+	}
+}
+
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+func clamp(value, low, high float64) float64 {
+	return math.Max(low, math.Min(high, value))
+}
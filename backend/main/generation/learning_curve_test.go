@@ -0,0 +1,103 @@
+package generation
+
+import (
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+)
+
+func accuracy(correct, total int16) float64 {
+	return float64(correct) / float64(total)
+}
+
+func TestSimulatedHistoryIsChronological(t *testing.T) {
+	g, err := newGenerator(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)
+
+	// past what an int16 holds, ids are serial
+	entries := g.simulateHistory(40000, 120, end)
+	if len(entries) != 120 {
+		t.Fatalf("expected 120 entries, got %d", len(entries))
+	}
+	if entries[0].UserID != 40000 {
+		t.Fatalf("expected the entries of user 40000, got %d", entries[0].UserID)
+	}
+	if last := entries[len(entries)-1].CreatedDate.String; last > "2024-05-17" {
+		t.Fatalf("last entry %s is after the end date", last)
+	}
+
+	for i := 1; i < len(entries); i++ {
+		if entries[i].CreatedDate.String <= entries[i-1].CreatedDate.String {
+			t.Fatalf("entry %d (%s) is not after entry %d (%s)",
+				i, entries[i].CreatedDate.String, i-1, entries[i-1].CreatedDate.String)
+		}
+	}
+}
+
+func TestSimulatedStudentsImprove(t *testing.T) {
+	g, err := newGenerator(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	improved := 0
+	for student := 1; student <= 20; student++ {
+		entries := g.simulateHistory(student, 150, time.Now())
+
+		var early, late float64
+		for _, entry := range entries[:20] {
+			early += accuracy(entry.CorrectQuestions, entry.TotalQuestions)
+		}
+		for _, entry := range entries[len(entries)-20:] {
+			late += accuracy(entry.CorrectQuestions, entry.TotalQuestions)
+		}
+		if late > early {
+			improved++
+		}
+
+		for _, entry := range entries {
+			if entry.CorrectQuestions > entry.TotalQuestions {
+				t.Fatalf("more correct than total questions: %+v", entry)
+			}
+			if err := entry.ValidateEntry(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if improved < 18 {
+		t.Fatalf("expected nearly every student to improve, only %d of 20 did", improved)
+	}
+}
+
+func TestRoughSessionsStillValidate(t *testing.T) {
+	// 1 question at 5% accuracy rounds to none right
+	total, correct := questionCounts(1, 1, 0.05)
+	if total != 1 || correct != 1 {
+		t.Fatalf("expected 1 of 1 right, got %d of %d", correct, total)
+	}
+
+	entry := dtos.Entry{TimeLength: "00:01:00", TotalQuestions: total, CorrectQuestions: correct, UserID: 1, NPM: 1}
+	if err := entry.ValidateEntry(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimulatedEntriesValidate(t *testing.T) {
+	g, err := newGenerator(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for student := 1; student <= 20; student++ {
+		for i, entry := range g.simulateHistory(student, 150, time.Now()) {
+			if err := entry.ValidateEntry(); err != nil {
+				t.Fatalf("entry %d of student %d does not validate: %v", i, student, err)
+			}
+		}
+	}
+}
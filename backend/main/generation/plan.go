@@ -0,0 +1,159 @@
+package generation
+
+import (
+	dtos "sight-reading/DTOs"
+)
+
+// The generator first plans a whole school in memory and only then hands it to
+// a loader (row by row or COPY). Planning consumes the random numbers, loading
+// does not, so both loaders write the same data for the same seed
+
+type fakeStudent struct {
+	user    dtos.User
+	entries []dtos.Entry
+}
+
+type fakeTeacher struct {
+	user     dtos.User
+	students []fakeStudent
+}
+
+// studentRef points at teachers[teacher].students[student]
+type studentRef struct {
+	teacher int
+	student int
+}
+
+// fakeFamily is a set of siblings and the parents they share
+type fakeFamily struct {
+	parents  []dtos.User
+	children []studentRef
+}
+
+type fakeSchoolData struct {
+	schoolId int16
+	teachers []fakeTeacher
+	families []fakeFamily
+}
+
+const maxSiblings = 4
+
+func (g *generator) planSchool(schoolId int16, opts Options) fakeSchoolData {
+	school := fakeSchoolData{schoolId: schoolId}
+
+	for i := 0; i < opts.TeachersPerSchool; i++ {
+		teacher := fakeTeacher{user: g.generateFakeUser(dtos.Teacher, schoolId)}
+
+		for j := 0; j < opts.StudentsPerTeacher; j++ {
+			student := fakeStudent{user: g.generateFakeUser(dtos.Student, schoolId)}
+			// user ids are only known once the loader has them
+			student.entries = g.simulateHistory(0, opts.EntriesPerStudent, opts.EndDate)
+
+			if opts.ParentsPerFamily > 0 {
+				g.placeInFamily(&school, &student.user, studentRef{teacher: i, student: j}, opts)
+			}
+
+			teacher.students = append(teacher.students, student)
+		}
+
+		school.teachers = append(school.teachers, teacher)
+	}
+
+	return school
+}
+
+// placeInFamily either makes the student a sibling of someone already in the
+// school (siblings can have different teachers) or starts a new family
+func (g *generator) placeInFamily(school *fakeSchoolData, student *dtos.User, ref studentRef, opts Options) {
+	var open []int
+	for i, family := range school.families {
+		if len(family.children) < maxSiblings {
+			open = append(open, i)
+		}
+	}
+
+	if len(open) > 0 && g.rng.Float64() < opts.SiblingRate {
+		family := &school.families[open[g.rng.IntN(len(open))]]
+		family.children = append(family.children, ref)
+
+		// siblings share the family name
+		student.LastName = family.parents[0].LastName
+		student.Email = student.FirstName + "." + student.LastName + "@email.com"
+		return
+	}
+
+	family := fakeFamily{children: []studentRef{ref}}
+	for i := 0; i < opts.ParentsPerFamily; i++ {
+		parent := g.generateFakeUser(dtos.Parent, school.schoolId)
+		parent.LastName = student.LastName
+		parent.Email = parent.FirstName + "." + parent.LastName + "@email.com"
+		family.parents = append(family.parents, parent)
+	}
+	school.families = append(school.families, family)
+}
+
+// teacherParents are the distinct (teacher, parent) pairs of the school, a
+// teacher is linked to the parents of every one of their students. Indexes are
+// into teachers and into the flattened parents of families
+func (school fakeSchoolData) teacherParents() [][2]int {
+	var pairs [][2]int
+	seen := map[[2]int]bool{}
+
+	parentIndex := 0
+	for _, family := range school.families {
+		for p := range family.parents {
+			for _, child := range family.children {
+				pair := [2]int{child.teacher, parentIndex + p}
+				if !seen[pair] {
+					seen[pair] = true
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+		parentIndex += len(family.parents)
+	}
+
+	return pairs
+}
+
+// rows is how many rows loading the school writes, for progress reporting
+func (school fakeSchoolData) rows() int {
+	rows := len(school.teacherParents())
+	for _, teacher := range school.teachers {
+		rows++
+		for _, student := range teacher.students {
+			// the student, their teacher link and their entries
+			rows += 2 + len(student.entries)
+		}
+	}
+	for _, family := range school.families {
+		// the parents and a parent to child link per parent and child
+		rows += len(family.parents) + len(family.parents)*len(family.children)
+	}
+	return rows
+}
+
+// users is how many user ids the school needs
+func (school fakeSchoolData) users() int {
+	users := 0
+	for _, teacher := range school.teachers {
+		users += 1 + len(teacher.students)
+	}
+	for _, family := range school.families {
+		users += len(family.parents)
+	}
+	return users
+}
+
+func (school fakeSchoolData) count(summary *Summary) {
+	for _, teacher := range school.teachers {
+		summary.Teachers++
+		for _, student := range teacher.students {
+			summary.Students++
+			summary.Entries += len(student.entries)
+		}
+	}
+	for _, family := range school.families {
+		summary.Parents += len(family.parents)
+	}
+}
@@ -0,0 +1,58 @@
+package generation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanSharesParentsBetweenSiblings(t *testing.T) {
+	g, err := newGenerator(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.TeachersPerSchool = 2
+	opts.StudentsPerTeacher = 15
+	opts.ParentsPerFamily = 2
+	opts.SiblingRate = 0.5
+	opts.EntriesPerStudent = 3
+	opts.EndDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	school := g.planSchool(1, opts)
+
+	children := 0
+	siblings := false
+	for _, family := range school.families {
+		if len(family.parents) != 2 {
+			t.Fatalf("expected 2 parents per family, got %d", len(family.parents))
+		}
+		if len(family.children) > maxSiblings {
+			t.Fatalf("family has %d children, more than %d", len(family.children), maxSiblings)
+		}
+		for _, child := range family.children {
+			student := school.teachers[child.teacher].students[child.student].user
+			if student.LastName != family.parents[0].LastName {
+				t.Fatalf("child %s does not share the family name %s", student.LastName, family.parents[0].LastName)
+			}
+		}
+		children += len(family.children)
+		siblings = siblings || len(family.children) > 1
+	}
+
+	if children != 30 {
+		t.Fatalf("every student should be in exactly one family, %d of 30 are", children)
+	}
+	if !siblings {
+		t.Fatal("expected at least one family with siblings")
+	}
+
+	if school.users() != 2+30+2*len(school.families) {
+		t.Fatalf("unexpected number of users to reserve ids for: %d", school.users())
+	}
+	for _, pair := range school.teacherParents() {
+		if pair[0] >= len(school.teachers) || pair[1] >= 2*len(school.families) {
+			t.Fatalf("teacher to parent pair out of range: %v", pair)
+		}
+	}
+}
@@ -5,6 +5,29 @@ import (
 )
 
 func TestEntryTimeGeneration(t *testing.T) {
-	entryTime := generateFakeEntryTimeLength()
+	g, err := newGenerator(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryTime := g.generateFakeEntryTimeLength()
 	t.Log(entryTime)
 }
+
+func TestGenerationIsDeterministic(t *testing.T) {
+	first, err := newGenerator(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newGenerator(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		a := first.generateFakeUser("STUDENT", 1)
+		b := second.generateFakeUser("STUDENT", 1)
+		if a != b {
+			t.Fatalf("same seed generated different users: %+v and %+v", a, b)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Package grading compares a recorded midi performance with the exercise it
+// was played from. The expected and played notes are aligned with dynamic
+// time warping whose off diagonal steps are a missed or an extra note, so a
+// slip does not drag the rest of the alignment along. Timing is judged
+// against the student's own tempo, fitted from the notes they got right
+package grading
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+
+	"sight-reading/midi"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+)
+
+// DefaultTolerance is how far from where it belongs, in quarter notes, an
+// onset can land and still be in time: a sixteenth
+const DefaultTolerance = 0.25
+
+// DefaultTempo is assumed to line up a performance of a single chord, in
+// quarter notes per minute
+const DefaultTempo = 100
+
+// MinTempo and MaxTempo bound the tempo a performance can be fitted to, in
+// quarter notes per minute. Outside them the recording is not a performance
+// of the exercise, like a long exercise played as one cluster
+const (
+	MinTempo = 5
+	MaxTempo = 1000
+)
+
+const (
+	// chordWindow is how close in seconds played notes start to be one chord
+	chordWindow = 0.05
+	// skipCost is the cost of a missed or an extra note, a match costs 0 to 2
+	skipCost = 1.0
+	// maxCells bounds the alignment matrix, about 2000 notes each way
+	maxCells = 4_000_000
+)
+
+type Options struct {
+	// Tolerance in quarter notes, 0 uses DefaultTolerance
+	Tolerance float64
+}
+
+// ExpectedNote is a note of the exercise. Beat counts quarter notes from 1
+// at the start of the measure
+type ExpectedNote struct {
+	Measure string  `json:"measure"`
+	Beat    float64 `json:"beat"`
+	Pitch   string  `json:"pitch"`
+}
+
+// PlayedNote is a note of the recording, Time is in seconds from its start
+type PlayedNote struct {
+	Time  float64 `json:"time"`
+	Pitch string  `json:"pitch"`
+}
+
+type WrongNote struct {
+	Expected ExpectedNote `json:"expected"`
+	Played   PlayedNote   `json:"played"`
+}
+
+// Result scores are 0 to 1. PitchAccuracy is the right notes over the
+// expected plus the extra ones, so playing everything does not pay.
+// RhythmAccuracy is the notes played in time over the expected ones
+type Result struct {
+	Score          float64 `json:"score"`
+	PitchAccuracy  float64 `json:"pitch_accuracy"`
+	RhythmAccuracy float64 `json:"rhythm_accuracy"`
+	// Tempo is what the student played at, in quarter notes per minute
+	Tempo    float64 `json:"tempo"`
+	Expected int     `json:"expected"`
+	Played   int     `json:"played"`
+	Correct  int     `json:"correct"`
+	InTime   int     `json:"in_time"`
+
+	Missed []ExpectedNote `json:"missed"`
+	Extra  []PlayedNote   `json:"extra"`
+	Wrong  []WrongNote    `json:"wrong"`
+}
+
+var (
+	ErrNoNotes       = errors.New("the exercise has no notes to play")
+	ErrNothingPlayed = errors.New("the recording has no notes")
+	ErrTooLong       = errors.New("the exercise or the recording is too long to grade")
+	ErrTempo         = errors.New("the recording is too fast or too slow to be the exercise")
+)
+
+type expected struct {
+	note  ExpectedNote
+	midi  int
+	onset float64
+}
+
+type played struct {
+	note  PlayedNote
+	midi  int
+	start float64
+}
+
+// pair is one step of the alignment, an index is -1 on the side skipped
+type pair struct {
+	expected, played int
+}
+
+// Grade scores performance against part, at sounding pitch since that is what
+// a keyboard records
+func Grade(part *musicxml.Part, performance []midi.Note, options Options) (Result, error) {
+	var result Result
+	tolerance := options.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	want := expectedNotes(part)
+	if len(want) == 0 {
+		return result, ErrNoNotes
+	}
+	got := playedNotes(performance)
+	if len(got) == 0 {
+		return result, ErrNothingPlayed
+	}
+	if len(want)*len(got) > maxCells {
+		return result, ErrTooLong
+	}
+
+	// a first guess stretches the performance over the exercise, then the
+	// tempo is fitted on the notes that guess got right and aligned again
+	perQuarter, offset := spanFit(want, got)
+	pairs := align(want, got, perQuarter, offset)
+	if fittedPerQuarter, fittedOffset, ok := leastSquares(want, got, pairs); ok {
+		perQuarter, offset = fittedPerQuarter, fittedOffset
+		pairs = align(want, got, perQuarter, offset)
+	}
+
+	result.Expected, result.Played = len(want), len(got)
+	result.Tempo = math.Round(60/perQuarter*10) / 10
+	if !(result.Tempo >= MinTempo && result.Tempo <= MaxTempo) {
+		return Result{}, ErrTempo
+	}
+	result.Missed, result.Extra, result.Wrong = []ExpectedNote{}, []PlayedNote{}, []WrongNote{}
+	for _, p := range pairs {
+		switch {
+		case p.played < 0:
+			result.Missed = append(result.Missed, want[p.expected].note)
+		case p.expected < 0:
+			result.Extra = append(result.Extra, got[p.played].note)
+		default:
+			w, g := want[p.expected], got[p.played]
+			if w.midi == g.midi {
+				result.Correct++
+			} else {
+				result.Wrong = append(result.Wrong, WrongNote{Expected: w.note, Played: g.note})
+			}
+			if math.Abs(quarters(g.start, perQuarter, offset)-w.onset) <= tolerance {
+				result.InTime++
+			}
+		}
+	}
+
+	result.PitchAccuracy = round(float64(result.Correct) / float64(result.Expected+len(result.Extra)))
+	result.RhythmAccuracy = round(float64(result.InTime) / float64(result.Expected))
+	result.Score = round((result.PitchAccuracy + result.RhythmAccuracy) / 2)
+	return result, nil
+}
+
+// expectedNotes are the sounded notes of part, tied notes once, ordered by
+// onset and then pitch so chords line up with how played chords are sorted
+func expectedNotes(part *musicxml.Part) []expected {
+	measureStarts := map[string]float64{}
+	offsets := part.MeasureOffsets()
+	for i := range part.Measures {
+		if _, ok := measureStarts[part.Measures[i].Number]; !ok {
+			measureStarts[part.Measures[i].Number] = offsets[i]
+		}
+	}
+
+	var notes []expected
+	for _, timed := range part.Timeline() {
+		pitch, ok := timed.Sounding()
+		if !ok || timed.Note.IsGrace() || continuesTie(timed.Note) {
+			continue
+		}
+		notes = append(notes, expected{
+			note: ExpectedNote{
+				Measure: timed.Measure,
+				Beat:    round(timed.Onset-measureStarts[timed.Measure]) + 1,
+				Pitch:   theory.FromMIDI(pitch).String(),
+			},
+			midi:  pitch,
+			onset: timed.Onset,
+		})
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].onset != notes[j].onset {
+			return notes[i].onset < notes[j].onset
+		}
+		return notes[i].midi < notes[j].midi
+	})
+	return notes
+}
+
+// continuesTie is a note tied from the one before, it is held not played
+func continuesTie(note *musicxml.Note) bool {
+	for _, tie := range note.Ties {
+		if tie.Type == "stop" {
+			return true
+		}
+	}
+	return false
+}
+
+// playedNotes groups notes started within chordWindow into chords, each
+// chord starts with its first note and is sorted by pitch
+func playedNotes(performance []midi.Note) []played {
+	notes := make([]played, len(performance))
+	for i, note := range performance {
+		notes[i] = played{
+			note:  PlayedNote{Time: round(note.Start), Pitch: theory.FromMIDI(note.Pitch).String()},
+			midi:  note.Pitch,
+			start: note.Start,
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].start < notes[j].start })
+
+	for i := 1; i < len(notes); i++ {
+		if notes[i].start-notes[i-1].start <= chordWindow {
+			notes[i].start = notes[i-1].start
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].start != notes[j].start {
+			return notes[i].start < notes[j].start
+		}
+		return notes[i].midi < notes[j].midi
+	})
+	return notes
+}
+
+// spanFit maps the first and last played onsets onto the first and last
+// expected ones, seconds = perQuarter * quarters + offset
+func spanFit(want []expected, got []played) (float64, float64) {
+	firstQuarter, lastQuarter := want[0].onset, want[len(want)-1].onset
+	firstSecond, lastSecond := got[0].start, got[len(got)-1].start
+
+	perQuarter := 60.0 / DefaultTempo
+	if lastQuarter > firstQuarter && lastSecond > firstSecond {
+		perQuarter = (lastSecond - firstSecond) / (lastQuarter - firstQuarter)
+	}
+	return perQuarter, firstSecond - perQuarter*firstQuarter
+}
+
+// leastSquares fits the mapping on the pairs with the right pitch, it needs
+// at least two different onsets to fit a line and is not ok otherwise
+func leastSquares(want []expected, got []played, pairs []pair) (float64, float64, bool) {
+	var n, sumQ, sumS, sumQQ, sumQS float64
+	for _, p := range pairs {
+		if p.expected < 0 || p.played < 0 || want[p.expected].midi != got[p.played].midi {
+			continue
+		}
+		q, s := want[p.expected].onset, got[p.played].start
+		n++
+		sumQ += q
+		sumS += s
+		sumQQ += q * q
+		sumQS += q * s
+	}
+
+	variance := n*sumQQ - sumQ*sumQ
+	if n < 2 || variance < 1e-9 {
+		return 0, 0, false
+	}
+	perQuarter := (n*sumQS - sumQ*sumS) / variance
+	if perQuarter <= 0 {
+		return 0, 0, false
+	}
+	return perQuarter, (sumS - perQuarter*sumQ) / n, true
+}
+
+func quarters(seconds, perQuarter, offset float64) float64 {
+	return (seconds - offset) / perQuarter
+}
+
+// align is the dynamic time warping. Matching costs 1 for a wrong pitch plus
+// up to 1 for being off by up to two quarter notes, skipping either side
+// costs skipCost
+func align(want []expected, got []played, perQuarter, offset float64) []pair {
+	n, m := len(want), len(got)
+	const (
+		match byte = iota
+		missed
+		extra
+	)
+
+	cost := make([]float64, (n+1)*(m+1))
+	from := make([]byte, (n+1)*(m+1))
+	at := func(i, j int) int { return i*(m+1) + j }
+
+	for i := 1; i <= n; i++ {
+		cost[at(i, 0)], from[at(i, 0)] = float64(i)*skipCost, missed
+	}
+	for j := 1; j <= m; j++ {
+		cost[at(0, j)], from[at(0, j)] = float64(j)*skipCost, extra
+	}
+
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			w, g := want[i-1], got[j-1]
+			step := math.Min(math.Abs(quarters(g.start, perQuarter, offset)-w.onset), 2) / 2
+			if w.midi != g.midi {
+				step++
+			}
+
+			best, how := cost[at(i-1, j-1)]+step, match
+			if skip := cost[at(i-1, j)] + skipCost; skip < best {
+				best, how = skip, missed
+			}
+			if skip := cost[at(i, j-1)] + skipCost; skip < best {
+				best, how = skip, extra
+			}
+			cost[at(i, j)], from[at(i, j)] = best, how
+		}
+	}
+
+	var pairs []pair
+	for i, j := n, m; i > 0 || j > 0; {
+		switch from[at(i, j)] {
+		case match:
+			i, j = i-1, j-1
+			pairs = append(pairs, pair{expected: i, played: j})
+		case missed:
+			i--
+			pairs = append(pairs, pair{expected: i, played: -1})
+		case extra:
+			j--
+			pairs = append(pairs, pair{expected: -1, played: j})
+		}
+	}
+
+	slices.Reverse(pairs)
+	return pairs
+}
+
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	// gin context keys
+	requestIDKey = "request_id"
+	loggerKey    = "logger"
+
+	// UserIDKey is where the auth layer leaves the id of the caller, the
+	// request logger picks it up when it is set
+	UserIDKey = "user_id"
+)
+
+// Logger is the process wide logger, anything outside of a request (startup,
+// the generator, cli commands) should log through this
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+func Init(level slog.Level) {
+	Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(Logger)
+}
+
+// RequestID reuses the callers X-Request-ID when there is one, otherwise it
+// makes a new one. The id is echoed back on the response and attached to a
+// request scoped logger, see FromContext
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Set(loggerKey, Logger.With(slog.String("request_id", requestID)))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// Middleware replaces gins default text logger with one json line per request
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := c.Get(UserIDKey); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		} else if c.Writer.Status() >= 400 {
+			level = slog.LevelWarn
+		}
+
+		FromContext(c).LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// FromContext returns the logger for the current request, tagged with its
+// request id. Falls back to the process logger outside of the middleware
+func FromContext(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get(loggerKey); ok {
+		return logger.(*slog.Logger)
+	}
+	return Logger
+}
+
+func RequestIDFromContext(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
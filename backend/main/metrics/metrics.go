@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tremolo"
+
+// NOTE: http
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of http requests handled, by route and status code",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of http requests, by route",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// NOTE: database
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Latency of database queries, by repository method",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"method"})
+
+// NOTE: domain
+var (
+	EntriesRecorded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "note_game_entries_recorded_total",
+		Help:      "Number of note game entries saved",
+	})
+
+	UsersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_created_total",
+		Help:      "Number of users created, by role",
+	}, []string{"role"})
+
+	AuthFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Number of requests refused with a 401, by reason (invalid token or missing token)",
+	}, []string{"reason"})
+
+	ExercisesServed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exercises_served_total",
+		Help:      "Number of exercises served through the music gateway, by kind",
+	}, []string{"kind"})
+
+	MusicCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "music_cache_requests_total",
+		Help:      "Lookups in the generated exercise cache, by kind and result (hit or miss)",
+	}, []string{"kind", "result"})
+)
+
+// Middleware records the count and latency of every request. The route label
+// is the gin route template (/student/:id), not the raw path, so the label
+// cardinality stays bounded
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the prometheus exposition format, mount it on /metrics
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// TimeQuery starts a timer for a repository method, call the returned func
+// once the query has finished
+//
+//	done := metrics.TimeQuery("GetTeachers")
+//	err := database.DBClient.Select(&teachers, query)
+//	done()
+func TimeQuery(method string) func() {
+	timer := prometheus.NewTimer(queryDuration.WithLabelValues(method))
+	return func() {
+		timer.ObserveDuration()
+	}
+}
+
+// RegisterDBStats exposes the sql.DB pool stats (open, idle, in use, waits)
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
@@ -0,0 +1,194 @@
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+
+	"sight-reading/musicxml"
+)
+
+const (
+	// PPQ is the ticks per quarter note of exported files
+	PPQ = 480
+	// DefaultTempo is used when neither the options nor the score set one
+	DefaultTempo = 100
+	// MinTempo and MaxTempo bound the tempo in quarter notes per minute, a
+	// score's own is clamped to them
+	MinTempo = 20
+	MaxTempo = 400
+
+	drumChannel = 9
+	velocity    = 80
+)
+
+type Options struct {
+	// Tempo in quarter notes per minute, 0 uses the score's
+	Tempo float64
+	// Program is the General MIDI program (0 is piano) for every part, nil
+	// uses the midi-instrument of each part
+	Program *int
+}
+
+var ErrNoParts = errors.New("the score has no parts to export")
+
+// Write exports score as a format 1 file: a conductor track with the tempo,
+// key and time signatures and then one track per part, each on its own
+// channel. Tied notes are joined and transposing parts sound at concert pitch
+func Write(w io.Writer, score *musicxml.Score, options Options) error {
+	if len(score.Parts) == 0 {
+		return ErrNoParts
+	}
+
+	tempo := options.Tempo
+	if tempo <= 0 {
+		tempo = DefaultTempo
+		if scoreTempo, ok := score.Parts[0].Tempo(); ok {
+			tempo = min(max(scoreTempo, MinTempo), MaxTempo)
+		}
+	}
+
+	conductor := &track{}
+	conductor.name(title(score))
+	conductor.tempo(0, tempo)
+	signatures(conductor, &score.Parts[0])
+
+	tracks := []*track{conductor}
+	for i := range score.Parts {
+		part := &score.Parts[i]
+		scorePart := scorePartFor(score, part.ID)
+
+		channel := i % 15
+		if channel >= drumChannel {
+			channel++
+		}
+
+		program := 0
+		if options.Program != nil {
+			program = *options.Program
+		} else if scorePart != nil && scorePart.Midi != nil && scorePart.Midi.Program > 0 {
+			// MusicXML counts programs from 1
+			program = scorePart.Midi.Program - 1
+		}
+
+		t := &track{}
+		if scorePart != nil && scorePart.Name != "" {
+			t.name(scorePart.Name)
+		}
+		t.add(0, 1, 0xC0|byte(channel), byte(program&0x7F))
+		notes(t, part, byte(channel))
+		tracks = append(tracks, t)
+	}
+
+	return writeFile(w, PPQ, tracks)
+}
+
+func Export(score *musicxml.Score, options Options) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, score, options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// signatures copies every time and key signature change of part to the
+// conductor track, at the start of its measure
+func signatures(conductor *track, part *musicxml.Part) {
+	offsets := part.MeasureOffsets()
+	for i := range part.Measures {
+		attributes := part.Measures[i].Attributes()
+		if attributes == nil {
+			continue
+		}
+		tick := ticks(offsets[i])
+
+		for _, time := range attributes.Times {
+			beats, beatsErr := strconv.Atoi(time.Beats)
+			beatType, beatTypeErr := strconv.Atoi(time.BeatType)
+			// compound beats like 3+2 are left out
+			if beatsErr == nil && beatTypeErr == nil {
+				conductor.timeSignature(tick, beats, beatType)
+			}
+		}
+		for _, key := range attributes.Keys {
+			conductor.keySignature(tick, key.Fifths, key.Mode == "minor")
+		}
+	}
+}
+
+// notes adds the note ons and offs of part. A tie start holds the note, the
+// notes it is tied to only extend it
+func notes(t *track, part *musicxml.Part, channel byte) {
+	type held struct {
+		start, end float64
+	}
+	tied := map[int]*held{}
+
+	emit := func(midi int, start, end float64) {
+		on, off := ticks(start), ticks(end)
+		if off <= on || midi < 0 || midi > 127 {
+			return
+		}
+		t.add(on, 2, 0x90|channel, byte(midi), velocity)
+		t.add(off, 1, 0x80|channel, byte(midi), 0)
+	}
+
+	for _, timed := range part.Timeline() {
+		midi, ok := timed.Sounding()
+		if !ok || timed.Note.IsGrace() {
+			continue
+		}
+		end := timed.Onset + timed.Duration
+
+		starts, stops := false, false
+		for _, tie := range timed.Note.Ties {
+			starts = starts || tie.Type == "start"
+			stops = stops || tie.Type == "stop"
+		}
+
+		if hold, ok := tied[midi]; ok && stops {
+			hold.end = end
+			if !starts {
+				emit(midi, hold.start, hold.end)
+				delete(tied, midi)
+			}
+			continue
+		}
+		if starts {
+			tied[midi] = &held{start: timed.Onset, end: end}
+			continue
+		}
+		emit(midi, timed.Onset, end)
+	}
+
+	// a tie that never stopped still sounds
+	for _, midi := range slices.Sorted(maps.Keys(tied)) {
+		emit(midi, tied[midi].start, tied[midi].end)
+	}
+}
+
+// ticks is quarters in ticks, never before the start. A <backup> past the
+// start of the first measure would otherwise be a negative delta time
+func ticks(quarters float64) int {
+	return max(int(math.Round(quarters*PPQ)), 0)
+}
+
+func title(score *musicxml.Score) string {
+	if score.Work != nil && score.Work.Title != "" {
+		return score.Work.Title
+	}
+	return score.MovementTitle
+}
+
+func scorePartFor(score *musicxml.Score, id string) *musicxml.ScorePart {
+	for i := range score.PartList.ScoreParts {
+		if score.PartList.ScoreParts[i].ID == id {
+			return &score.PartList.ScoreParts[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Note is a played note of a recording, times are in seconds from the start
+// of the file
+type Note struct {
+	Pitch    int
+	Velocity int
+	Channel  int
+	Start    float64
+	End      float64
+}
+
+var ErrNotMIDI = errors.New("not a standard midi file")
+
+// maxTracks keeps a hostile header from allocating without bound, recordings
+// from keyboards have one or two
+const maxTracks = 256
+
+type tempoChange struct {
+	tick int
+	// micros per quarter note from tick on
+	micros int
+}
+
+// rawNote is a note in ticks before the tempo map turns it into seconds
+type rawNote struct {
+	pitch, velocity, channel int
+	start, end               int
+}
+
+// Read decodes the notes of a Standard MIDI File of any format, merging every
+// track and channel. A note on with velocity 0 is a note off, notes that are
+// never released end with their track
+func Read(r io.Reader) ([]Note, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ReadBytes(data)
+}
+
+func ReadBytes(data []byte) ([]Note, error) {
+	reader := bytes.NewReader(data)
+
+	id, header, err := chunk(reader)
+	if err != nil || id != "MThd" || len(header) < 6 {
+		return nil, ErrNotMIDI
+	}
+	tracks := int(binary.BigEndian.Uint16(header[2:4]))
+	division := binary.BigEndian.Uint16(header[4:6])
+	if tracks > maxTracks {
+		return nil, fmt.Errorf("%w: %d tracks", ErrNotMIDI, tracks)
+	}
+
+	var notes []rawNote
+	tempos := []tempoChange{{tick: 0, micros: 500_000}}
+	for i := 0; i < tracks; i++ {
+		id, body, err := chunk(reader)
+		if err != nil {
+			return nil, fmt.Errorf("%w: track %d: %v", ErrNotMIDI, i+1, err)
+		}
+		// unknown chunks are allowed and skipped
+		if id != "MTrk" {
+			i--
+			continue
+		}
+
+		trackNotes, trackTempos, err := readTrack(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: track %d: %v", ErrNotMIDI, i+1, err)
+		}
+		notes = append(notes, trackNotes...)
+		tempos = append(tempos, trackTempos...)
+	}
+
+	seconds := clock(division, tempos)
+	played := make([]Note, len(notes))
+	for i, note := range notes {
+		played[i] = Note{
+			Pitch:    note.pitch,
+			Velocity: note.velocity,
+			Channel:  note.channel,
+			Start:    seconds(note.start),
+			End:      seconds(note.end),
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool {
+		if played[i].Start != played[j].Start {
+			return played[i].Start < played[j].Start
+		}
+		return played[i].Pitch < played[j].Pitch
+	})
+	return played, nil
+}
+
+func chunk(reader *bytes.Reader) (string, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return "", nil, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if int64(length) > int64(reader.Len()) {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return "", nil, err
+	}
+	return string(header[:4]), body, nil
+}
+
+// readTrack walks the events of a track chunk, keeping the notes and the
+// tempo changes and skipping everything else
+func readTrack(data []byte) ([]rawNote, []tempoChange, error) {
+	var notes []rawNote
+	var tempos []tempoChange
+	// sounding notes by channel and pitch, a key pressed twice without a
+	// release is released first in first out
+	sounding := map[[2]int][]int{}
+
+	tick, position := 0, 0
+	var status byte
+	for position < len(data) {
+		delta, n, err := readVLQ(data[position:])
+		if err != nil {
+			return nil, nil, err
+		}
+		position += n
+		tick += delta
+
+		if position >= len(data) {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		if data[position]&0x80 != 0 {
+			status = data[position]
+			position++
+		} else if status == 0 {
+			return nil, nil, errors.New("running status without a status byte")
+		}
+
+		switch {
+		case status == 0xFF:
+			if position >= len(data) {
+				return nil, nil, io.ErrUnexpectedEOF
+			}
+			kind := data[position]
+			length, n, err := readVLQ(data[position+1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			start := position + 1 + n
+			if start+length > len(data) {
+				return nil, nil, io.ErrUnexpectedEOF
+			}
+			if kind == 0x51 && length == 3 {
+				micros := int(data[start])<<16 | int(data[start+1])<<8 | int(data[start+2])
+				tempos = append(tempos, tempoChange{tick: tick, micros: micros})
+			}
+			position = start + length
+			// meta and sysex events cancel running status
+			status = 0
+			if kind == 0x2F {
+				position = len(data)
+			}
+
+		case status == 0xF0 || status == 0xF7:
+			length, n, err := readVLQ(data[position:])
+			if err != nil {
+				return nil, nil, err
+			}
+			position += n + length
+			status = 0
+
+		default:
+			size := 2
+			if kind := status & 0xF0; kind == 0xC0 || kind == 0xD0 {
+				size = 1
+			}
+			if position+size > len(data) {
+				return nil, nil, io.ErrUnexpectedEOF
+			}
+			channel := int(status & 0x0F)
+			switch kind := status & 0xF0; {
+			case kind == 0x90 && data[position+1] > 0:
+				key := [2]int{channel, int(data[position])}
+				notes = append(notes, rawNote{
+					pitch:    int(data[position]),
+					velocity: int(data[position+1]),
+					channel:  channel,
+					start:    tick,
+					end:      -1,
+				})
+				sounding[key] = append(sounding[key], len(notes)-1)
+			case kind == 0x80 || kind == 0x90:
+				key := [2]int{channel, int(data[position])}
+				if open := sounding[key]; len(open) > 0 {
+					notes[open[0]].end = tick
+					sounding[key] = open[1:]
+				}
+			}
+			position += size
+		}
+	}
+
+	for i := range notes {
+		if notes[i].end < 0 {
+			notes[i].end = tick
+		}
+	}
+	return notes, tempos, nil
+}
+
+// clock turns ticks into seconds. division is the header field: ticks per
+// quarter note, or with the high bit set SMPTE frames per second and ticks
+// per frame, which ignores the tempo map
+func clock(division uint16, tempos []tempoChange) func(int) float64 {
+	if division&0x8000 != 0 {
+		fps := -int(int8(division >> 8))
+		ticksPerFrame := int(division & 0xFF)
+		perTick := 1 / float64(max(fps*ticksPerFrame, 1))
+		return func(tick int) float64 {
+			return float64(tick) * perTick
+		}
+	}
+
+	ppq := float64(max(int(division), 1))
+	sort.SliceStable(tempos, func(i, j int) bool { return tempos[i].tick < tempos[j].tick })
+
+	// seconds at the start of each tempo change
+	starts := make([]float64, len(tempos))
+	for i := 1; i < len(tempos); i++ {
+		elapsed := float64(tempos[i].tick-tempos[i-1].tick) / ppq * float64(tempos[i-1].micros) / 1e6
+		starts[i] = starts[i-1] + elapsed
+	}
+
+	return func(tick int) float64 {
+		i := sort.Search(len(tempos), func(i int) bool { return tempos[i].tick > tick }) - 1
+		i = max(i, 0)
+		return starts[i] + float64(tick-tempos[i].tick)/ppq*float64(tempos[i].micros)/1e6
+	}
+}
+
+func readVLQ(data []byte) (int, int, error) {
+	value := 0
+	for i := 0; i < 4 && i < len(data); i++ {
+		value = value<<7 | int(data[i]&0x7F)
+		if data[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("invalid variable length quantity")
+}
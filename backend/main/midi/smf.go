@@ -0,0 +1,116 @@
+// Package midi writes Standard MIDI Files, format 1, from parsed MusicXML so
+// exercises can be played on devices without a notation renderer, and reads
+// the notes of recorded performances back
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// event is one track event at an absolute tick, order breaks ties so note
+// offs come before note ons at the same tick
+type event struct {
+	tick  int
+	order int
+	data  []byte
+}
+
+type track struct {
+	events []event
+}
+
+func (t *track) add(tick int, order int, data ...byte) {
+	t.events = append(t.events, event{tick: tick, order: order, data: data})
+}
+
+func (t *track) meta(tick int, kind byte, data []byte) {
+	payload := append([]byte{0xFF, kind}, vlq(len(data))...)
+	t.add(tick, 0, append(payload, data...)...)
+}
+
+func (t *track) name(name string) {
+	t.meta(0, 0x03, []byte(name))
+}
+
+// tempo in quarter notes per minute, stored as microseconds per quarter in
+// 24 bits
+func (t *track) tempo(tick int, bpm float64) {
+	micros := min(max(int(60_000_000/bpm), 1), 0xFFFFFF)
+	t.meta(tick, 0x51, []byte{byte(micros >> 16), byte(micros >> 8), byte(micros)})
+}
+
+// timeSignature takes the denominator as written (4, 8), the file stores its
+// power of two
+func (t *track) timeSignature(tick int, beats, beatType int) {
+	power := 0
+	for 1<<power < beatType {
+		power++
+	}
+	t.meta(tick, 0x58, []byte{byte(beats), byte(power), 24, 8})
+}
+
+func (t *track) keySignature(tick int, fifths int, minor bool) {
+	mode := byte(0)
+	if minor {
+		mode = 1
+	}
+	t.meta(tick, 0x59, []byte{byte(int8(fifths)), mode})
+}
+
+// bytes encodes the track chunk, events sorted and delta timed, ending with
+// the end of track meta event
+func (t *track) bytes() []byte {
+	sort.SliceStable(t.events, func(i, j int) bool {
+		if t.events[i].tick != t.events[j].tick {
+			return t.events[i].tick < t.events[j].tick
+		}
+		return t.events[i].order < t.events[j].order
+	})
+
+	var body bytes.Buffer
+	last := 0
+	for _, e := range t.events {
+		body.Write(vlq(e.tick - last))
+		body.Write(e.data)
+		last = e.tick
+	}
+	body.Write([]byte{0x00, 0xFF, 0x2F, 0x00})
+
+	var chunk bytes.Buffer
+	chunk.WriteString("MTrk")
+	binary.Write(&chunk, binary.BigEndian, uint32(body.Len()))
+	chunk.Write(body.Bytes())
+	return chunk.Bytes()
+}
+
+func writeFile(w io.Writer, ppq int, tracks []*track) error {
+	var header bytes.Buffer
+	header.WriteString("MThd")
+	binary.Write(&header, binary.BigEndian, uint32(6))
+	binary.Write(&header, binary.BigEndian, uint16(1))
+	binary.Write(&header, binary.BigEndian, uint16(len(tracks)))
+	binary.Write(&header, binary.BigEndian, uint16(ppq))
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	for _, t := range tracks {
+		if _, err := w.Write(t.bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vlq is the variable length quantity the file format uses for delta times
+// and lengths, 7 bits a byte with the high bit set on all but the last
+func vlq(value int) []byte {
+	encoded := []byte{byte(value & 0x7F)}
+	for value >>= 7; value > 0; value >>= 7 {
+		encoded = append([]byte{byte(value&0x7F) | 0x80}, encoded...)
+	}
+	return encoded
+}
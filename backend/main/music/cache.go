@@ -0,0 +1,110 @@
+package music
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+
+	dtos "sight-reading/DTOs"
+)
+
+// Store is where generated exercises are kept, keys are content addresses
+// built by cacheKey
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// Cached serves exercises from a store and only calls the generator on a
+// miss. Mary is deterministic so it gets one slot per parameters, random
+// melodies and note game questions get several, a request picks one at
+// random, so a classroom shares exercises without every question being the
+// same note
+type Cached struct {
+	generator Generator
+	store     Store
+	variants  int
+}
+
+func NewCached(generator Generator, store Store, variants int) *Cached {
+	if variants < 1 {
+		variants = 1
+	}
+	return &Cached{generator: generator, store: store, variants: variants}
+}
+
+func (cached *Cached) Mary(ctx context.Context, req dtos.MaryRequest) ([]byte, error) {
+	return cached.get(ctx, "mary", req, 1, func() ([]byte, error) {
+		return cached.generator.Mary(ctx, req)
+	})
+}
+
+func (cached *Cached) Random(ctx context.Context, req dtos.RandomRequest) ([]byte, error) {
+	return cached.get(ctx, "random", req, cached.variants, func() ([]byte, error) {
+		return cached.generator.Random(ctx, req)
+	})
+}
+
+func (cached *Cached) NoteGame(ctx context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error) {
+	var noteGame dtos.NoteGame
+
+	body, err := cached.get(ctx, "note-game", req, cached.variants, func() ([]byte, error) {
+		generated, err := cached.generator.NoteGame(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(generated)
+	})
+	if err != nil {
+		return noteGame, err
+	}
+
+	err = json.Unmarshal(body, &noteGame)
+	return noteGame, err
+}
+
+// get returns the cached exercise or generates and stores it. A failing store
+// is logged and skipped, the cache must never be why an exercise fails
+func (cached *Cached) get(ctx context.Context, kind string, params any, variants int, generate func() ([]byte, error)) ([]byte, error) {
+	key, err := cacheKey(kind, params, rand.IntN(variants))
+	if err != nil {
+		return nil, err
+	}
+
+	body, ok, err := cached.store.Get(ctx, key)
+	if err != nil {
+		logging.Logger.Warn("music cache read failed", "kind", kind, "error", err)
+	}
+	if ok {
+		metrics.MusicCacheRequests.WithLabelValues(kind, "hit").Inc()
+		return body, nil
+	}
+	metrics.MusicCacheRequests.WithLabelValues(kind, "miss").Inc()
+
+	body, err = generate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cached.store.Set(ctx, key, body); err != nil {
+		logging.Logger.Warn("music cache write failed", "kind", kind, "error", err)
+	}
+	return body, nil
+}
+
+// cacheKey addresses an exercise by what generated it, the json of the
+// request is stable since the request types are structs
+func cacheKey(kind string, params any, variant int) (string, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%d", kind, encoded, variant))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,187 @@
+package music
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	dtos "sight-reading/DTOs"
+)
+
+// Generator is everything the api needs from a music generator. The http
+// client to the django service is one implementation, swapping the generator
+// out means providing another
+type Generator interface {
+	Mary(ctx context.Context, req dtos.MaryRequest) ([]byte, error)
+	Random(ctx context.Context, req dtos.RandomRequest) ([]byte, error)
+	NoteGame(ctx context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error)
+}
+
+// RequestError is a 4xx from the music service, the request itself was wrong
+// and retrying will not help. Message can be shown to the client, Body is
+// what the music service answered and only goes to the logs
+type RequestError struct {
+	Status  int
+	Message string
+	Body    string
+}
+
+func (e *RequestError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("music service rejected the request (%d): %s: %s", e.Status, e.Message, e.Body)
+	}
+	return fmt.Sprintf("music service rejected the request (%d): %s", e.Status, e.Message)
+}
+
+type ClientOptions struct {
+	BaseURL string
+	// Token is sent as a bearer token on every call
+	Token   string
+	Timeout time.Duration
+	// Retries is how many times a failed call is retried, only network
+	// errors and 5xx are retried
+	Retries int
+	Backoff time.Duration
+	// the breaker opens after BreakerThreshold failed calls in a row
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type Client struct {
+	options ClientOptions
+	http    *http.Client
+	breaker *breaker
+}
+
+func NewClient(options ClientOptions) *Client {
+	if options.Timeout == 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.Backoff == 0 {
+		options.Backoff = 200 * time.Millisecond
+	}
+	if options.BreakerThreshold == 0 {
+		options.BreakerThreshold = 5
+	}
+	if options.BreakerCooldown == 0 {
+		options.BreakerCooldown = 30 * time.Second
+	}
+
+	return &Client{
+		options: options,
+		http:    &http.Client{Timeout: options.Timeout},
+		breaker: newBreaker(options.BreakerThreshold, options.BreakerCooldown),
+	}
+}
+
+func (client *Client) Mary(ctx context.Context, req dtos.MaryRequest) ([]byte, error) {
+	return client.post(ctx, "/mary", req)
+}
+
+func (client *Client) Random(ctx context.Context, req dtos.RandomRequest) ([]byte, error) {
+	return client.post(ctx, "/random", req)
+}
+
+func (client *Client) NoteGame(ctx context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error) {
+	var noteGame dtos.NoteGame
+
+	body, err := client.post(ctx, "/note-game", req)
+	if err != nil {
+		return noteGame, err
+	}
+
+	if err := json.Unmarshal(body, &noteGame); err != nil {
+		return noteGame, fmt.Errorf("decoding the note game: %w", err)
+	}
+	return noteGame, nil
+}
+
+// post sends body as json and returns the raw response, retrying with an
+// exponential backoff and going through the circuit breaker
+func (client *Client) post(ctx context.Context, path string, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= client.options.Retries; attempt++ {
+		if attempt > 0 {
+			backoff := client.options.Backoff * time.Duration(1<<(attempt-1))
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		if !client.breaker.allow() {
+			return nil, ErrCircuitOpen
+		}
+
+		response, err := client.do(ctx, path, payload)
+		if err == nil {
+			client.breaker.success()
+			return response, nil
+		}
+
+		var requestErr *RequestError
+		if errors.As(err, &requestErr) {
+			// the service is fine, the request was not
+			client.breaker.success()
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			// the caller gave up, that says nothing about the service
+			client.breaker.abandon()
+			return nil, ctx.Err()
+		}
+
+		client.breaker.failure()
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+func (client *Client) do(ctx context.Context, path string, payload []byte) ([]byte, error) {
+	url := strings.TrimSuffix(client.options.BaseURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client.options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+client.options.Token)
+	}
+
+	response, err := client.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case response.StatusCode >= 500:
+		return nil, fmt.Errorf("music service returned %d", response.StatusCode)
+	case response.StatusCode >= 400:
+		return nil, &RequestError{
+			Status:  response.StatusCode,
+			Message: "the music service could not generate that exercise, check the request",
+			Body:    strings.TrimSpace(string(body)),
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,48 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"sight-reading/logging"
+
+	dtos "sight-reading/DTOs"
+)
+
+// Fallback sends note game questions to primary and answers them locally when
+// primary is down or the request needs the local generator. A rejected
+// request is not retried locally, the local generator would reject it too
+type Fallback struct {
+	primary Generator
+	local   *Local
+}
+
+func NewFallback(primary Generator, local *Local) *Fallback {
+	return &Fallback{primary: primary, local: local}
+}
+
+func (fallback *Fallback) Mary(ctx context.Context, req dtos.MaryRequest) ([]byte, error) {
+	return fallback.primary.Mary(ctx, req)
+}
+
+func (fallback *Fallback) Random(ctx context.Context, req dtos.RandomRequest) ([]byte, error) {
+	return fallback.primary.Random(ctx, req)
+}
+
+func (fallback *Fallback) NoteGame(ctx context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error) {
+	if req.NeedsLocalGenerator() {
+		return fallback.local.NoteGame(ctx, req)
+	}
+
+	noteGame, err := fallback.primary.NoteGame(ctx, req)
+	if err == nil {
+		return noteGame, nil
+	}
+
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) || ctx.Err() != nil {
+		return noteGame, err
+	}
+
+	logging.Logger.Warn("music service failed, generating the note game locally", "error", err)
+	return fallback.local.NoteGame(ctx, req)
+}
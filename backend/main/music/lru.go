@@ -0,0 +1,77 @@
+package music
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// LRU is an in memory Store holding at most maxBytes of exercises, the least
+// recently used ones are evicted first
+type LRU struct {
+	maxBytes int
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+func NewLRU(maxBytes int) *LRU {
+	return &LRU{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (lru *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	element, ok := lru.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	lru.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true, nil
+}
+
+func (lru *LRU) Set(_ context.Context, key string, value []byte) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if len(value) > lru.maxBytes {
+		return nil
+	}
+
+	if element, ok := lru.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		lru.size += len(value) - len(entry.value)
+		entry.value = value
+		lru.order.MoveToFront(element)
+	} else {
+		lru.entries[key] = lru.order.PushFront(&lruEntry{key: key, value: value})
+		lru.size += len(value)
+	}
+
+	for lru.size > lru.maxBytes {
+		oldest := lru.order.Back()
+		entry := oldest.Value.(*lruEntry)
+		lru.order.Remove(oldest)
+		delete(lru.entries, entry.key)
+		lru.size -= len(entry.value)
+	}
+	return nil
+}
+
+func (lru *LRU) Len() int {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	return lru.order.Len()
+}
@@ -0,0 +1,47 @@
+package music
+
+import (
+	"fmt"
+	"sight-reading/config"
+	"sight-reading/database"
+)
+
+// Service is the generator the handlers use, set once at startup like
+// database.DBClient
+var Service Generator
+
+// Init builds the client to the music service, with the local note game as
+// its fallback, and puts the configured cache in front of it. The postgres
+// cache needs the database connected first
+func Init(cfg config.Config) error {
+	var generator Generator = NewFallback(NewClient(ClientOptions{
+		BaseURL: cfg.MusicServiceURL,
+		Token:   cfg.MusicServiceToken,
+		Timeout: cfg.MusicServiceTimeout,
+		Retries: 2,
+	}), NewLocal(nil))
+
+	var store Store
+	switch cfg.MusicCache {
+	case "off":
+	case "memory":
+		store = NewLRU(cfg.MusicCacheSize)
+	case "postgres":
+		store = NewPostgresStore(database.DBClient, cfg.MusicCacheTTL)
+	case "disk":
+		disk, err := NewDiskStore(cfg.MusicCacheDir, cfg.MusicCacheTTL)
+		if err != nil {
+			return err
+		}
+		store = disk
+	default:
+		return fmt.Errorf("unknown MUSIC_CACHE %q, expected memory, postgres, disk or off", cfg.MusicCache)
+	}
+
+	if store != nil {
+		generator = NewCached(generator, store, cfg.MusicCacheVariants)
+	}
+
+	Service = generator
+	return nil
+}
@@ -0,0 +1,207 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+
+	dtos "sight-reading/DTOs"
+)
+
+var ErrNotSupported = errors.New("not supported by the local generator")
+
+// Local generates note game questions in go. Mary and random melodies still
+// need the python service
+type Local struct {
+	rng *rand.Rand
+}
+
+// NewLocal uses rng for every choice, nil means the shared source
+func NewLocal(rng *rand.Rand) *Local {
+	return &Local{rng: rng}
+}
+
+func (local *Local) intN(n int) int {
+	if local.rng == nil {
+		return rand.IntN(n)
+	}
+	return local.rng.IntN(n)
+}
+
+func (local *Local) Mary(context.Context, dtos.MaryRequest) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (local *Local) Random(context.Context, dtos.RandomRequest) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+// NoteGame picks a note of the major scale on req.Scale, like the python
+// service, then applies the clef, range, accidental and ledger line options
+func (local *Local) NoteGame(_ context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error) {
+	options, err := ReadNoteGameRequest(req)
+	if err != nil {
+		return dtos.NoteGame{}, err
+	}
+
+	pitch := options.Candidates[local.intN(len(options.Candidates))]
+	if req.Accidentals && local.intN(3) == 0 {
+		pitch = local.alter(pitch)
+	}
+	return Question(pitch, options.Fifths, options.Clef)
+}
+
+// NoteGameOptions is a note game request read: the key signature, the clef
+// and the scale notes that fit the range and ledger lines asked for
+type NoteGameOptions struct {
+	Fifths     int
+	Clef       theory.Clef
+	Candidates []theory.Pitch
+}
+
+func ReadNoteGameRequest(req dtos.NoteGameRequest) (NoteGameOptions, error) {
+	var options NoteGameOptions
+
+	tonic, err := theory.ParsePitch(req.Scale)
+	if err != nil {
+		return options, badRequest(err.Error())
+	}
+	octave, err := strconv.Atoi(req.Octave)
+	if err != nil || octave < 0 || octave > 9 {
+		return options, badRequest("octave must be a number from 0 to 9")
+	}
+	tonic.Octave = octave
+
+	if options.Fifths, err = theory.KeyFifths(tonic); err != nil {
+		return options, badRequest(err.Error())
+	}
+	if options.Clef, err = theory.ParseClef(req.Clef); err != nil {
+		return options, badRequest(err.Error())
+	}
+
+	low, high := 0, 127
+	if req.RangeLow != "" {
+		pitch, err := theory.ParsePitch(req.RangeLow)
+		if err != nil {
+			return options, badRequest(err.Error())
+		}
+		low = pitch.MIDI()
+	}
+	if req.RangeHigh != "" {
+		pitch, err := theory.ParsePitch(req.RangeHigh)
+		if err != nil {
+			return options, badRequest(err.Error())
+		}
+		high = pitch.MIDI()
+	}
+
+	candidates := noteGameCandidates(tonic, max(req.Octaves, 1), options.Clef, req.MaxLedgerLines)
+	options.Candidates = slices.DeleteFunc(candidates, func(pitch theory.Pitch) bool {
+		return pitch.MIDI() < low || pitch.MIDI() > high
+	})
+	if len(options.Candidates) == 0 {
+		return options, badRequest("no note of the scale fits the range and ledger lines asked for")
+	}
+	return options, nil
+}
+
+// Question is the note game question for pitch
+func Question(pitch theory.Pitch, fifths int, clef theory.Clef) (dtos.NoteGame, error) {
+	var noteGame dtos.NoteGame
+
+	body, err := musicxml.Marshal(noteGameScore(pitch, fifths, clef))
+	if err != nil {
+		return noteGame, err
+	}
+
+	noteGame.GeneratedXML = string(body)
+	noteGame.NoteName = pitch.Name()
+	noteGame.NoteOctave = strconv.Itoa(pitch.Octave)
+	return noteGame, nil
+}
+
+func noteGameCandidates(tonic theory.Pitch, octaves int, clef theory.Clef, maxLedgerLines *int) []theory.Pitch {
+	var candidates []theory.Pitch
+	for i := 0; i < octaves; i++ {
+		for _, pitch := range theory.MajorScale(tonic.Transpose(7*i, 12*i)) {
+			if maxLedgerLines != nil && clef.LedgerLines(pitch) > *maxLedgerLines {
+				continue
+			}
+			candidates = append(candidates, pitch)
+		}
+	}
+	return candidates
+}
+
+// alter raises or lowers pitch a half step, keeping the letter and never
+// past a single sharp or flat, those are the only answers the game offers
+func (local *Local) alter(pitch theory.Pitch) theory.Pitch {
+	switch {
+	case pitch.Alter == 1:
+		pitch.Alter = 0
+	case pitch.Alter == -1:
+		pitch.Alter = 0
+	case local.intN(2) == 0:
+		pitch.Alter = 1
+	default:
+		pitch.Alter = -1
+	}
+	return pitch
+}
+
+// Altered is the notes a question with accidentals asks instead of pitch,
+// the same letter raised or lowered and never past a single sharp or flat
+func Altered(pitch theory.Pitch) []theory.Pitch {
+	if pitch.Alter != 0 {
+		pitch.Alter = 0
+		return []theory.Pitch{pitch}
+	}
+	sharp, flat := pitch, pitch
+	sharp.Alter, flat.Alter = 1, -1
+	return []theory.Pitch{sharp, flat}
+}
+
+// noteGameScore is a single whole note in one measure, with the key
+// signature and clef and the part name left blank as the python service does
+func noteGameScore(pitch theory.Pitch, fifths int, clef theory.Clef) *musicxml.Score {
+	note := &musicxml.Note{
+		Pitch:    pitch.MusicXML(),
+		Duration: 4,
+		Type:     "whole",
+	}
+	if pitch.Alter != theory.KeyAlter(fifths, pitch.Step) {
+		note.Accidental = map[int]string{-1: "flat", 0: "natural", 1: "sharp"}[pitch.Alter]
+	}
+
+	return &musicxml.Score{
+		Version: "3.1",
+		PartList: musicxml.PartList{
+			ScoreParts: []musicxml.ScorePart{{ID: "P1"}},
+		},
+		Parts: []musicxml.Part{{
+			ID: "P1",
+			Measures: []musicxml.Measure{{
+				Number: "1",
+				Elements: []musicxml.Element{
+					&musicxml.Attributes{
+						Divisions: 1,
+						Keys:      []musicxml.Key{{Fifths: fifths}},
+						Clefs:     []musicxml.Clef{clef.MusicXML()},
+					},
+					note,
+					&musicxml.Barline{Location: "right", Style: "light-heavy"},
+				},
+			}},
+		}},
+	}
+}
+
+func badRequest(message string) error {
+	return &RequestError{Status: http.StatusBadRequest, Message: message}
+}
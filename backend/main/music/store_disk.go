@@ -0,0 +1,130 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DiskStore keeps one file per exercise under dir, sharded by the first two
+// characters of the key so no directory gets too big. Files older than ttl
+// are misses and are removed by a sweep, 0 keeps them forever
+type DiskStore struct {
+	dir     string
+	ttl     time.Duration
+	sweeper *sweeper
+}
+
+func NewDiskStore(dir string, ttl time.Duration) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir, ttl: ttl, sweeper: newSweeper()}, nil
+}
+
+func (store *DiskStore) path(key string) string {
+	return filepath.Join(store.dir, key[:2], key)
+}
+
+func (store *DiskStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	file, err := os.Open(store.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if store.expired(info, time.Now()) {
+		return nil, false, nil
+	}
+
+	body, err := io.ReadAll(file)
+	if err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// Set writes to a temporary file and renames it, a reader never sees half an
+// exercise
+func (store *DiskStore) Set(_ context.Context, key string, value []byte) error {
+	path := store.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if store.ttl > 0 {
+		store.sweeper.run(store.Sweep)
+	}
+	return nil
+}
+
+// Sweep removes the expired files, temporary ones left by a crash included,
+// and returns how many
+func (store *DiskStore) Sweep(ctx context.Context) (int, error) {
+	if store.ttl <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	removed := 0
+	err := filepath.WalkDir(store.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !store.expired(info, now) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+func (store *DiskStore) expired(info fs.FileInfo, now time.Time) bool {
+	return store.ttl > 0 && now.Sub(info.ModTime()) >= store.ttl
+}
@@ -0,0 +1,72 @@
+package music
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore keeps exercises in the generated_exercises table so every
+// instance of the api shares them. Exercises older than ttl are misses and
+// are deleted by a sweep, 0 keeps them forever
+type PostgresStore struct {
+	db      *sqlx.DB
+	ttl     time.Duration
+	sweeper *sweeper
+}
+
+func NewPostgresStore(db *sqlx.DB, ttl time.Duration) *PostgresStore {
+	return &PostgresStore{db: db, ttl: ttl, sweeper: newSweeper()}
+}
+
+func (store *PostgresStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var body []byte
+	err := store.db.GetContext(ctx, &body, `SELECT body FROM generated_exercises WHERE key = $1 AND created_at > $2`, key, store.cutoff())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// Set stores value, replacing an expired exercise the sweep has not deleted
+// yet
+func (store *PostgresStore) Set(ctx context.Context, key string, value []byte) error {
+	query := `
+  INSERT INTO generated_exercises (key, body)
+  VALUES ($1, $2)
+  ON CONFLICT (key) DO UPDATE SET body = excluded.body, created_at = now()
+  WHERE generated_exercises.created_at <= $3
+  `
+	_, err := store.db.ExecContext(ctx, query, key, value, store.cutoff())
+	if err == nil && store.ttl > 0 {
+		store.sweeper.run(store.Sweep)
+	}
+	return err
+}
+
+// Sweep deletes the expired exercises and returns how many
+func (store *PostgresStore) Sweep(ctx context.Context) (int, error) {
+	if store.ttl <= 0 {
+		return 0, nil
+	}
+	result, err := store.db.ExecContext(ctx, `DELETE FROM generated_exercises WHERE created_at <= $1`, store.cutoff())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// cutoff is when the oldest exercise still served was stored
+func (store *PostgresStore) cutoff() time.Time {
+	if store.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-store.ttl)
+}
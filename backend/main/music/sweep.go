@@ -0,0 +1,55 @@
+package music
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sight-reading/logging"
+)
+
+const (
+	// sweepInterval is how often a store deletes its expired exercises
+	sweepInterval = time.Hour
+	// sweepTimeout bounds one sweep of a store
+	sweepTimeout = 5 * time.Minute
+)
+
+// sweeper runs a store's sweep in the background at most once every
+// sweepInterval, a write never waits for it
+type sweeper struct {
+	mu      sync.Mutex
+	last    time.Time
+	running bool
+}
+
+// newSweeper waits a full interval before the first sweep, a restart does not
+// sweep again
+func newSweeper() *sweeper {
+	return &sweeper{last: time.Now()}
+}
+
+func (s *sweeper) run(sweep func(ctx context.Context) (int, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running || time.Since(s.last) < sweepInterval {
+		return
+	}
+	s.running, s.last = true, time.Now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sweepTimeout)
+		defer cancel()
+
+		removed, err := sweep(ctx)
+		if err != nil {
+			logging.Logger.Warn("music cache sweep failed", "error", err)
+		} else if removed > 0 {
+			logging.Logger.Info("music cache swept", "removed", removed)
+		}
+
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+}
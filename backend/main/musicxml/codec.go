@@ -0,0 +1,135 @@
+package musicxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+const doctype = `<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 3.1 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">`
+
+// Parse reads a score-partwise document, score-timewise is not supported
+func Parse(r io.Reader) (*Score, error) {
+	decoder := xml.NewDecoder(r)
+
+	var score Score
+	if err := decoder.Decode(&score); err != nil {
+		if err, ok := err.(xml.UnmarshalError); ok {
+			return nil, fmt.Errorf("not a score-partwise document: %w", err)
+		}
+		return nil, err
+	}
+	return &score, nil
+}
+
+func ParseBytes(data []byte) (*Score, error) {
+	return Parse(bytes.NewReader(data))
+}
+
+func ParseFile(path string) (*Score, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Encode writes score with the xml declaration and the 3.1 doctype
+func Encode(w io.Writer, score *Score) error {
+	if _, err := io.WriteString(w, xml.Header+doctype+"\n"); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(score); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func Marshal(score *Score) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, score); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (measure *Measure) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "number":
+			measure.Number = attr.Value
+		case "width":
+			measure.Width = attr.Value
+		case "implicit":
+			measure.Implicit = attr.Value
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			var element Element
+			switch token.Name.Local {
+			case "attributes":
+				element = &Attributes{}
+			case "note":
+				element = &Note{}
+			case "backup":
+				element = &Backup{}
+			case "forward":
+				element = &Forward{}
+			case "direction":
+				element = &Direction{}
+			case "barline":
+				element = &Barline{}
+			default:
+				element = &Raw{}
+			}
+
+			if err := decoder.DecodeElement(element, &token); err != nil {
+				return fmt.Errorf("measure %s: %w", measure.Number, err)
+			}
+			measure.Elements = append(measure.Elements, element)
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (measure Measure) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "measure"}
+	start.Attr = nil
+	for _, attr := range []struct{ name, value string }{
+		{"number", measure.Number},
+		{"implicit", measure.Implicit},
+		{"width", measure.Width},
+	} {
+		if attr.value != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr.name}, Value: attr.value})
+		}
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, element := range measure.Elements {
+		name := xml.StartElement{Name: xml.Name{Local: element.elementName()}}
+		if err := encoder.EncodeElement(element, name); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
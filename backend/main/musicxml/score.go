@@ -0,0 +1,233 @@
+// Package musicxml reads and writes score-partwise MusicXML 3.1, the format
+// the music service generates (see test.xml at the root of the repo). Only
+// the elements the backend inspects are typed. Anything else directly in a
+// measure is kept as raw xml and written back where it was, but unknown
+// children of the typed elements, like a note's notehead or a key's
+// key-octave, and the part groups of the part list are dropped. A parsed
+// document writes back out with the same music, not always the same markup
+package musicxml
+
+import (
+	"encoding/xml"
+)
+
+type Score struct {
+	XMLName        xml.Name        `xml:"score-partwise"`
+	Version        string          `xml:"version,attr,omitempty"`
+	Work           *Work           `xml:"work"`
+	MovementTitle  string          `xml:"movement-title,omitempty"`
+	Identification *Identification `xml:"identification"`
+	Defaults       *Raw            `xml:"defaults"`
+	Credits        []Raw           `xml:"credit"`
+	PartList       PartList        `xml:"part-list"`
+	Parts          []Part          `xml:"part"`
+}
+
+type Work struct {
+	Title string `xml:"work-title,omitempty"`
+}
+
+type Identification struct {
+	Creators []Creator `xml:"creator"`
+	Encoding *Encoding `xml:"encoding"`
+}
+
+type Creator struct {
+	Type string `xml:"type,attr,omitempty"`
+	Name string `xml:",chardata"`
+}
+
+type Encoding struct {
+	Date     string   `xml:"encoding-date,omitempty"`
+	Software []string `xml:"software"`
+}
+
+type PartList struct {
+	ScoreParts []ScorePart `xml:"score-part"`
+}
+
+type ScorePart struct {
+	ID         string           `xml:"id,attr"`
+	Name       string           `xml:"part-name"`
+	Instrument *ScoreInstrument `xml:"score-instrument"`
+	Midi       *MidiInstrument  `xml:"midi-instrument"`
+}
+
+type ScoreInstrument struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"instrument-name"`
+}
+
+type MidiInstrument struct {
+	ID      string `xml:"id,attr"`
+	Channel int    `xml:"midi-channel,omitempty"`
+	Program int    `xml:"midi-program,omitempty"`
+}
+
+type Part struct {
+	ID       string    `xml:"id,attr"`
+	Measures []Measure `xml:"measure"`
+}
+
+// Measure keeps its children in document order, a measure interleaves
+// attributes, notes, backups and forwards and the order is the timing
+type Measure struct {
+	Number   string
+	Width    string
+	Implicit string
+	Elements []Element
+}
+
+// Element is one child of a measure: *Attributes, *Note, *Backup, *Forward,
+// *Direction, *Barline or *Raw for anything else
+type Element interface {
+	elementName() string
+}
+
+type Attributes struct {
+	Divisions int         `xml:"divisions,omitempty"`
+	Keys      []Key       `xml:"key"`
+	Times     []Time      `xml:"time"`
+	Staves    int         `xml:"staves,omitempty"`
+	Clefs     []Clef      `xml:"clef"`
+	Transpose []Transpose `xml:"transpose"`
+}
+
+type Key struct {
+	Fifths int    `xml:"fifths"`
+	Mode   string `xml:"mode,omitempty"`
+}
+
+type Time struct {
+	Beats    string `xml:"beats"`
+	BeatType string `xml:"beat-type"`
+}
+
+type Clef struct {
+	Number       int    `xml:"number,attr,omitempty"`
+	Sign         string `xml:"sign"`
+	Line         int    `xml:"line,omitempty"`
+	OctaveChange int    `xml:"clef-octave-change,omitempty"`
+}
+
+// Transpose is how the written part differs from concert pitch, chromatic is
+// in half steps
+type Transpose struct {
+	Diatonic     int `xml:"diatonic,omitempty"`
+	Chromatic    int `xml:"chromatic"`
+	OctaveChange int `xml:"octave-change,omitempty"`
+}
+
+type Note struct {
+	Grace            *Empty            `xml:"grace"`
+	Chord            *Empty            `xml:"chord"`
+	Pitch            *Pitch            `xml:"pitch"`
+	Unpitched        *Raw              `xml:"unpitched"`
+	Rest             *Rest             `xml:"rest"`
+	Duration         int               `xml:"duration,omitempty"`
+	Ties             []Tie             `xml:"tie"`
+	Voice            string            `xml:"voice,omitempty"`
+	Type             string            `xml:"type,omitempty"`
+	Dots             []Empty           `xml:"dot"`
+	Accidental       string            `xml:"accidental,omitempty"`
+	TimeModification *TimeModification `xml:"time-modification"`
+	Stem             string            `xml:"stem,omitempty"`
+	Staff            int               `xml:"staff,omitempty"`
+	Beams            []Beam            `xml:"beam"`
+	Notations        []Raw             `xml:"notations"`
+	Lyrics           []Raw             `xml:"lyric"`
+}
+
+type Empty struct{}
+
+type Pitch struct {
+	Step   string  `xml:"step"`
+	Alter  float64 `xml:"alter,omitempty"`
+	Octave int     `xml:"octave"`
+}
+
+type Rest struct {
+	Measure string `xml:"measure,attr,omitempty"`
+}
+
+type Tie struct {
+	Type string `xml:"type,attr"`
+}
+
+type TimeModification struct {
+	ActualNotes int `xml:"actual-notes"`
+	NormalNotes int `xml:"normal-notes"`
+}
+
+type Beam struct {
+	Number int    `xml:"number,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+type Backup struct {
+	Duration int `xml:"duration"`
+}
+
+type Forward struct {
+	Duration int `xml:"duration"`
+}
+
+type Direction struct {
+	Placement string `xml:"placement,attr,omitempty"`
+	Inner     string `xml:",innerxml"`
+}
+
+type Barline struct {
+	Location string `xml:"location,attr,omitempty"`
+	Style    string `xml:"bar-style,omitempty"`
+	Inner    []Raw  `xml:",any"`
+}
+
+// Raw is an element kept as it was read
+type Raw struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+func (*Attributes) elementName() string { return "attributes" }
+func (*Note) elementName() string       { return "note" }
+func (*Backup) elementName() string     { return "backup" }
+func (*Forward) elementName() string    { return "forward" }
+func (*Direction) elementName() string  { return "direction" }
+func (*Barline) elementName() string    { return "barline" }
+func (raw *Raw) elementName() string    { return raw.XMLName.Local }
+
+// Notes returns the notes of the measure in order, chords and rests included
+func (measure *Measure) Notes() []*Note {
+	var notes []*Note
+	for _, element := range measure.Elements {
+		if note, ok := element.(*Note); ok {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// Attributes returns the first attributes of the measure, nil when it has
+// none
+func (measure *Measure) Attributes() *Attributes {
+	for _, element := range measure.Elements {
+		if attributes, ok := element.(*Attributes); ok {
+			return attributes
+		}
+	}
+	return nil
+}
+
+func (note *Note) IsRest() bool {
+	return note.Rest != nil
+}
+
+func (note *Note) IsChord() bool {
+	return note.Chord != nil
+}
+
+func (note *Note) IsGrace() bool {
+	return note.Grace != nil
+}
@@ -0,0 +1,179 @@
+package musicxml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+var stepSemitones = map[string]int{"C": 0, "D": 2, "E": 4, "F": 5, "G": 7, "A": 9, "B": 11}
+
+// MIDI is the midi note number of the pitch, middle C (C4) is 60. Microtonal
+// alters are rounded to the nearest half step
+func (pitch Pitch) MIDI() int {
+	return (pitch.Octave+1)*12 + stepSemitones[pitch.Step] + int(math.Round(pitch.Alter))
+}
+
+func (pitch Pitch) String() string {
+	accidental := ""
+	switch int(math.Round(pitch.Alter)) {
+	case -2:
+		accidental = "bb"
+	case -1:
+		accidental = "b"
+	case 1:
+		accidental = "#"
+	case 2:
+		accidental = "##"
+	}
+	return fmt.Sprintf("%s%s%d", pitch.Step, accidental, pitch.Octave)
+}
+
+// TimedNote is a note placed on the timeline of its part, onset and duration
+// are in quarter notes from the start of the part. Transpose is the
+// transpose element in effect, the half steps from written to sounding pitch
+type TimedNote struct {
+	Measure   string
+	Onset     float64
+	Duration  float64
+	Transpose int
+	Note      *Note
+}
+
+// Sounding is the midi number that sounds, the written pitch moved by the
+// part's transposition. Rests and unpitched notes have none
+func (timed TimedNote) Sounding() (int, bool) {
+	if timed.Note.Pitch == nil {
+		return 0, false
+	}
+	return timed.Note.Pitch.MIDI() + timed.Transpose, true
+}
+
+// Timeline flattens a part into its notes with absolute times, following
+// divisions changes, chords, backups and forwards. Grace notes take no time
+func (part *Part) Timeline() []TimedNote {
+	var timeline []TimedNote
+
+	offsets := part.MeasureOffsets()
+	divisions, transpose := 1, 0
+	for i := range part.Measures {
+		measure := &part.Measures[i]
+
+		position, lastOnset := 0.0, 0.0
+		for _, element := range measure.Elements {
+			switch element := element.(type) {
+			case *Attributes:
+				if element.Divisions > 0 {
+					divisions = element.Divisions
+				}
+				if len(element.Transpose) > 0 {
+					transpose = element.Transpose[0].Semitones()
+				}
+			case *Backup:
+				position -= float64(element.Duration) / float64(divisions)
+			case *Forward:
+				position += float64(element.Duration) / float64(divisions)
+			case *Note:
+				duration := float64(element.Duration) / float64(divisions)
+				onset := position
+				if element.IsChord() {
+					onset = lastOnset
+				} else if !element.IsGrace() {
+					position += duration
+				}
+				lastOnset = onset
+
+				timeline = append(timeline, TimedNote{
+					Measure:   measure.Number,
+					Onset:     offsets[i] + onset,
+					Duration:  duration,
+					Transpose: transpose,
+					Note:      element,
+				})
+			}
+		}
+	}
+
+	return timeline
+}
+
+// MeasureOffsets is where each measure starts, in quarter notes. A measure
+// lasts as long as its furthest note, so pickups and incomplete measures
+// take only the time they use
+func (part *Part) MeasureOffsets() []float64 {
+	offsets := make([]float64, len(part.Measures))
+
+	divisions := 1
+	start := 0.0
+	for i := range part.Measures {
+		offsets[i] = start
+
+		position, longest := 0.0, 0.0
+		for _, element := range part.Measures[i].Elements {
+			switch element := element.(type) {
+			case *Attributes:
+				if element.Divisions > 0 {
+					divisions = element.Divisions
+				}
+			case *Backup:
+				position -= float64(element.Duration) / float64(divisions)
+			case *Forward:
+				position += float64(element.Duration) / float64(divisions)
+			case *Note:
+				if !element.IsChord() && !element.IsGrace() {
+					position += float64(element.Duration) / float64(divisions)
+				}
+			}
+			longest = max(longest, position)
+		}
+		start += longest
+	}
+	return offsets
+}
+
+// Semitones is the whole transposition in half steps
+func (transpose Transpose) Semitones() int {
+	return transpose.Chromatic + 12*transpose.OctaveChange
+}
+
+// Divisions is the divisions in effect at the start of the part
+func (part *Part) Divisions() int {
+	for i := range part.Measures {
+		if attributes := part.Measures[i].Attributes(); attributes != nil && attributes.Divisions > 0 {
+			return attributes.Divisions
+		}
+	}
+	return 1
+}
+
+var tempoPattern = regexp.MustCompile(`tempo="([0-9.]+)"`)
+
+// Tempo is the first tempo the part sets with a sound element, in quarter
+// notes per minute
+func (part *Part) Tempo() (float64, bool) {
+	for _, measure := range part.Measures {
+		for _, element := range measure.Elements {
+			var inner string
+			switch element := element.(type) {
+			case *Direction:
+				inner = element.Inner
+			case *Raw:
+				if element.XMLName.Local != "sound" {
+					continue
+				}
+				for _, attr := range element.Attrs {
+					if attr.Name.Local == "tempo" {
+						inner = `tempo="` + attr.Value + `"`
+					}
+				}
+			}
+			if match := tempoPattern.FindStringSubmatch(inner); match != nil {
+				if tempo, err := strconv.ParseFloat(match[1], 64); err == nil && tempo > 0 {
+					return tempo, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,70 @@
+package musicxml
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks what the dtd cannot: every part is declared in the part
+// list, a part sets its divisions before timing anything, pitches are real
+// pitches and notes that take time have a duration. It returns every problem
+// found, joined
+func (score *Score) Validate() error {
+	var problems []error
+
+	declared := map[string]bool{}
+	for _, scorePart := range score.PartList.ScoreParts {
+		declared[scorePart.ID] = true
+	}
+	if len(score.Parts) == 0 {
+		problems = append(problems, errors.New("score has no parts"))
+	}
+
+	for _, part := range score.Parts {
+		if !declared[part.ID] {
+			problems = append(problems, fmt.Errorf("part %s is not in the part list", part.ID))
+		}
+
+		hasDivisions := false
+		for _, measure := range part.Measures {
+			for _, element := range measure.Elements {
+				switch element := element.(type) {
+				case *Attributes:
+					if element.Divisions > 0 {
+						hasDivisions = true
+					}
+				case *Note:
+					if err := validateNote(element, hasDivisions); err != nil {
+						problems = append(problems, fmt.Errorf("part %s measure %s: %w", part.ID, measure.Number, err))
+					}
+				}
+			}
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+func validateNote(note *Note, hasDivisions bool) error {
+	if note.Pitch != nil {
+		if _, ok := stepSemitones[note.Pitch.Step]; !ok {
+			return fmt.Errorf("invalid step %q", note.Pitch.Step)
+		}
+		if note.Pitch.Octave < 0 || note.Pitch.Octave > 9 {
+			return fmt.Errorf("octave %d is out of range", note.Pitch.Octave)
+		}
+	} else if note.Rest == nil && note.Unpitched == nil {
+		return errors.New("note has neither a pitch nor a rest")
+	}
+
+	if note.IsGrace() {
+		return nil
+	}
+	if note.Duration <= 0 {
+		return errors.New("note has no duration")
+	}
+	if !hasDivisions {
+		return errors.New("note comes before any divisions")
+	}
+	return nil
+}
@@ -0,0 +1,412 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route documents one gin route. Path is written the gin way (/student/:id),
+// path parameters are picked up from it automatically
+type Route struct {
+	Method  string
+	Path    string
+	Summary string
+	Tags    []string
+
+	// Request and Response are zero values of the body types, eg dtos.User{}
+	Request  any
+	Response any
+	Query    []Param
+
+	// Status defaults to 200, ContentType and RequestContentType to
+	// application/json
+	Status             int
+	ContentType        string
+	RequestContentType string
+
+	// Auth marks routes behind auth.Require, they need Config.Bearer
+	Auth bool
+}
+
+type Param struct {
+	Name        string
+	Description string
+	Type        string
+	Required    bool
+}
+
+// TagRule applies one validate tag (with its param, "max=10" gives "10") to a
+// schema. Rules for the stock validator tags live in defaultRules, the repo
+// specific ones (len255, role...) are passed to Generate
+type TagRule func(schema *Schema, param string)
+
+type Config struct {
+	Info   Info
+	Routes []Route
+	Rules  map[string]TagRule
+
+	// Problem is the error body, documented as the default response
+	Problem any
+
+	// Models are extra types that no route uses yet, eg the models the
+	// frontend shares with the music service
+	Models []any
+
+	// Bearer is the token scheme Auth routes are documented with
+	Bearer *SecurityScheme
+}
+
+// bearerScheme is the name Bearer is registered under
+const bearerScheme = "bearerAuth"
+
+type generator struct {
+	rules   map[string]TagRule
+	schemas map[string]*Schema
+}
+
+func Generate(config Config) *Document {
+	g := &generator{
+		rules:   map[string]TagRule{},
+		schemas: map[string]*Schema{},
+	}
+	for tag, rule := range defaultRules {
+		g.rules[tag] = rule
+	}
+	for tag, rule := range config.Rules {
+		g.rules[tag] = rule
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    config.Info,
+		Paths:   map[string]*PathItem{},
+	}
+	if config.Bearer != nil {
+		doc.Components.SecuritySchemes = map[string]*SecurityScheme{bearerScheme: config.Bearer}
+	}
+
+	var problemSchema *Schema
+	if config.Problem != nil {
+		problemSchema = g.schemaFor(reflect.TypeOf(config.Problem))
+	}
+
+	for _, model := range config.Models {
+		g.schemaFor(reflect.TypeOf(model))
+	}
+
+	for _, route := range config.Routes {
+		path, params := convertPath(route.Path)
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		op := &Operation{
+			OperationID: operationID(route.Method, path),
+			Summary:     route.Summary,
+			Tags:        route.Tags,
+			Responses:   map[string]*Response{},
+		}
+		if route.Auth && config.Bearer != nil {
+			op.Security = []SecurityRequirement{{bearerScheme: {}}}
+		}
+
+		for _, name := range params {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   pathParamSchema(name),
+			})
+		}
+		for _, query := range route.Query {
+			schemaType := query.Type
+			if schemaType == "" {
+				schemaType = "string"
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        query.Name,
+				In:          "query",
+				Description: query.Description,
+				Required:    query.Required,
+				Schema:      &Schema{Type: schemaType},
+			})
+		}
+
+		if route.Request != nil {
+			contentType := route.RequestContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]*MediaType{
+					contentType: {Schema: g.schemaFor(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &Response{Description: http.StatusText(status)}
+		if route.Response != nil {
+			contentType := route.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			success.Content = map[string]*MediaType{
+				contentType: {Schema: g.schemaFor(reflect.TypeOf(route.Response))},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = success
+
+		if problemSchema != nil {
+			op.Responses["default"] = &Response{
+				Description: "Problem details (RFC 7807)",
+				Content: map[string]*MediaType{
+					"application/problem+json": {Schema: problemSchema},
+				},
+			}
+		}
+
+		item.set(route.Method, op)
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// sql.Null* types are left alone on purpose, encoding/json writes them as
+// {"String": "", "Valid": false} objects and the spec should say so
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte{})
+)
+
+// schemaFor returns the schema of t, named structs become components and are
+// returned as a $ref
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema *Schema
+	switch t {
+	case timeType:
+		schema = &Schema{Type: "string", Format: "date-time"}
+	case bytesType:
+		schema = &Schema{Type: "string", Format: "byte"}
+	}
+	if schema != nil {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		schema = &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		schema = &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		schema = &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		schema = &Schema{Type: "number"}
+	case reflect.String:
+		schema = &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		schema = &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		schema = &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			schema = g.structSchema(t)
+			break
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// placeholder first so self referencing types terminate
+			g.schemas[t.Name()] = &Schema{}
+			*g.schemas[t.Name()] = *g.structSchema(t)
+		}
+		schema = &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		schema = &Schema{}
+	}
+
+	if nullable && schema.Ref == "" {
+		schema.Nullable = true
+	}
+	return schema
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		if jsonName, _, _ := strings.Cut(jsonTag, ","); jsonName != "" {
+			name = jsonName
+		}
+
+		// embedded structs are flattened by encoding/json
+		if field.Anonymous && jsonTag == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type)
+			for propName, prop := range embedded.Properties {
+				schema.Properties[propName] = prop
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		prop := g.schemaFor(field.Type)
+
+		// rules after dive are the validator's for each item
+		required := false
+		target := prop
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			tag, param, _ := strings.Cut(rule, "=")
+			if tag == "" {
+				continue
+			}
+			if tag == "dive" {
+				target = prop.Items
+				if target == nil {
+					break
+				}
+				continue
+			}
+			if tag == "required" {
+				required = required || target == prop
+				continue
+			}
+			if apply, ok := g.rules[tag]; ok && target.Ref == "" {
+				apply(target, param)
+			}
+		}
+
+		if doc := field.Tag.Get("doc"); doc != "" {
+			prop = withDescription(prop, doc)
+		}
+
+		schema.Properties[name] = prop
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// $ref siblings are ignored in 3.0, so wrap refs before describing them
+func withDescription(schema *Schema, description string) *Schema {
+	if schema.Ref != "" {
+		return &Schema{Description: description, AllOf: []*Schema{schema}}
+	}
+	schema.Description = description
+	return schema
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func floatPtr(n float64) *float64 {
+	return &n
+}
+
+var defaultRules = map[string]TagRule{
+	"email": func(s *Schema, _ string) { s.Format = "email" },
+	"alpha": func(s *Schema, _ string) { s.Pattern = "^[a-zA-Z]+$" },
+	"alphanum": func(s *Schema, _ string) {
+		s.Pattern = "^[a-zA-Z0-9]+$"
+	},
+	"url":  func(s *Schema, _ string) { s.Format = "uri" },
+	"uuid": func(s *Schema, _ string) { s.Format = "uuid" },
+	"oneof": func(s *Schema, param string) {
+		for _, v := range strings.Fields(param) {
+			s.Enum = append(s.Enum, v)
+		}
+	},
+	"min": func(s *Schema, param string) { applyBound(s, param, true) },
+	"max": func(s *Schema, param string) { applyBound(s, param, false) },
+	"gte": func(s *Schema, param string) { applyBound(s, param, true) },
+	"lte": func(s *Schema, param string) { applyBound(s, param, false) },
+}
+
+// min/max mean length on strings and value on numbers, same as the validator
+func applyBound(s *Schema, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch s.Type {
+	case "string":
+		if lower {
+			s.MinLength = intPtr(int(n))
+		} else {
+			s.MaxLength = intPtr(int(n))
+		}
+	case "integer", "number":
+		if lower {
+			s.Minimum = floatPtr(n)
+		} else {
+			s.Maximum = floatPtr(n)
+		}
+	case "array":
+		if lower {
+			s.MinItems = intPtr(int(n))
+		} else {
+			s.MaxItems = intPtr(int(n))
+		}
+	}
+}
+
+// convertPath turns /student/:id into /student/{id} and returns the params
+func convertPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// ids are numeric everywhere in this api, anything else is a string
+func pathParamSchema(name string) *Schema {
+	if name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "Id") {
+		return &Schema{Type: "integer"}
+	}
+	return &Schema{Type: "string"}
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.Trim(segment, "{}")
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+// Package practice counts practice streaks and progress toward daily goals.
+// Days are calendar dates in the school's timezone, "2006-01-02", so a
+// session at 11pm counts for the day the student lived it
+package practice
+
+import (
+	"math"
+	"time"
+
+	dtos "sight-reading/DTOs"
+
+	// schools name any IANA timezone, the image may not ship zoneinfo
+	_ "time/tzdata"
+)
+
+// MaxDays bounds how far back progress goes
+const MaxDays = 31
+
+// Day is the practice of one day
+type Day struct {
+	Date      string  `db:"day"`
+	Seconds   float64 `db:"seconds"`
+	Questions int     `db:"questions"`
+}
+
+// ValidTimezone reports whether name is an IANA timezone. Local is not one,
+// it would mean whatever the server runs in
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Streak counts the days in a row in practiced, any order, up to today. A
+// streak is current while its last day is today or yesterday
+func Streak(practiced []string, today string) (dtos.Streak, error) {
+	streak := dtos.Streak{Today: today}
+
+	end, err := time.Parse(time.DateOnly, today)
+	if err != nil {
+		return streak, err
+	}
+
+	days := map[time.Time]bool{}
+	var last time.Time
+	for _, date := range practiced {
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return streak, err
+		}
+		if day.After(end) {
+			// a clock running ahead of the school's
+			continue
+		}
+		days[day] = true
+		if day.After(last) {
+			last = day
+		}
+	}
+	if len(days) == 0 {
+		return streak, nil
+	}
+
+	for day := range days {
+		if days[day.AddDate(0, 0, -1)] {
+			// counted from the first day of its run
+			continue
+		}
+		length := 1
+		for days[day.AddDate(0, 0, length)] {
+			length++
+		}
+		streak.Longest = max(streak.Longest, length)
+	}
+
+	lastPracticed := last.Format(time.DateOnly)
+	streak.LastPracticed = &lastPracticed
+	streak.PracticedToday = last.Equal(end)
+	if last.Equal(end) || last.Equal(end.AddDate(0, 0, -1)) {
+		for days[last.AddDate(0, 0, -streak.Current)] {
+			streak.Current++
+		}
+	}
+	return streak, nil
+}
+
+// Progress is each of the days up to today against goal, oldest first, the
+// days without practice at zero. goal nil is progress without a goal, never
+// met
+func Progress(practiced []Day, goal *dtos.PracticeGoal, today string, days int) ([]dtos.DayProgress, error) {
+	end, err := time.Parse(time.DateOnly, today)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := map[string]Day{}
+	for _, day := range practiced {
+		byDate[day.Date] = day
+	}
+
+	progress := make([]dtos.DayProgress, days)
+	for i := range progress {
+		date := end.AddDate(0, 0, i-days+1).Format(time.DateOnly)
+		day := byDate[date]
+
+		progress[i] = dtos.DayProgress{
+			Date:      date,
+			Minutes:   math.Round(day.Seconds/60*10) / 10,
+			Questions: day.Questions,
+		}
+		if goal == nil {
+			continue
+		}
+
+		percent := 1.0
+		if goal.DailyMinutes != nil {
+			percent = math.Min(percent, day.Seconds/60/float64(*goal.DailyMinutes))
+		}
+		if goal.DailyQuestions != nil {
+			percent = math.Min(percent, float64(day.Questions)/float64(*goal.DailyQuestions))
+		}
+		progress[i].Percent = math.Round(percent*1000) / 10
+		progress[i].Met = percent >= 1
+	}
+	return progress, nil
+}
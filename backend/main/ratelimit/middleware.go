@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sight-reading/apperrors"
+	"sight-reading/logging"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Group string
+
+const (
+	// Default applies to every request
+	Default Group = "default"
+	// Signup guards user creation
+	Signup Group = "signup"
+	// Auth guards anything that takes a secret, where a low limit on failures
+	// is what stops brute forcing. The api has no login or join code routes,
+	// tokens are issued with the issue-token command, so today it only counts
+	// rejected bearer tokens. Such routes go behind For(Auth) when added
+	Auth Group = "auth"
+	// Music guards the music routes per student, so one of them cannot keep
+	// the generator busy for everyone
+	Music Group = "music"
+)
+
+var defaultLimits = map[Group]Limit{
+	Default: {Rate: 10, Burst: 40},
+	Signup:  {Rate: 5.0 / 60, Burst: 5},
+	Auth:    {Rate: 10.0 / 60, Burst: 5},
+	Music:   {Rate: 1, Burst: 30},
+}
+
+var (
+	mu       sync.Mutex
+	limiters = map[Group]*Limiter{}
+)
+
+// For returns the middleware of a route group. Routes in the same group share
+// their buckets, so hammering one of them also counts against the others
+//
+// Limits default to defaultLimits and are overridden with RATE_LIMIT_<GROUP>,
+// written as <tokens>/<s|m|h>:<burst>, eg RATE_LIMIT_AUTH="10/m:5"
+func For(group Group) gin.HandlerFunc {
+	limiter := limiterFor(group)
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, ok := c.Get(logging.UserIDKey); ok {
+			key = fmt.Sprintf("user:%v", userID)
+		}
+
+		allowed, retryAfter := limiter.Allow(key)
+		if allowed {
+			c.Next()
+			return
+		}
+		refuse(c, group, key, retryAfter)
+	}
+}
+
+// Failures returns the middleware of a group that only counts failures,
+// requests answered 401. It goes in front of the check that fails, once the
+// bucket of an ip is empty its requests are refused before the secret is
+// looked at. Requests without an Authorization header have nothing to guess
+// and pass
+func Failures(group Group) gin.HandlerFunc {
+	limiter := limiterFor(group)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if allowed, retryAfter := limiter.Check(key); !allowed {
+			refuse(c, group, key, retryAfter)
+			return
+		}
+
+		c.Next()
+		if c.Writer.Status() == http.StatusUnauthorized {
+			limiter.Allow(key)
+		}
+	}
+}
+
+func refuse(c *gin.Context, group Group, key string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	logging.FromContext(c).Warn("rate limited", "group", group, "key", key)
+	apperrors.Abort(c, apperrors.RateLimited("too many requests, try again later"))
+}
+
+func limiterFor(group Group) *Limiter {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if limiter, ok := limiters[group]; ok {
+		return limiter
+	}
+
+	limit := defaultLimits[group]
+	if raw := os.Getenv("RATE_LIMIT_" + strings.ToUpper(string(group))); raw != "" {
+		parsed, err := ParseLimit(raw)
+		if err != nil {
+			logging.Logger.Error("ignoring invalid rate limit", "group", group, "error", err)
+		} else {
+			limit = parsed
+		}
+	}
+
+	limiters[group] = NewLimiter(limit)
+	return limiters[group]
+}
+
+// ParseLimit reads <tokens>/<s|m|h>:<burst>, the burst defaults to tokens
+func ParseLimit(raw string) (Limit, error) {
+	rate, burstStr, hasBurst := strings.Cut(raw, ":")
+	tokensStr, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 10/m:5", raw)
+	}
+
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil || tokens <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid token count", raw)
+	}
+
+	var per float64
+	switch unit {
+	case "s":
+		per = 1
+	case "m":
+		per = 60
+	case "h":
+		per = 3600
+	default:
+		return Limit{}, fmt.Errorf("rate limit %q must use s, m or h", raw)
+	}
+
+	burst := int(math.Ceil(tokens))
+	if hasBurst {
+		burst, err = strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return Limit{}, fmt.Errorf("rate limit %q has an invalid burst", raw)
+		}
+	}
+
+	return Limit{Rate: tokens / per, Burst: burst}, nil
+}
@@ -0,0 +1,183 @@
+// Package rating estimates how well a student reads, per skill, on an Elo
+// scale. Every question and performance is a game between the student and
+// the item, the item rated by its difficulty level, so a right answer to a
+// hard question gains more than one to an easy question and ratings compare
+// across difficulty settings where raw accuracy does not
+package rating
+
+import (
+	"math"
+	"slices"
+
+	"sight-reading/grading"
+	"sight-reading/theory"
+)
+
+// Skill dimensions
+const (
+	TrebleNotes   = "treble_notes"
+	BassNotes     = "bass_notes"
+	KeySignatures = "key_signatures"
+	Rhythm        = "rhythm"
+)
+
+var Dimensions = []string{TrebleNotes, BassNotes, KeySignatures, Rhythm}
+
+// Sources of the sessions that move ratings
+const (
+	SourceNoteGame    = "note-game"
+	SourcePerformance = "performance"
+)
+
+const (
+	// Initial is the rating of a skill never rated, it is an item of the
+	// middle difficulty level
+	Initial = 1000
+	// Scale is the rating gap at which the stronger side is expected to
+	// score ten times as often as the weaker
+	Scale = 400
+
+	k = 32
+	// a student's first sessions move their ratings twice as far, so they
+	// find their level quickly
+	provisionalK        = 64
+	provisionalSessions = 5
+	// fullWeight is how many notes a session needs to move a rating by the
+	// whole K, a three question session is weaker evidence
+	fullWeight = 10
+)
+
+// Observation is one item of a session: the student scored Score, 0 to 1,
+// on something of difficulty Level, 1 to 10 as the difficulty package
+// rates. Weight is how many notes it stands for
+type Observation struct {
+	Dimension string
+	Level     float64
+	Score     float64
+	Weight    float64
+}
+
+type Rating struct {
+	Dimension string  `db:"dimension" json:"dimension"`
+	Rating    float64 `db:"rating"    json:"rating"`
+	Sessions  int     `db:"sessions"  json:"sessions"`
+}
+
+func New(dimension string) Rating {
+	return Rating{Dimension: dimension, Rating: Initial}
+}
+
+// ItemRating puts a difficulty level on the rating scale, every level is 100
+// points and the middle one, 5.5, is Initial
+func ItemRating(level float64) float64 {
+	return Initial + (math.Max(1, math.Min(level, 10))-5.5)*100
+}
+
+// Expected is the score a student of rating is expected to get on an item
+func Expected(rating, item float64) float64 {
+	return 1 / (1 + math.Pow(10, (item-rating)/Scale))
+}
+
+// Update rates a session on r's dimension, observations of other dimensions
+// are left out. The rating moves by K times how far the session scored
+// above or below what was expected, per note. It reports false when the
+// session has nothing to rate on the dimension
+func (r Rating) Update(observations []Observation) (Rating, bool) {
+	var surprise, weight float64
+	for _, observation := range observations {
+		if observation.Dimension != r.Dimension || observation.Weight <= 0 {
+			continue
+		}
+		expected := Expected(r.Rating, ItemRating(observation.Level))
+		surprise += observation.Weight * (observation.Score - expected)
+		weight += observation.Weight
+	}
+	if weight == 0 {
+		return r, false
+	}
+
+	factor := float64(k)
+	if r.Sessions < provisionalSessions {
+		factor = provisionalK
+	}
+	delta := factor * surprise / weight * math.Min(1, weight/fullWeight)
+
+	r.Rating = math.Round((r.Rating+delta)*100) / 100
+	r.Sessions++
+	return r, true
+}
+
+// NoteAnswer is a note game answer as rated
+type NoteAnswer struct {
+	Pitch theory.Pitch
+	Clef  theory.Clef
+	// Fifths is the key of the session, nil when the game did not say
+	Fifths  *int
+	Correct bool
+}
+
+// NoteObservations rates note game answers. Treble and bass clef answers
+// rate reading notes on that clef, harder with every ledger line and an
+// accidental. Answers on a letter the key signature alters also rate key
+// signatures, harder with every sharp or flat. Alto and tenor clef answers
+// only rate key signatures
+func NoteObservations(answers []NoteAnswer) []Observation {
+	var observations []Observation
+	for _, answer := range answers {
+		score := 0.0
+		if answer.Correct {
+			score = 1
+		}
+
+		keyAlter := 0
+		if answer.Fifths != nil {
+			keyAlter = theory.KeyAlter(*answer.Fifths, answer.Pitch.Step)
+		}
+
+		if dimension, ok := clefDimensions[answer.Clef]; ok {
+			level := 1 + 1.5*float64(answer.Clef.LedgerLines(answer.Pitch))
+			if answer.Pitch.Alter != keyAlter {
+				level += 2
+			}
+			observations = append(observations, Observation{Dimension: dimension, Level: level, Score: score, Weight: 1})
+		}
+
+		if answer.Fifths != nil && keyAlter != 0 {
+			level := 1 + 1.5*math.Abs(float64(*answer.Fifths))
+			observations = append(observations, Observation{Dimension: KeySignatures, Level: level, Score: score, Weight: 1})
+		}
+	}
+	return observations
+}
+
+var clefDimensions = map[theory.Clef]string{
+	theory.Treble: TrebleNotes,
+	theory.Bass:   BassNotes,
+}
+
+// PerformanceObservations rates a graded performance of an exercise of
+// difficulty level on rhythm, every expected note is an item
+func PerformanceObservations(level float64, result grading.Result) []Observation {
+	if result.Expected == 0 {
+		return nil
+	}
+	return []Observation{{
+		Dimension: Rhythm,
+		Level:     level,
+		Score:     result.RhythmAccuracy,
+		Weight:    float64(result.Expected),
+	}}
+}
+
+// dimensionsOf is the dimensions observations rate, in Dimensions order
+func dimensionsOf(observations []Observation) []string {
+	var dimensions []string
+	for _, dimension := range Dimensions {
+		if slices.ContainsFunc(observations, func(observation Observation) bool {
+			return observation.Dimension == dimension
+		}) {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	return dimensions
+}
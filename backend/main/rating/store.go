@@ -0,0 +1,85 @@
+package rating
+
+import (
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Change is a rating a session moved
+type Change struct {
+	Rating
+	Delta float64 `json:"delta"`
+}
+
+// Apply rates a session of userID at at, updates skill_ratings and records
+// the history. It works on the db or inside a transaction, in a transaction
+// the ratings are locked until it ends so sessions rated at once do not
+// overwrite each other
+func Apply(db sqlx.Ext, userID int, source string, sourceID int64, at time.Time, observations []Observation) ([]Change, error) {
+	dimensions := dimensionsOf(observations)
+	if len(dimensions) == 0 {
+		return []Change{}, nil
+	}
+
+	var current []Rating
+	query := `
+  SELECT dimension, rating, sessions
+  FROM skill_ratings
+  WHERE user_id = $1 AND dimension = ANY($2)
+  FOR UPDATE
+  `
+	if err := sqlx.Select(db, &current, query, userID, pq.StringArray(dimensions)); err != nil {
+		return nil, err
+	}
+	ratings := map[string]Rating{}
+	for _, rating := range current {
+		ratings[rating.Dimension] = rating
+	}
+
+	upsert := `
+  INSERT INTO skill_ratings (user_id, dimension, rating, sessions, updated_at)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT (user_id, dimension) DO UPDATE SET
+    rating = excluded.rating,
+    sessions = excluded.sessions,
+    updated_at = excluded.updated_at
+  `
+	history := `
+  INSERT INTO skill_rating_history (
+    user_id, dimension, rating, delta, observations, source, source_id, rated_at
+  )
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+  `
+
+	changes := []Change{}
+	for _, dimension := range dimensions {
+		before, ok := ratings[dimension]
+		if !ok {
+			before = New(dimension)
+		}
+		after, ok := before.Update(observations)
+		if !ok {
+			continue
+		}
+
+		count := 0
+		for _, observation := range observations {
+			if observation.Dimension == dimension {
+				count++
+			}
+		}
+
+		delta := math.Round((after.Rating-before.Rating)*100) / 100
+		if _, err := db.Exec(upsert, userID, dimension, after.Rating, after.Sessions, at); err != nil {
+			return nil, err
+		}
+		if _, err := db.Exec(history, userID, dimension, after.Rating, delta, count, source, sourceID, at); err != nil {
+			return nil, err
+		}
+		changes = append(changes, Change{Rating: after, Delta: delta})
+	}
+	return changes, nil
+}
@@ -0,0 +1,245 @@
+// Package recommend nudges note game settings from a student's latest
+// sessions. An accurate and fluent student gets a harder key, an accurate
+// but slow one a faster tempo and one whose sessions are short more notes.
+// A struggling student slows down and, at the slowest tempo, goes back a key.
+// Every setting comes with the reason it changed or stayed
+package recommend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"sight-reading/theory"
+
+	dtos "sight-reading/DTOs"
+)
+
+// Settings
+const (
+	Scale     = "scale"
+	Octaves   = "octaves"
+	NoteCount = "note_count"
+	Tempo     = "tempo"
+)
+
+// Changes
+const (
+	Up   = "up"
+	Down = "down"
+	Keep = "keep"
+)
+
+// DefaultRules are the rules of a class whose teacher has not set any
+var DefaultRules = dtos.RecommendationRules{
+	Sessions:        5,
+	MinSessions:     3,
+	MinSeconds:      30,
+	PromoteAccuracy: 0.9,
+	DemoteAccuracy:  0.6,
+	TargetNPM:       30,
+	TargetSeconds:   120,
+	NoteCountStep:   5,
+	MaxNoteCount:    50,
+	TempoStep:       10,
+	MinTempo:        40,
+	MaxTempo:        160,
+	MaxOctaves:      3,
+}
+
+// DefaultSettings are what a student practices with until they say
+var DefaultSettings = dtos.PracticeSettings{Scale: "C", Octave: 4, Octaves: 1, NoteCount: 10, Tempo: 60}
+
+// keys are the major keys from easiest, by how many sharps or flats, sharps
+// first
+var keys = []int{0, 1, -1, 2, -2, 3, -3, 4, -4, 5, -5, 6, -6, 7, -7}
+
+var ErrUnknownScale = errors.New("scale must be the tonic of a major key with at most 7 sharps or flats")
+
+// Session is a note game session as recommend sees it, a note_game_entries
+// row
+type Session struct {
+	Total   int `db:"total_questions"`
+	Correct int `db:"correct_questions"`
+	NPM     int `db:"notes_per_minute"`
+	Seconds int `db:"seconds"`
+}
+
+// Recommend is the next settings after sessions, newest first, under rules
+func Recommend(sessions []Session, current dtos.PracticeSettings, rules dtos.RecommendationRules) (dtos.Recommendation, error) {
+	recommendation := dtos.Recommendation{Current: current, Recommended: current, Rules: rules}
+
+	key, err := scaleIndex(current.Scale)
+	if err != nil {
+		return recommendation, err
+	}
+
+	var total, correct, npm, seconds float64
+	counted := 0
+	for _, session := range sessions {
+		if counted == rules.Sessions {
+			break
+		}
+		if session.Total == 0 || session.Seconds < rules.MinSeconds {
+			continue
+		}
+		counted++
+		total += float64(session.Total)
+		correct += float64(session.Correct)
+		npm += float64(session.NPM)
+		seconds += float64(session.Seconds)
+	}
+	recommendation.Sessions = counted
+
+	keep := func(setting, reason string) {
+		value := settingValue(current, setting)
+		recommendation.Changes = append(recommendation.Changes, dtos.SettingChange{
+			Setting: setting, From: value, To: value, Change: Keep, Reason: reason,
+		})
+	}
+	change := func(setting, direction, reason string) {
+		recommendation.Changes = append(recommendation.Changes, dtos.SettingChange{
+			Setting: setting,
+			From:    settingValue(current, setting),
+			To:      settingValue(recommendation.Recommended, setting),
+			Change:  direction,
+			Reason:  reason,
+		})
+	}
+
+	if counted < rules.MinSessions {
+		reason := fmt.Sprintf("only %d of the %d sessions needed to recommend a change", counted, rules.MinSessions)
+		for _, setting := range []string{Scale, Octaves, NoteCount, Tempo} {
+			keep(setting, reason)
+		}
+		return recommendation, nil
+	}
+
+	accuracy := correct / total
+	recommendation.Accuracy = round(accuracy)
+	recommendation.NPM = round(npm / float64(counted))
+	recommendation.SessionSeconds = round(seconds / float64(counted))
+	over := fmt.Sprintf("over the last %d sessions", counted)
+
+	switch {
+	case accuracy >= rules.PromoteAccuracy && recommendation.NPM >= float64(rules.TargetNPM):
+		fluent := fmt.Sprintf("%s accuracy and %v notes per minute %s reach the %s and %d targets",
+			percent(accuracy), recommendation.NPM, over, percent(rules.PromoteAccuracy), rules.TargetNPM)
+		switch {
+		case key < len(keys)-1:
+			recommendation.Recommended.Scale = scaleName(keys[key+1])
+			change(Scale, Up, fluent+", a key with more sharps or flats is next")
+			keep(Octaves, "one harder setting at a time, the key changes first")
+		case current.Octaves < rules.MaxOctaves:
+			recommendation.Recommended.Octaves++
+			keep(Scale, "no major key has more sharps or flats")
+			change(Octaves, Up, fluent+", a wider range is next")
+		default:
+			keep(Scale, "no major key has more sharps or flats")
+			keep(Octaves, fmt.Sprintf("already at the class maximum of %d octaves", rules.MaxOctaves))
+		}
+		keep(Tempo, "reading speed is on target")
+
+	case accuracy >= rules.PromoteAccuracy:
+		slow := fmt.Sprintf("%s accuracy %s but %v notes per minute is under the %d target",
+			percent(accuracy), over, recommendation.NPM, rules.TargetNPM)
+		keep(Scale, slow+", speed comes before a harder key")
+		keep(Octaves, slow+", speed comes before a wider range")
+		if current.Tempo+rules.TempoStep <= rules.MaxTempo {
+			recommendation.Recommended.Tempo += rules.TempoStep
+			change(Tempo, Up, slow+", a faster tempo builds speed")
+		} else {
+			keep(Tempo, fmt.Sprintf("%s, already at the class maximum of %d bpm", slow, rules.MaxTempo))
+		}
+
+	case accuracy <= rules.DemoteAccuracy:
+		struggling := fmt.Sprintf("%s accuracy %s is at or under the %s floor",
+			percent(accuracy), over, percent(rules.DemoteAccuracy))
+		keep(Octaves, struggling+", the range stays while the tempo and key ease")
+		if current.Tempo-rules.TempoStep >= rules.MinTempo {
+			recommendation.Recommended.Tempo -= rules.TempoStep
+			keep(Scale, struggling+", slowing down comes before an easier key")
+			change(Tempo, Down, struggling+", a slower tempo gives time to read")
+		} else if key > 0 {
+			recommendation.Recommended.Scale = scaleName(keys[key-1])
+			change(Scale, Down, fmt.Sprintf("%s and the tempo is at the class minimum of %d bpm, an easier key is next", struggling, rules.MinTempo))
+			keep(Tempo, fmt.Sprintf("already at the class minimum of %d bpm", rules.MinTempo))
+		} else {
+			keep(Scale, "C major is the easiest key")
+			keep(Tempo, fmt.Sprintf("already at the class minimum of %d bpm", rules.MinTempo))
+		}
+
+	default:
+		steady := fmt.Sprintf("%s accuracy %s is between the %s floor and the %s target, keep practicing at these settings",
+			percent(accuracy), over, percent(rules.DemoteAccuracy), percent(rules.PromoteAccuracy))
+		keep(Scale, steady)
+		keep(Octaves, steady)
+		keep(Tempo, steady)
+	}
+
+	switch {
+	case accuracy >= rules.PromoteAccuracy && recommendation.SessionSeconds < float64(rules.TargetSeconds) && current.NoteCount < rules.MaxNoteCount:
+		recommendation.Recommended.NoteCount = min(current.NoteCount+rules.NoteCountStep, rules.MaxNoteCount)
+		change(NoteCount, Up, fmt.Sprintf("sessions average %v seconds, under the %d second target, more notes make a longer practice",
+			recommendation.SessionSeconds, rules.TargetSeconds))
+	case accuracy >= rules.PromoteAccuracy && current.NoteCount >= rules.MaxNoteCount:
+		keep(NoteCount, fmt.Sprintf("already at the class maximum of %d notes", rules.MaxNoteCount))
+	case accuracy >= rules.PromoteAccuracy:
+		keep(NoteCount, fmt.Sprintf("sessions average %v seconds, long enough for the %d second target",
+			recommendation.SessionSeconds, rules.TargetSeconds))
+	default:
+		keep(NoteCount, "more notes only once accuracy reaches the target")
+	}
+
+	// the settings in the order the game shows them
+	order := []string{Scale, Octaves, NoteCount, Tempo}
+	slices.SortStableFunc(recommendation.Changes, func(a, b dtos.SettingChange) int {
+		return slices.Index(order, a.Setting) - slices.Index(order, b.Setting)
+	})
+	return recommendation, nil
+}
+
+func scaleIndex(scale string) (int, error) {
+	tonic, err := theory.ParsePitch(scale)
+	if err != nil {
+		return 0, ErrUnknownScale
+	}
+	fifths, err := theory.KeyFifths(tonic)
+	if err != nil {
+		return 0, ErrUnknownScale
+	}
+	index := slices.Index(keys, fifths)
+	if index < 0 {
+		return 0, ErrUnknownScale
+	}
+	return index, nil
+}
+
+// scaleName is the tonic of the major key with fifths sharps, or flats when
+// negative
+func scaleName(fifths int) string {
+	c := theory.Pitch{Step: 'C', Octave: 4}
+	return c.Transpose(4*fifths, 7*fifths).Name()
+}
+
+func settingValue(settings dtos.PracticeSettings, setting string) string {
+	switch setting {
+	case Scale:
+		return settings.Scale
+	case Octaves:
+		return strconv.Itoa(settings.Octaves)
+	case NoteCount:
+		return strconv.Itoa(settings.NoteCount)
+	}
+	return strconv.Itoa(settings.Tempo)
+}
+
+func percent(value float64) string {
+	return strconv.Itoa(int(math.Round(value*100))) + "%"
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,154 @@
+// Package repetition schedules the notes a student drills with SM-2, the
+// SuperMemo spaced repetition algorithm. Every pitch a student reads on a
+// clef is a card: answering it right pushes its next review further out,
+// answering it wrong brings it back in the next session
+package repetition
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultEase is the ease of a card never reviewed
+	DefaultEase = 2.5
+	// MinEase keeps hard cards from being scheduled ever closer together
+	MinEase = 1.3
+	// Relearn is when a missed card is due again, soon enough to come back
+	// in the next session
+	Relearn = 10 * time.Minute
+
+	day = 24 * time.Hour
+)
+
+// Reasons a note is in a question set
+const (
+	Due   = "due"
+	New   = "new"
+	Ahead = "ahead"
+)
+
+// Card is the schedule of one note. Interval is in days
+type Card struct {
+	Ease        float64   `db:"ease"          json:"ease"`
+	Interval    float64   `db:"interval_days" json:"interval_days"`
+	Repetitions int       `db:"repetitions"   json:"repetitions"`
+	Lapses      int       `db:"lapses"        json:"lapses"`
+	Due         time.Time `db:"due_at"        json:"due_at"`
+}
+
+func NewCard(now time.Time) Card {
+	return Card{Ease: DefaultEase, Due: now}
+}
+
+// Quality grades an answer on the SM-2 scale of 0 to 5. A wrong answer is a
+// 1, a right one is a 5 when it was read at a glance and a 3 when it had to
+// be worked out
+func Quality(correct bool, response time.Duration) int {
+	switch {
+	case !correct:
+		return 1
+	case response <= 2*time.Second:
+		return 5
+	case response <= 5*time.Second:
+		return 4
+	}
+	return 3
+}
+
+// Review schedules card after an answer of quality at now. Under 3 the card
+// starts over, otherwise it is due after 1 day, then 6, then the last
+// interval times the ease. The ease moves with every answer
+func (card Card) Review(quality int, now time.Time) Card {
+	quality = max(0, min(quality, 5))
+
+	miss := float64(5 - quality)
+	card.Ease = math.Max(MinEase, card.Ease+0.1-miss*(0.08+miss*0.02))
+	card.Ease = math.Round(card.Ease*100) / 100
+
+	if quality < 3 {
+		card.Repetitions = 0
+		card.Lapses++
+		card.Interval = 0
+		card.Due = now.Add(Relearn)
+		return card
+	}
+
+	card.Repetitions++
+	switch card.Repetitions {
+	case 1:
+		card.Interval = 1
+	case 2:
+		card.Interval = 6
+	default:
+		card.Interval = math.Round(card.Interval*card.Ease*1000) / 1000
+	}
+	card.Due = now.Add(time.Duration(card.Interval * float64(day)))
+	return card
+}
+
+// Choice is a note picked for a question set and why
+type Choice struct {
+	Note   string
+	Reason string
+	Due    *time.Time
+}
+
+// Plan picks count questions from notes, the notes the session can ask. It
+// takes the due cards first, the most overdue and hardest ahead, then notes
+// never asked, at most maxNew of them, then the cards due soonest. When
+// there are fewer notes than questions the due and hard ones come back
+// again. The set is shuffled with rng, nil means the shared source
+func Plan(notes []string, cards map[string]Card, count, maxNew int, now time.Time, rng *rand.Rand) []Choice {
+	var due, ahead, fresh []Choice
+	for _, note := range notes {
+		card, ok := cards[note]
+		switch {
+		case !ok:
+			if len(fresh) < maxNew {
+				fresh = append(fresh, Choice{Note: note, Reason: New})
+			}
+		case !card.Due.After(now):
+			due = append(due, Choice{Note: note, Reason: Due, Due: &card.Due})
+		default:
+			ahead = append(ahead, Choice{Note: note, Reason: Ahead, Due: &card.Due})
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		a, b := cards[due[i].Note], cards[due[j].Note]
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		return a.Ease < b.Ease
+	})
+	sort.SliceStable(ahead, func(i, j int) bool {
+		return cards[ahead[i].Note].Due.Before(cards[ahead[j].Note].Due)
+	})
+
+	pool := slices.Concat(due, fresh, ahead)
+	if len(pool) == 0 || count <= 0 {
+		return []Choice{}
+	}
+
+	// what needs practice repeats first, everything else only once the
+	// pool runs out
+	repeat := due
+	if len(repeat) == 0 {
+		repeat = pool
+	}
+	plan := pool[:min(count, len(pool))]
+	for i := 0; len(plan) < count; i++ {
+		plan = append(plan, repeat[i%len(repeat)])
+	}
+
+	shuffle := rand.Shuffle
+	if rng != nil {
+		shuffle = rng.Shuffle
+	}
+	shuffle(len(plan), func(i, j int) { plan[i], plan[j] = plan[j], plan[i] })
+	return plan
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"net/http"
+	"sight-reading/apperrors"
 	"sight-reading/database"
+	"sight-reading/metrics"
 	"strconv"
 
 	dtos "sight-reading/DTOs"
@@ -21,12 +23,11 @@ func GetTeachers(c *gin.Context) {
 
 	var students []dtos.User
 
+	done := metrics.TimeQuery("GetTeachers")
 	err := database.DBClient.Select(&students, query)
+	done()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   err.Error(),
-			"message": "not able to get all the teachers",
-		})
+		_ = c.Error(apperrors.FromDB(err, "teachers"))
 		return
 	}
 	c.JSON(http.StatusOK, students)
@@ -36,10 +37,7 @@ func GetTeacher(c *gin.Context) {
 	idSrt := c.Param("id")
 	id, err := strconv.Atoi(idSrt)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   true,
-			"message": "Invalid request body",
-		})
+		_ = c.Error(apperrors.Validation("id must be a number"))
 		return
 	}
 
@@ -51,12 +49,11 @@ func GetTeacher(c *gin.Context) {
   FROM users
   WHERE id = $1;
   `
+	done := metrics.TimeQuery("GetTeacher")
 	err = database.DBClient.Get(&post, query, id)
+	done()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   true,
-			"message": "not found",
-		})
+		_ = c.Error(apperrors.FromDB(err, "teacher"))
 		return
 	}
 	c.JSON(http.StatusOK, post)
@@ -73,12 +70,11 @@ func GetSchoolTeachers(c *gin.Context) {
 
 	var teachers []dtos.User
 
+	done := metrics.TimeQuery("GetSchoolTeachers")
 	err := database.DBClient.Select(&teachers, query)
+	done()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   err.Error(),
-			"message": "not able to get all the teachers",
-		})
+		_ = c.Error(apperrors.FromDB(err, "teachers"))
 		return
 	}
 
@@ -95,12 +91,11 @@ func GetSchoolStudents(c *gin.Context) {
 
 	var students []dtos.User
 
+	done := metrics.TimeQuery("GetSchoolStudents")
 	err := database.DBClient.Select(&students, query)
+	done()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   err.Error(),
-			"message": "not able to get all the teachers",
-		})
+		_ = c.Error(apperrors.FromDB(err, "students"))
 		return
 	}
 
@@ -0,0 +1,613 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/difficulty"
+	"sight-reading/metrics"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const exerciseColumns = `
+    id, school_id, uploaded_by, title, composer, instrument, key,
+    difficulty, tags, shared, created_at
+`
+
+// visibleExercise is the where clause for exercises the signed in user may
+// see: $1 is their school, $2 their id and $3 their role. Teachers see their
+// own and the shared ones, admins everything of their school. Students see
+// none of them, only what they were assigned
+const visibleExercise = `
+  school_id = $1 AND (
+    (shared AND $3::text <> 'STUDENT') OR uploaded_by = $2 OR $3::text = 'ADMIN'
+  )
+`
+
+// metadataLength is how many characters the title, composer and instrument
+// columns hold
+const metadataLength = 255
+
+// likeEscaper makes the wildcards of a search term match themselves, \ is
+// the default LIKE escape
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// UploadExercise takes a multipart form with the MusicXML, or an .abc tune,
+// in "file" or an ABC tune pasted in "abc", and optional title, composer,
+// instrument, tags and shared fields. ABC is stored converted to MusicXML
+func UploadExercise(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxScoreBytes+64<<10)
+	body, filename, ok := uploadedScore(c)
+	if !ok {
+		return
+	}
+
+	score, ok := parseScore(c, body)
+	if !ok {
+		return
+	}
+
+	metadata := formMetadata(c)
+	if err := metadata.ValidateExerciseMetadata(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	report, err := difficulty.Rate(score)
+	if err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	title, composer, instrument := scoreMetadata(score)
+	if title == "" {
+		title = clip(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	}
+	tags := []string{}
+	shared := false
+	if metadata.Title != nil {
+		title = *metadata.Title
+	}
+	if metadata.Composer != nil {
+		composer = *metadata.Composer
+	}
+	if metadata.Instrument != nil {
+		instrument = *metadata.Instrument
+	}
+	if metadata.Tags != nil {
+		tags = *metadata.Tags
+	}
+	if metadata.Shared != nil {
+		shared = *metadata.Shared
+	}
+
+	sum := sha256.Sum256(body)
+
+	query := `
+  INSERT INTO exercises (
+    school_id, uploaded_by, title, composer, instrument, key,
+    difficulty, tags, shared, checksum, body
+  )
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+  RETURNING` + exerciseColumns
+
+	var exercise dtos.Exercise
+
+	done := metrics.TimeQuery("UploadExercise")
+	err = database.DBClient.Get(&exercise, query,
+		claims.SchoolID, claims.UserID, title, composer, instrument, scoreKey(score),
+		report.Level, pq.StringArray(tags), shared, hex.EncodeToString(sum[:]), body,
+	)
+	done()
+	if err != nil {
+		// the same file twice in one school is the unique checksum
+		_ = c.Error(apperrors.FromDB(err, "exercise"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, exercise)
+}
+
+// SearchExercises lists the library of the signed in user's school. Every
+// filter is optional: q matches title and composer, tag, instrument, key,
+// min_difficulty and max_difficulty narrow it down
+func SearchExercises(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+
+	where := []string{visibleExercise}
+	args := []any{claims.SchoolID, claims.UserID, claims.Role}
+	filter := func(clause string, value any) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if q := c.Query("q"); q != "" {
+		filter("(title ILIKE $%[1]d OR composer ILIKE $%[1]d)", "%"+likeEscaper.Replace(q)+"%")
+	}
+	if tag := c.Query("tag"); tag != "" {
+		filter("tags @> ARRAY[$%d]::text[]", strings.ToLower(tag))
+	}
+	if instrument := c.Query("instrument"); instrument != "" {
+		filter("instrument ILIKE $%d", likeEscaper.Replace(instrument))
+	}
+	if key := c.Query("key"); key != "" {
+		filter("key = $%d", key)
+	}
+	if c.Query("mine") == "true" {
+		filter("uploaded_by = $%d", claims.UserID)
+	}
+	for _, bound := range []struct{ param, clause string }{
+		{"min_difficulty", "difficulty >= $%d"},
+		{"max_difficulty", "difficulty <= $%d"},
+	} {
+		if value := c.Query(bound.param); value != "" {
+			level, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				_ = c.Error(apperrors.Validation(bound.param + " must be a number"))
+				return
+			}
+			filter(bound.clause, level)
+		}
+	}
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	query := `
+  SELECT` + exerciseColumns + `
+  FROM exercises
+  WHERE ` + strings.Join(where, " AND ") + `
+  ORDER BY created_at DESC, id DESC
+  LIMIT ` + strconv.Itoa(limit) + ` OFFSET ` + strconv.Itoa(offset)
+
+	exercises := []dtos.Exercise{}
+
+	done := metrics.TimeQuery("SearchExercises")
+	err := database.DBClient.Select(&exercises, query, args...)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "exercises"))
+		return
+	}
+	c.JSON(http.StatusOK, exercises)
+}
+
+func GetExercise(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	query := `
+  SELECT` + exerciseColumns + `
+  FROM exercises
+  WHERE id = $4 AND (` + visibleExercise + ` OR id IN (
+    SELECT exercise_id FROM exercise_assignments WHERE student_id = $2
+  ))
+  `
+
+	var exercise dtos.Exercise
+
+	done := metrics.TimeQuery("GetExercise")
+	err := database.DBClient.Get(&exercise, query, claims.SchoolID, claims.UserID, claims.Role, id)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "exercise"))
+		return
+	}
+	c.JSON(http.StatusOK, exercise)
+}
+
+// GetExerciseXML serves the stored MusicXML, to anyone who can see the
+// exercise or has it assigned. It is served for ?instrument= or else for the
+// primary instrument of the user
+func GetExerciseXML(c *gin.Context) {
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	body, ok := readableExercise(c, id, "GetExerciseXML")
+	if !ok {
+		return
+	}
+
+	body, ok = forInstrument(c, body)
+	if !ok {
+		return
+	}
+
+	// stored exercises never change, the etag of a body stays valid
+	writeExercise(c, "private, max-age=86400", "application/xml", body)
+}
+
+// GetExerciseABC serves the exercise as an ABC tune, like the MusicXML it is
+// for ?instrument= or else the primary instrument of the user
+func GetExerciseABC(c *gin.Context) {
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	body, ok := readableExercise(c, id, "GetExerciseABC")
+	if !ok {
+		return
+	}
+	body, ok = forInstrument(c, body)
+	if !ok {
+		return
+	}
+
+	score, err := musicxml.ParseBytes(body)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	writeABC(c, score, "private, max-age=86400")
+}
+
+// GetExerciseMIDI converts the stored MusicXML to midi, same access as the
+// MusicXML itself
+func GetExerciseMIDI(c *gin.Context) {
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+	options, ok := midiOptions(c)
+	if !ok {
+		return
+	}
+
+	body, ok := readableExercise(c, id, "GetExerciseMIDI")
+	if !ok {
+		return
+	}
+
+	// it was validated on upload
+	score, err := musicxml.ParseBytes(body)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+
+	writeMIDI(c, score, options, "exercise-"+strconv.Itoa(id)+".mid")
+}
+
+// UpdateExercise edits the metadata, only the uploader or an admin can
+func UpdateExercise(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	var reqBody dtos.ExerciseMetadata
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if err := reqBody.ValidateExerciseMetadata(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	var tags any
+	if reqBody.Tags != nil {
+		tags = pq.StringArray(*reqBody.Tags)
+	}
+
+	// a null parameter keeps the current value
+	query := `
+  UPDATE exercises SET
+    title = COALESCE($5, title),
+    composer = COALESCE($6, composer),
+    instrument = COALESCE($7, instrument),
+    tags = COALESCE($8, tags),
+    shared = COALESCE($9, shared)
+  WHERE id = $4 AND school_id = $1 AND (uploaded_by = $2 OR $3::text = 'ADMIN')
+  RETURNING` + exerciseColumns
+
+	var exercise dtos.Exercise
+
+	done := metrics.TimeQuery("UpdateExercise")
+	err := database.DBClient.Get(&exercise, query,
+		claims.SchoolID, claims.UserID, claims.Role, id,
+		reqBody.Title, reqBody.Composer, reqBody.Instrument, tags, reqBody.Shared,
+	)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "exercise"))
+		return
+	}
+	c.JSON(http.StatusOK, exercise)
+}
+
+func DeleteExercise(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	query := `
+  DELETE FROM exercises
+  WHERE id = $4 AND school_id = $1 AND (uploaded_by = $2 OR $3::text = 'ADMIN')
+  `
+
+	done := metrics.TimeQuery("DeleteExercise")
+	result, err := database.DBClient.Exec(query, claims.SchoolID, claims.UserID, claims.Role, id)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "exercise"))
+		return
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		_ = c.Error(apperrors.NotFound("exercise not found"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AssignExercise hands a visible exercise to students. Teachers can only
+// assign to their own students, admins to any student of the school
+func AssignExercise(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	var reqBody dtos.AssignmentRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if err := reqBody.ValidateAssignment(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	var visible bool
+	err := database.DBClient.Get(&visible, `SELECT EXISTS (SELECT 1 FROM exercises WHERE id = $4 AND `+visibleExercise+`)`,
+		claims.SchoolID, claims.UserID, claims.Role, id)
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "exercise"))
+		return
+	}
+	if !visible {
+		_ = c.Error(apperrors.NotFound("exercise not found"))
+		return
+	}
+
+	studentIDs := pq.Array(reqBody.StudentIDs)
+	query := `
+  SELECT count(DISTINCT id)
+  FROM users
+  WHERE id = ANY($1) AND role = 'STUDENT' AND school_id = $2
+  AND ($3::text = 'ADMIN' OR id IN (SELECT student_id FROM teacher_to_student WHERE teacher_id = $4))
+  `
+	var allowed int
+	err = database.DBClient.Get(&allowed, query, studentIDs, claims.SchoolID, claims.Role, claims.UserID)
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "students"))
+		return
+	}
+	if allowed != len(dtos.UniqueInts(reqBody.StudentIDs)) {
+		_ = c.Error(apperrors.Forbidden("exercises can only be assigned to your own students"))
+		return
+	}
+
+	query = `
+  INSERT INTO exercise_assignments (exercise_id, student_id, assigned_by)
+  SELECT $1::int, student_id, $3::int
+  FROM unnest($2::int[]) AS student_id
+  ON CONFLICT (exercise_id, student_id) DO NOTHING
+  `
+
+	done := metrics.TimeQuery("AssignExercise")
+	result, err := database.DBClient.Exec(query, id, studentIDs, claims.UserID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "assignment"))
+		return
+	}
+
+	assigned, _ := result.RowsAffected()
+	c.JSON(http.StatusCreated, gin.H{"assigned": assigned})
+}
+
+// GetAssignments lists the exercises assigned to the signed in student
+func GetAssignments(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+
+	query := `
+  SELECT
+    e.id, e.school_id, e.uploaded_by, e.title, e.composer, e.instrument,
+    e.key, e.difficulty, e.tags, e.shared, e.created_at,
+    a.assigned_by, a.assigned_at
+  FROM exercise_assignments a
+  JOIN exercises e ON e.id = a.exercise_id
+  WHERE a.student_id = $1
+  ORDER BY a.assigned_at DESC
+  `
+
+	assignments := []dtos.Assignment{}
+
+	done := metrics.TimeQuery("GetAssignments")
+	err := database.DBClient.Select(&assignments, query, claims.UserID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "assignments"))
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
+
+func exerciseID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperrors.Validation("id must be a number"))
+		return 0, false
+	}
+	return id, true
+}
+
+// uploadedScore is the MusicXML of an upload and the name of its file, ABC
+// in the abc field or an .abc file is converted
+func uploadedScore(c *gin.Context) ([]byte, string, bool) {
+	if tune, ok := c.GetPostForm("abc"); ok {
+		body, ok := fromABC(c, tune)
+		return body, "", ok
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(apperrors.Validation("a MusicXML or ABC file is required in the file field, or an ABC tune in the abc field"))
+		return nil, "", false
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return nil, "", false
+	}
+	body, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return nil, "", false
+	}
+
+	if strings.EqualFold(filepath.Ext(fileHeader.Filename), ".abc") {
+		body, ok := fromABC(c, string(body))
+		return body, fileHeader.Filename, ok
+	}
+	return body, fileHeader.Filename, true
+}
+
+// readableExercise is the stored MusicXML of exercise id, for anyone who can
+// see the exercise or has it assigned
+func readableExercise(c *gin.Context, id int, queryName string) ([]byte, bool) {
+	claims, _ := auth.FromContext(c)
+
+	query := `
+  SELECT body
+  FROM exercises
+  WHERE id = $4 AND (` + visibleExercise + ` OR id IN (
+    SELECT exercise_id FROM exercise_assignments WHERE student_id = $2
+  ))
+  `
+
+	var body []byte
+
+	done := metrics.TimeQuery(queryName)
+	err := database.DBClient.Get(&body, query, claims.SchoolID, claims.UserID, claims.Role, id)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "exercise"))
+		return nil, false
+	}
+	return body, true
+}
+
+// pagination reads limit (default 50, at most 200) and offset
+func pagination(c *gin.Context) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		_ = c.Error(apperrors.Validation("limit must be a number from 1 to 200"))
+		return 0, 0, false
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		_ = c.Error(apperrors.Validation("offset must be a positive number"))
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+func formMetadata(c *gin.Context) dtos.ExerciseMetadata {
+	var metadata dtos.ExerciseMetadata
+	if title, ok := c.GetPostForm("title"); ok {
+		metadata.Title = &title
+	}
+	if composer, ok := c.GetPostForm("composer"); ok {
+		metadata.Composer = &composer
+	}
+	if instrument, ok := c.GetPostForm("instrument"); ok {
+		metadata.Instrument = &instrument
+	}
+	if tags, ok := c.GetPostFormArray("tags"); ok {
+		metadata.Tags = &tags
+	}
+	if shared, err := strconv.ParseBool(c.PostForm("shared")); err == nil {
+		metadata.Shared = &shared
+	}
+	return metadata
+}
+
+// scoreMetadata is the title, composer and instrument the MusicXML names,
+// clipped to fit their columns
+func scoreMetadata(score *musicxml.Score) (string, string, string) {
+	title := strings.TrimSpace(score.MovementTitle)
+	if score.Work != nil && score.Work.Title != "" {
+		title = score.Work.Title
+	}
+
+	composer := ""
+	if score.Identification != nil {
+		for _, creator := range score.Identification.Creators {
+			if creator.Type == "composer" {
+				composer = strings.TrimSpace(creator.Name)
+			}
+		}
+	}
+
+	instrument := ""
+	if parts := score.PartList.ScoreParts; len(parts) > 0 {
+		instrument = strings.TrimSpace(parts[0].Name)
+		if parts[0].Instrument != nil {
+			instrument = parts[0].Instrument.Name
+		}
+	}
+	return clip(title), clip(composer), clip(instrument)
+}
+
+// clip cuts text read from an upload to metadataLength characters, the
+// metadata fields of the form are validated instead
+func clip(text string) string {
+	if utf8.RuneCountInString(text) <= metadataLength {
+		return text
+	}
+	return string([]rune(text)[:metadataLength])
+}
+
+func scoreKey(score *musicxml.Score) string {
+	for _, part := range score.Parts {
+		for i := range part.Measures {
+			if attributes := part.Measures[i].Attributes(); attributes != nil && len(attributes.Keys) > 0 {
+				key := attributes.Keys[0]
+				return theory.KeyName(key.Fifths, key.Mode)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+	"sight-reading/musicxml"
+	"sight-reading/transpose"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const instrumentColumns = `
+    i.id, i.slug, i.name, i.family, i.clef, i.transpose,
+    i.range_low, i.range_high, i.program
+`
+
+func GetInstruments(c *gin.Context) {
+	query := `
+  SELECT` + instrumentColumns + `
+  FROM instruments i
+  ORDER BY i.family, i.name
+  `
+
+	instruments := []dtos.Instrument{}
+
+	done := metrics.TimeQuery("GetInstruments")
+	err := database.DBClient.Select(&instruments, query)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "instruments"))
+		return
+	}
+	c.JSON(http.StatusOK, instruments)
+}
+
+func GetUserInstruments(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	query := `
+  SELECT` + instrumentColumns + `, ui.is_primary
+  FROM user_instruments ui
+  JOIN instruments i ON i.id = ui.instrument_id
+  WHERE ui.user_id = $1
+  ORDER BY ui.is_primary DESC, i.name
+  `
+
+	instruments := []dtos.UserInstrument{}
+
+	done := metrics.TimeQuery("GetUserInstruments")
+	err := database.DBClient.Select(&instruments, query, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "instruments"))
+		return
+	}
+	c.JSON(http.StatusOK, instruments)
+}
+
+// SetUserInstruments replaces what a user plays. With a single instrument it
+// is the primary one even when the request does not say so, with several one
+// has to be marked
+func SetUserInstruments(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	var reqBody dtos.SetInstrumentsRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if err := reqBody.ValidateSetInstruments(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+	if len(reqBody.Instruments) == 1 {
+		reqBody.Instruments[0].Primary = true
+	}
+
+	slugs := make([]string, len(reqBody.Instruments))
+	primary := ""
+	for i, choice := range reqBody.Instruments {
+		slugs[i] = choice.Slug
+		if choice.Primary {
+			primary = choice.Slug
+		}
+	}
+
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	defer tx.Rollback()
+
+	done := metrics.TimeQuery("SetUserInstruments")
+	defer done()
+
+	if _, err := tx.Exec(`DELETE FROM user_instruments WHERE user_id = $1`, userID); err != nil {
+		_ = c.Error(apperrors.FromDB(err, "instruments"))
+		return
+	}
+
+	query := `
+  INSERT INTO user_instruments (user_id, instrument_id, is_primary)
+  SELECT $1::int, id, slug = $3
+  FROM instruments
+  WHERE slug = ANY($2)
+  `
+	result, err := tx.Exec(query, userID, pq.StringArray(slugs), primary)
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "instruments"))
+		return
+	}
+	if inserted, _ := result.RowsAffected(); int(inserted) != len(slugs) {
+		_ = c.Error(apperrors.Validation("unknown instrument, see /music/instruments for the slugs"))
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// managedUser is the :id user, when the signed in user may manage them:
+// themselves, a student they teach or, for admins, anyone in their school
+func managedUser(c *gin.Context) (int, bool) {
+	claims, _ := auth.FromContext(c)
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperrors.Validation("id must be a number"))
+		return 0, false
+	}
+	if userID == claims.UserID {
+		return userID, true
+	}
+
+	query := `
+  SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE id = $1 AND school_id = $2 AND (
+      $3::text = 'ADMIN'
+      OR id IN (SELECT student_id FROM teacher_to_student WHERE teacher_id = $4)
+    )
+  )
+  `
+	var allowed bool
+	err = database.DBClient.Get(&allowed, query, userID, claims.SchoolID, claims.Role, claims.UserID)
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "user"))
+		return 0, false
+	}
+	if !allowed {
+		_ = c.Error(apperrors.Forbidden("you can only manage yourself and your own students"))
+		return 0, false
+	}
+	return userID, true
+}
+
+func instrumentBySlug(slug string) (dtos.Instrument, error) {
+	var instrument dtos.Instrument
+
+	done := metrics.TimeQuery("GetInstrument")
+	err := database.DBClient.Get(&instrument, `SELECT`+instrumentColumns+`FROM instruments i WHERE i.slug = $1`, slug)
+	done()
+	return instrument, err
+}
+
+// requestedInstrument is the ?instrument= one, or else the primary
+// instrument of the signed in user. nil, true means there is neither
+func requestedInstrument(c *gin.Context) (*dtos.Instrument, bool) {
+	if slug := c.Query("instrument"); slug != "" {
+		instrument, err := instrumentBySlug(slug)
+		if err != nil {
+			_ = c.Error(apperrors.FromDB(err, "instrument"))
+			return nil, false
+		}
+		return &instrument, true
+	}
+
+	claims, ok := auth.FromContext(c)
+	if !ok {
+		return nil, true
+	}
+
+	query := `
+  SELECT` + instrumentColumns + `
+  FROM user_instruments ui
+  JOIN instruments i ON i.id = ui.instrument_id
+  WHERE ui.user_id = $1
+  ORDER BY ui.is_primary DESC
+  LIMIT 1
+  `
+
+	var instruments []dtos.Instrument
+
+	done := metrics.TimeQuery("GetPrimaryInstrument")
+	err := database.DBClient.Select(&instruments, query, claims.UserID)
+	done()
+	if err != nil {
+		// the default is a convenience, concert pitch is still an answer
+		logging.FromContext(c).Warn("could not look up the primary instrument", "error", err)
+		return nil, true
+	}
+	if len(instruments) == 0 {
+		return nil, true
+	}
+	return &instruments[0], true
+}
+
+// forInstrument rewrites a MusicXML body for the requested instrument,
+// returning it as is when there is none or it reads at concert pitch
+func forInstrument(c *gin.Context, body []byte) ([]byte, bool) {
+	instrument, ok := requestedInstrument(c)
+	if !ok {
+		return nil, false
+	}
+	if instrument == nil || (instrument.Transpose == "P1" && instrument.Clef == "") {
+		return body, true
+	}
+
+	score, err := musicxml.ParseBytes(body)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return nil, false
+	}
+	if err := transpose.ToInstrument(score, *instrument); err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return nil, false
+	}
+
+	body, err = musicxml.Marshal(score)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return nil, false
+	}
+	return body, true
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	dtos "sight-reading/DTOs"
+	"sight-reading/abc"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/difficulty"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+	"sight-reading/midi"
+	"sight-reading/music"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+	"sight-reading/transpose"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GenerateMary(c *gin.Context) {
+	var reqBody dtos.MaryRequest
+	if !bindMusicRequest(c, &reqBody) {
+		return
+	}
+
+	xml, err := music.Service.Mary(c.Request.Context(), reqBody)
+	if err != nil {
+		_ = c.Error(musicError(err))
+		return
+	}
+
+	xml, ok := forInstrument(c, xml)
+	if !ok {
+		return
+	}
+
+	recordServed(c, "mary", reqBody, "")
+	// mary only depends on its parameters, the browser can keep it
+	writeExercise(c, "private, max-age=86400", "application/xml", xml)
+}
+
+func GenerateRandom(c *gin.Context) {
+	var reqBody dtos.RandomRequest
+	if !bindMusicRequest(c, &reqBody) {
+		return
+	}
+
+	xml, err := music.Service.Random(c.Request.Context(), reqBody)
+	if err != nil {
+		_ = c.Error(musicError(err))
+		return
+	}
+
+	xml, ok := forInstrument(c, xml)
+	if !ok {
+		return
+	}
+
+	recordServed(c, "random", reqBody, "")
+	writeExercise(c, "private, no-cache", "application/xml", xml)
+}
+
+func GenerateNoteGame(c *gin.Context) {
+	var reqBody dtos.NoteGameRequest
+	if !bindMusicRequest(c, &reqBody) {
+		return
+	}
+
+	if !defaultToInstrument(c, &reqBody) {
+		return
+	}
+
+	noteGame, err := music.Service.NoteGame(c.Request.Context(), reqBody)
+	if err != nil {
+		_ = c.Error(musicError(err))
+		return
+	}
+
+	body, err := json.Marshal(noteGame)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+
+	recordServed(c, "note-game", reqBody, noteGame.NoteName)
+	writeExercise(c, "private, no-cache", "application/json; charset=utf-8", body)
+}
+
+// writeExercise sends body with an ETag of its content, a client that
+// already has it gets a 304 instead
+func writeExercise(c *gin.Context, cacheControl string, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+
+	for _, match := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+		if match == etag || match == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// maxScoreBytes bounds uploaded MusicXML, generated exercises are a few
+// dozen kilobytes
+const maxScoreBytes = 2 << 20
+
+// RateDifficulty scores a MusicXML document posted as the request body
+func RateDifficulty(c *gin.Context) {
+	score, ok := readScore(c)
+	if !ok {
+		return
+	}
+
+	report, err := difficulty.Rate(score)
+	if err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ConvertToMIDI turns a MusicXML document posted as the request body, like a
+// generated exercise, into a midi file
+func ConvertToMIDI(c *gin.Context) {
+	options, ok := midiOptions(c)
+	if !ok {
+		return
+	}
+	score, ok := readScore(c)
+	if !ok {
+		return
+	}
+
+	writeMIDI(c, score, options, "exercise.mid")
+}
+
+// ConvertToABC writes a MusicXML document posted as the request body as an
+// ABC tune, a compact text version of an exercise
+func ConvertToABC(c *gin.Context) {
+	score, ok := readScore(c)
+	if !ok {
+		return
+	}
+	writeABC(c, score, "no-store")
+}
+
+// Transpose rewrites a MusicXML document posted as the request body for an
+// instrument (?instrument=bb-clarinet) or by an interval (?interval=-M2)
+func Transpose(c *gin.Context) {
+	score, ok := readScore(c)
+	if !ok {
+		return
+	}
+	if !transposeScore(c, score) {
+		return
+	}
+
+	body, err := musicxml.Marshal(score)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	writeExercise(c, "private, no-cache", "application/xml", body)
+}
+
+// transposeScore applies the instrument or interval query parameter, exactly
+// one of them is required
+func transposeScore(c *gin.Context, score *musicxml.Score) bool {
+	slug, interval := c.Query("instrument"), c.Query("interval")
+
+	switch {
+	case slug != "" && interval != "":
+		_ = c.Error(apperrors.Validation("pass either instrument or interval, not both"))
+		return false
+	case slug != "":
+		instrument, err := instrumentBySlug(slug)
+		if err != nil {
+			_ = c.Error(apperrors.FromDB(err, "instrument"))
+			return false
+		}
+		if err := transpose.ToInstrument(score, instrument); err != nil {
+			_ = c.Error(apperrors.Internal(err))
+			return false
+		}
+	case interval != "":
+		parsed, err := theory.ParseInterval(interval)
+		if err != nil {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return false
+		}
+		if err := transpose.ByInterval(score, parsed); err != nil {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return false
+		}
+	default:
+		_ = c.Error(apperrors.Validation("instrument or interval is required"))
+		return false
+	}
+	return true
+}
+
+// midiOptions reads the optional tempo and program query parameters
+func midiOptions(c *gin.Context) (midi.Options, bool) {
+	var options midi.Options
+
+	if value := c.Query("tempo"); value != "" {
+		tempo, err := strconv.ParseFloat(value, 64)
+		if err != nil || tempo < midi.MinTempo || tempo > midi.MaxTempo {
+			_ = c.Error(apperrors.Validation("tempo must be a number from " + strconv.Itoa(midi.MinTempo) + " to " + strconv.Itoa(midi.MaxTempo)))
+			return options, false
+		}
+		options.Tempo = tempo
+	}
+	if value := c.Query("program"); value != "" {
+		program, err := strconv.Atoi(value)
+		if err != nil || program < 0 || program > 127 {
+			_ = c.Error(apperrors.Validation("program must be a General MIDI program from 0 to 127"))
+			return options, false
+		}
+		options.Program = &program
+	}
+	return options, true
+}
+
+func writeMIDI(c *gin.Context, score *musicxml.Score, options midi.Options, filename string) {
+	file, err := midi.Export(score, options)
+	if err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "audio/midi", file)
+}
+
+func readScore(c *gin.Context) (*musicxml.Score, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxScoreBytes))
+	if err != nil {
+		_ = c.Error(apperrors.Validation("the score is too large or could not be read"))
+		return nil, false
+	}
+	return parseScore(c, body)
+}
+
+// parseScore only accepts MusicXML that parses and validates
+func parseScore(c *gin.Context, body []byte) (*musicxml.Score, bool) {
+	score, err := musicxml.ParseBytes(body)
+	if err != nil {
+		_ = c.Error(apperrors.Validation("invalid MusicXML: " + err.Error()))
+		return nil, false
+	}
+	if err := score.Validate(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return nil, false
+	}
+	return score, true
+}
+
+const abcContentType = "text/vnd.abc; charset=utf-8"
+
+func writeABC(c *gin.Context, score *musicxml.Score, cacheControl string) {
+	tune, err := abc.Marshal(score)
+	if err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+	writeExercise(c, cacheControl, abcContentType, tune)
+}
+
+// fromABC converts an ABC tune to MusicXML
+func fromABC(c *gin.Context, tune string) ([]byte, bool) {
+	score, err := abc.Parse(tune)
+	if err != nil {
+		_ = c.Error(apperrors.Validation("invalid ABC: " + err.Error()))
+		return nil, false
+	}
+	body, err := musicxml.Marshal(score)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return nil, false
+	}
+	return body, true
+}
+
+type musicRequest interface {
+	Validate() error
+}
+
+// defaultToInstrument fills the clef and range of a note game request the
+// student left out from their instrument. It is false when the ?instrument=
+// is not one, the error is on c and the handler stops
+func defaultToInstrument(c *gin.Context, req *dtos.NoteGameRequest) bool {
+	if req.Clef != "" || req.RangeLow != "" || req.RangeHigh != "" {
+		return true
+	}
+
+	instrument, ok := requestedInstrument(c)
+	if !ok {
+		return false
+	}
+	if instrument == nil {
+		return true
+	}
+	req.Clef = instrument.Clef
+	req.RangeLow = instrument.RangeLow
+	req.RangeHigh = instrument.RangeHigh
+	return true
+}
+
+func bindMusicRequest(c *gin.Context, reqBody musicRequest) bool {
+	if err := c.ShouldBindJSON(reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return false
+	}
+	if err := reqBody.Validate(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return false
+	}
+	return true
+}
+
+// musicError maps a generator failure to what the client sees, a rejected
+// request is their fault, anything else means the service is unavailable.
+// What the music service answered is logged, never shown
+func musicError(err error) *apperrors.Error {
+	var requestErr *music.RequestError
+	if errors.As(err, &requestErr) {
+		return &apperrors.Error{Kind: apperrors.KindValidation, Message: requestErr.Message, Err: err}
+	}
+	if errors.Is(err, music.ErrCircuitOpen) {
+		return apperrors.Unavailable("the music service is unavailable, try again shortly", err)
+	}
+	return apperrors.Unavailable("the music service did not respond", err)
+}
+
+// recordServed logs which exercise a user got. It is best effort, the
+// exercise has already been generated so a failed insert only gets logged
+func recordServed(c *gin.Context, kind string, params any, noteName string) {
+	metrics.ExercisesServed.WithLabelValues(kind).Inc()
+
+	claims, ok := auth.FromContext(c)
+	if !ok {
+		return
+	}
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+
+	query := `
+  INSERT INTO served_exercises (user_id, kind, params, note_name)
+  VALUES ($1, $2, $3, NULLIF($4, ''))
+  `
+	done := metrics.TimeQuery("RecordServedExercise")
+	_, err = database.DBClient.Exec(query, claims.UserID, kind, encoded, noteName)
+	done()
+	if err != nil {
+		logging.FromContext(c).Warn("could not record served exercise", "kind", kind, "error", err)
+	}
+}
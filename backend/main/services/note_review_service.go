@@ -0,0 +1,318 @@
+package services
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/metrics"
+	"sight-reading/music"
+	"sight-reading/rating"
+	"sight-reading/repetition"
+	"sight-reading/theory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// defaultSessionQuestions is how many questions a note game session has when
+// the game does not say
+const defaultSessionQuestions = 10
+
+const noteReviewColumns = `
+    user_id, pitch, clef, ease, interval_days, repetitions, lapses, reviews,
+    due_at, reviewed_at
+`
+
+// RecordNoteAnswers takes the answers of a finished note game session,
+// records every one and the session as an entry, and reviews each note asked
+// once, graded by its worst
+// answer, so a note asked three times in a session is not pushed out three
+// intervals. The session then moves the student's skill ratings. It responds
+// with the new schedules
+func RecordNoteAnswers(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+
+	var reqBody dtos.NoteAnswersRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if err := reqBody.ValidateNoteAnswers(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+	clef := clefName(reqBody.Clef)
+	// validated with the request
+	parsedClef, _ := theory.ParseClef(clef)
+
+	var fifths *int
+	if reqBody.Scale != "" {
+		tonic, err := theory.ParsePitch(reqBody.Scale)
+		if err != nil {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return
+		}
+		key, err := theory.KeyFifths(tonic)
+		if err != nil {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return
+		}
+		fifths = &key
+	}
+
+	answers := make([]rating.NoteAnswer, len(reqBody.Answers))
+	// the worst quality of each note, in the order the notes were first asked
+	quality := map[string]int{}
+	var notes []string
+	for i, reqAnswer := range reqBody.Answers {
+		pitch, err := theory.ParsePitch(reqAnswer.Pitch)
+		if err != nil {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return
+		}
+		correct := false
+		if reqAnswer.Answer != "" {
+			chosen, err := theory.ParsePitch(reqAnswer.Answer)
+			if err != nil {
+				_ = c.Error(apperrors.Validation(err.Error()))
+				return
+			}
+			correct = chosen.Step == pitch.Step && chosen.Alter == pitch.Alter
+		}
+		answers[i] = rating.NoteAnswer{Pitch: pitch, Clef: parsedClef, Fifths: fifths, Correct: correct}
+
+		q := repetition.Quality(correct, time.Duration(reqAnswer.ResponseMS)*time.Millisecond)
+		if worst, ok := quality[pitch.String()]; !ok || q < worst {
+			if !ok {
+				notes = append(notes, pitch.String())
+			}
+			quality[pitch.String()] = q
+		}
+	}
+
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	defer tx.Rollback()
+
+	done := metrics.TimeQuery("RecordNoteAnswers")
+	defer done()
+
+	insertAnswer := `
+  INSERT INTO note_game_answers (user_id, pitch, clef, answer, correct, response_ms, fifths)
+  VALUES ($1, $2, $3, $4, $5, $6, $7)
+  RETURNING id, answered_at
+  `
+	// every answer of the session has the time the transaction started
+	inserted := make([]struct {
+		ID         int64     `db:"id"`
+		AnsweredAt time.Time `db:"answered_at"`
+	}, len(answers))
+	for i, reqAnswer := range reqBody.Answers {
+		err := tx.Get(&inserted[i], insertAnswer,
+			claims.UserID, answers[i].Pitch.String(), clef, reqAnswer.Answer, answers[i].Correct, reqAnswer.ResponseMS, fifths,
+		)
+		if err != nil {
+			_ = c.Error(apperrors.FromDB(err, "note answers"))
+			return
+		}
+	}
+
+	// the session is also an entry, streaks, goals and recommendations read
+	// those
+	seconds, npm := sessionLength(reqBody.Answers)
+	correct := 0
+	for _, answer := range answers {
+		if answer.Correct {
+			correct++
+		}
+	}
+	insertEntry := `
+  INSERT INTO note_game_entries (user_id, time_length, total_questions, correct_questions, notes_per_minute)
+  VALUES ($1, make_interval(secs => $2)::time, $3, $4, $5)
+  `
+	if _, err := tx.Exec(insertEntry, claims.UserID, seconds, len(answers), correct, npm); err != nil {
+		_ = c.Error(apperrors.FromDB(err, "entry"))
+		return
+	}
+
+	var current []dtos.NoteReview
+	query := `
+  SELECT` + noteReviewColumns + `
+  FROM note_reviews
+  WHERE user_id = $1 AND clef = $2 AND pitch = ANY($3)
+  FOR UPDATE
+  `
+	if err := tx.Select(&current, query, claims.UserID, clef, pq.StringArray(notes)); err != nil {
+		_ = c.Error(apperrors.FromDB(err, "note reviews"))
+		return
+	}
+	cards := map[string]repetition.Card{}
+	for _, review := range current {
+		cards[review.Pitch] = review.Card
+	}
+
+	upsert := `
+  INSERT INTO note_reviews (
+    user_id, pitch, clef, ease, interval_days, repetitions, lapses, reviews,
+    due_at, reviewed_at
+  )
+  VALUES ($1, $2, $3, $4, $5, $6, $7, 1, $8, $9)
+  ON CONFLICT (user_id, pitch, clef) DO UPDATE SET
+    ease = excluded.ease,
+    interval_days = excluded.interval_days,
+    repetitions = excluded.repetitions,
+    lapses = excluded.lapses,
+    reviews = note_reviews.reviews + 1,
+    due_at = excluded.due_at,
+    reviewed_at = excluded.reviewed_at
+  RETURNING` + noteReviewColumns
+
+	now := time.Now()
+	reviews := make([]dtos.NoteReview, len(notes))
+	for i, note := range notes {
+		card, ok := cards[note]
+		if !ok {
+			card = repetition.NewCard(now)
+		}
+		card = card.Review(quality[note], now)
+
+		err := tx.Get(&reviews[i], upsert,
+			claims.UserID, note, clef, card.Ease, card.Interval, card.Repetitions, card.Lapses, card.Due, now,
+		)
+		if err != nil {
+			_ = c.Error(apperrors.FromDB(err, "note reviews"))
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	metrics.EntriesRecorded.Inc()
+
+	rateSession(c, claims.UserID, rating.SourceNoteGame, inserted[0].ID, inserted[0].AnsweredAt, rating.NoteObservations(answers))
+	c.JSON(http.StatusCreated, reviews)
+}
+
+// NextNoteQuestions is the next question set of the note game for the signed
+// in user. The note game options decide which notes can be asked, the
+// student's schedules which of them are: notes due for review first, then a
+// few new ones, lowest first, then the ones due soonest
+func NextNoteQuestions(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+
+	var reqBody dtos.NoteSessionRequest
+	if !bindMusicRequest(c, &reqBody) {
+		return
+	}
+	if !defaultToInstrument(c, &reqBody.NoteGameRequest) {
+		return
+	}
+
+	options, err := music.ReadNoteGameRequest(reqBody.NoteGameRequest)
+	if err != nil {
+		_ = c.Error(musicError(err))
+		return
+	}
+	clef := clefName(reqBody.Clef)
+
+	pitches := map[string]theory.Pitch{}
+	var notes []string
+	add := func(pitch theory.Pitch) {
+		if _, ok := pitches[pitch.String()]; !ok {
+			pitches[pitch.String()] = pitch
+			notes = append(notes, pitch.String())
+		}
+	}
+	for _, pitch := range options.Candidates {
+		add(pitch)
+	}
+	if reqBody.Accidentals {
+		for _, pitch := range options.Candidates {
+			for _, altered := range music.Altered(pitch) {
+				add(altered)
+			}
+		}
+	}
+
+	query := `
+  SELECT` + noteReviewColumns + `
+  FROM note_reviews
+  WHERE user_id = $1 AND clef = $2 AND pitch = ANY($3)
+  `
+
+	var reviews []dtos.NoteReview
+
+	done := metrics.TimeQuery("GetNoteReviews")
+	err = database.DBClient.Select(&reviews, query, claims.UserID, clef, pq.StringArray(notes))
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "note reviews"))
+		return
+	}
+	cards := map[string]repetition.Card{}
+	for _, review := range reviews {
+		cards[review.Pitch] = review.Card
+	}
+
+	count := reqBody.Count
+	if count == 0 {
+		count = defaultSessionQuestions
+	}
+	plan := repetition.Plan(notes, cards, count, (count+1)/2, time.Now(), nil)
+
+	session := dtos.NoteSession{Clef: clef, Questions: make([]dtos.NoteQuestion, len(plan))}
+	for i, choice := range plan {
+		noteGame, err := music.Question(pitches[choice.Note], options.Fifths, options.Clef)
+		if err != nil {
+			_ = c.Error(apperrors.Internal(err))
+			return
+		}
+		session.Questions[i] = dtos.NoteQuestion{
+			NoteGame: noteGame,
+			Pitch:    choice.Note,
+			Reason:   choice.Reason,
+			Due:      choice.Due,
+		}
+	}
+
+	metrics.ExercisesServed.WithLabelValues("note-game").Add(float64(len(plan)))
+	c.Header("Cache-Control", "private, no-cache")
+	c.JSON(http.StatusOK, session)
+}
+
+// maxSessionSeconds keeps a session's length in a time column, a day less a
+// second
+const maxSessionSeconds = 24*60*60 - 1
+
+// sessionLength is how long a note game session took, the response times
+// added up, in seconds, and how many notes a minute were answered
+func sessionLength(answers []dtos.NoteAnswer) (float64, int) {
+	ms := 0
+	for _, answer := range answers {
+		ms += answer.ResponseMS
+	}
+	if ms == 0 {
+		return 0, 0
+	}
+	npm := int(math.Round(float64(len(answers)) * 60_000 / float64(ms)))
+	return min(float64(ms)/1000, maxSessionSeconds), npm
+}
+
+// clefName is how a note game clef is stored, the game defaults to treble
+func clefName(clef string) string {
+	if clef == "" {
+		return "treble"
+	}
+	return strings.ToLower(clef)
+}
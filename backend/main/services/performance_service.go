@@ -0,0 +1,187 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/grading"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+	"sight-reading/midi"
+	"sight-reading/musicxml"
+	"sight-reading/rating"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRecordingBytes bounds uploaded performances, a few minutes of keyboard
+// playing is tens of kilobytes
+const maxRecordingBytes = 1 << 20
+
+const performanceColumns = `
+    p.id, p.user_id, p.exercise_id, p.score, p.pitch_accuracy,
+    p.rhythm_accuracy, p.tempo, p.report, p.created_at
+`
+
+// GradePerformance takes a midi recording of the signed in user playing the
+// exercise as the request body, grades it against the first part, or
+// ?part=, and records the result. The result moves the rhythm rating
+func GradePerformance(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+
+	options := grading.Options{}
+	if value := c.Query("tolerance"); value != "" {
+		tolerance, err := strconv.ParseFloat(value, 64)
+		if err != nil || tolerance <= 0 || tolerance > 2 {
+			_ = c.Error(apperrors.Validation("tolerance must be a number of quarter notes above 0 and at most 2"))
+			return
+		}
+		options.Tolerance = tolerance
+	}
+
+	recording, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRecordingBytes))
+	if err != nil {
+		_ = c.Error(apperrors.Validation("the recording is too large or could not be read"))
+		return
+	}
+	played, err := midi.ReadBytes(recording)
+	if err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	body, ok := readableExercise(c, id, "GetPerformanceExercise")
+	if !ok {
+		return
+	}
+	// it was validated on upload
+	score, err := musicxml.ParseBytes(body)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	part, ok := gradedPart(c, score)
+	if !ok {
+		return
+	}
+
+	result, err := grading.Grade(part, played, options)
+	if err != nil {
+		if errors.Is(err, grading.ErrNothingPlayed) || errors.Is(err, grading.ErrTooLong) || errors.Is(err, grading.ErrNoNotes) || errors.Is(err, grading.ErrTempo) {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return
+		}
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+
+	report, err := json.Marshal(result)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+
+	query := `
+  INSERT INTO performances (
+    user_id, exercise_id, score, pitch_accuracy, rhythm_accuracy, tempo, report
+  )
+  VALUES ($1, $2, $3, $4, $5, $6, $7)
+  RETURNING id, user_id, exercise_id, score, pitch_accuracy,
+    rhythm_accuracy, tempo, report, created_at
+  `
+
+	var performance dtos.Performance
+
+	done := metrics.TimeQuery("CreatePerformance")
+	err = database.DBClient.Get(&performance, query,
+		claims.UserID, id, result.Score, result.PitchAccuracy, result.RhythmAccuracy, result.Tempo, report,
+	)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "performance"))
+		return
+	}
+
+	var level float64
+	done = metrics.TimeQuery("GetExerciseDifficulty")
+	err = database.DBClient.Get(&level, `SELECT difficulty FROM exercises WHERE id = $1`, id)
+	done()
+	if err != nil {
+		logging.FromContext(c).Warn("could not rate the performance", "error", err)
+	} else {
+		rateSession(c, claims.UserID, rating.SourcePerformance, int64(performance.ID), performance.CreatedAt, rating.PerformanceObservations(level, result))
+	}
+	c.JSON(http.StatusCreated, performance)
+}
+
+// GetPerformances lists the graded performances of an exercise, newest first.
+// Students see their own, teachers theirs and their students', admins the
+// whole school's
+func GetPerformances(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	id, ok := exerciseID(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	query := `
+  SELECT` + performanceColumns + `
+  FROM performances p
+  JOIN users u ON u.id = p.user_id
+  WHERE p.exercise_id = $1 AND u.school_id = $2 AND (
+    p.user_id = $3
+    OR $4::text = 'ADMIN'
+    OR ($4::text = 'TEACHER' AND p.user_id IN (
+      SELECT student_id FROM teacher_to_student WHERE teacher_id = $3
+    ))
+  )
+  ORDER BY p.created_at DESC
+  LIMIT $5 OFFSET $6
+  `
+
+	performances := []dtos.Performance{}
+
+	done := metrics.TimeQuery("GetPerformances")
+	err := database.DBClient.Select(&performances, query, id, claims.SchoolID, claims.UserID, claims.Role, limit, offset)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "performances"))
+		return
+	}
+	c.JSON(http.StatusOK, performances)
+}
+
+// gradedPart is the ?part= part by id, or the first one
+func gradedPart(c *gin.Context, score *musicxml.Score) (*musicxml.Part, bool) {
+	if len(score.Parts) == 0 {
+		_ = c.Error(apperrors.Validation(grading.ErrNoNotes.Error()))
+		return nil, false
+	}
+
+	id := c.Query("part")
+	if id == "" {
+		return &score.Parts[0], true
+	}
+	for i := range score.Parts {
+		if score.Parts[i].ID == id {
+			return &score.Parts[i], true
+		}
+	}
+	_ = c.Error(apperrors.Validation("the exercise has no part " + id))
+	return nil, false
+}
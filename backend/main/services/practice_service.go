@@ -0,0 +1,268 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/metrics"
+	"sight-reading/practice"
+
+	"github.com/gin-gonic/gin"
+)
+
+// practiceDay is the date of an entry in the school's timezone, e the entry
+// and s the school. created_date and created_time are the database's clock
+const practiceDay = `
+    ((e.created_date + coalesce(e.created_time, time '00:00'))
+      AT TIME ZONE current_setting('TimeZone') AT TIME ZONE s.timezone)::date
+`
+
+const goalColumns = `user_id, daily_minutes, daily_questions, set_by, updated_at`
+
+// GetStreak is the :id student's current and longest practice streak, in
+// days with a note game session
+func GetStreak(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+	today, timezone, ok := schoolToday(c, userID)
+	if !ok {
+		return
+	}
+
+	query := `
+  SELECT DISTINCT (` + practiceDay + `)::text
+  FROM note_game_entries e
+  JOIN users u ON u.id = e.user_id
+  JOIN schools s ON s.id = u.school_id
+  WHERE e.user_id = $1 AND e.created_date IS NOT NULL
+  `
+
+	var days []string
+
+	done := metrics.TimeQuery("GetPracticeDays")
+	err := database.DBClient.Select(&days, query, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "entries"))
+		return
+	}
+
+	streak, err := practice.Streak(days, today)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	streak.Timezone = timezone
+	c.JSON(http.StatusOK, streak)
+}
+
+func GetGoal(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	var goal dtos.PracticeGoal
+
+	done := metrics.TimeQuery("GetGoal")
+	err := database.DBClient.Get(&goal, `SELECT `+goalColumns+` FROM practice_goals WHERE user_id = $1`, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "goal"))
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+// SetGoal replaces the :id student's daily goal, students set their own and
+// teachers their students'
+func SetGoal(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	var reqBody dtos.GoalRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if err := reqBody.ValidateGoal(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	query := `
+  INSERT INTO practice_goals (user_id, daily_minutes, daily_questions, set_by)
+  VALUES ($1, $2, $3, $4)
+  ON CONFLICT (user_id) DO UPDATE SET
+    daily_minutes = excluded.daily_minutes,
+    daily_questions = excluded.daily_questions,
+    set_by = excluded.set_by,
+    updated_at = now()
+  RETURNING ` + goalColumns
+
+	var goal dtos.PracticeGoal
+
+	done := metrics.TimeQuery("SetGoal")
+	err := database.DBClient.Get(&goal, query, userID, reqBody.DailyMinutes, reqBody.DailyQuestions, claims.UserID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "goal"))
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+func DeleteGoal(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	done := metrics.TimeQuery("DeleteGoal")
+	result, err := database.DBClient.Exec(`DELETE FROM practice_goals WHERE user_id = $1`, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "goal"))
+		return
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		_ = c.Error(apperrors.NotFound("goal not found"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetGoalProgress is the :id student's practice of the last ?days= days,
+// default 1 for today only, against their goal
+func GetGoalProgress(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "1"))
+	if err != nil || days < 1 || days > practice.MaxDays {
+		_ = c.Error(apperrors.Validation("days must be a number from 1 to " + strconv.Itoa(practice.MaxDays)))
+		return
+	}
+	today, timezone, ok := schoolToday(c, userID)
+	if !ok {
+		return
+	}
+
+	var goals []dtos.PracticeGoal
+
+	done := metrics.TimeQuery("GetGoal")
+	err = database.DBClient.Select(&goals, `SELECT `+goalColumns+` FROM practice_goals WHERE user_id = $1`, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "goal"))
+		return
+	}
+
+	// created_date is at most a day off the school's date, the outer query
+	// keeps exactly the days asked for
+	query := `
+  SELECT day::text AS day, sum(seconds) AS seconds, sum(questions) AS questions
+  FROM (
+    SELECT ` + practiceDay + ` AS day,
+      extract(epoch FROM e.time_length) AS seconds,
+      e.total_questions AS questions
+    FROM note_game_entries e
+    JOIN users u ON u.id = e.user_id
+    JOIN schools s ON s.id = u.school_id
+    WHERE e.user_id = $1 AND e.created_date >= $2::date - $3::int - 1
+  ) d
+  WHERE day > $2::date - $3::int
+  GROUP BY day
+  `
+
+	var practiced []practice.Day
+
+	done = metrics.TimeQuery("GetPracticeTotals")
+	err = database.DBClient.Select(&practiced, query, userID, today, days)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "entries"))
+		return
+	}
+
+	progress := dtos.GoalProgress{Timezone: timezone}
+	if len(goals) > 0 {
+		progress.Goal = &goals[0]
+	}
+	progress.Days, err = practice.Progress(practiced, progress.Goal, today, days)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// SetSchoolTimezone sets the timezone practice days of the :id school are
+// counted in, admins set their own school's
+func SetSchoolTimezone(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+
+	schoolID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperrors.Validation("id must be a number"))
+		return
+	}
+	if schoolID != int(claims.SchoolID) {
+		_ = c.Error(apperrors.Forbidden("you can only manage your own school"))
+		return
+	}
+
+	var reqBody dtos.TimezoneRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if !practice.ValidTimezone(reqBody.Timezone) {
+		_ = c.Error(apperrors.Validation("timezone must be an IANA timezone like America/Chicago"))
+		return
+	}
+
+	done := metrics.TimeQuery("SetSchoolTimezone")
+	_, err = database.DBClient.Exec(`UPDATE schools SET timezone = $1 WHERE id = $2`, reqBody.Timezone, schoolID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "school"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// schoolToday is today's date in the timezone of userID's school, and the
+// timezone
+func schoolToday(c *gin.Context, userID int) (string, string, bool) {
+	query := `
+  SELECT (now() AT TIME ZONE s.timezone)::date::text AS today, s.timezone
+  FROM users u
+  JOIN schools s ON s.id = u.school_id
+  WHERE u.id = $1
+  `
+
+	var school struct {
+		Today    string `db:"today"`
+		Timezone string `db:"timezone"`
+	}
+
+	done := metrics.TimeQuery("GetSchoolToday")
+	err := database.DBClient.Get(&school, query, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "user"))
+		return "", "", false
+	}
+	return school.Today, school.Timezone, true
+}
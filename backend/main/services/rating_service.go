@@ -0,0 +1,125 @@
+package services
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/database"
+	"sight-reading/logging"
+	"sight-reading/metrics"
+	"sight-reading/rating"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSkillRatings is the :id user's rating on every skill, the ones never
+// rated at the initial rating
+func GetSkillRatings(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	query := `
+  SELECT dimension, rating, sessions, updated_at
+  FROM skill_ratings
+  WHERE user_id = $1
+  `
+
+	var rated []dtos.SkillRating
+
+	done := metrics.TimeQuery("GetSkillRatings")
+	err := database.DBClient.Select(&rated, query, userID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "skill ratings"))
+		return
+	}
+
+	ratings := make([]dtos.SkillRating, len(rating.Dimensions))
+	for i, dimension := range rating.Dimensions {
+		ratings[i] = dtos.SkillRating{Dimension: dimension, Rating: rating.Initial}
+		if j := slices.IndexFunc(rated, func(r dtos.SkillRating) bool { return r.Dimension == dimension }); j >= 0 {
+			ratings[i] = rated[j]
+		}
+	}
+	c.JSON(http.StatusOK, ratings)
+}
+
+// GetSkillRatingHistory is how the :id user's ratings moved, oldest first for
+// charting, of one skill with ?dimension= and from ?since=, a date or time
+func GetSkillRatingHistory(c *gin.Context) {
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	dimension := c.Query("dimension")
+	if dimension != "" && !slices.Contains(rating.Dimensions, dimension) {
+		_ = c.Error(apperrors.Validation("dimension must be one of treble_notes bass_notes key_signatures rhythm"))
+		return
+	}
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.DateOnly, value); err != nil {
+			if since, err = time.Parse(time.RFC3339, value); err != nil {
+				_ = c.Error(apperrors.Validation("since must be a date, 2024-09-02, or an RFC 3339 time"))
+				return
+			}
+		}
+	}
+
+	query := `
+  SELECT dimension, rating, delta, observations, source, source_id, rated_at
+  FROM skill_rating_history
+  WHERE user_id = $1
+    AND ($2::text = '' OR dimension = $2)
+    AND rated_at >= $3
+  ORDER BY rated_at, id
+  LIMIT $4 OFFSET $5
+  `
+
+	history := []dtos.SkillRatingPoint{}
+
+	done := metrics.TimeQuery("GetSkillRatingHistory")
+	err := database.DBClient.Select(&history, query, userID, dimension, since, limit, offset)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "skill rating history"))
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// rateSession moves the skill ratings of userID with a finished session.
+// The session is already recorded and ratings can be recalculated from it,
+// so a failure is logged instead of failing the request
+func rateSession(c *gin.Context, userID int, source string, sourceID int64, at time.Time, observations []rating.Observation) {
+	logger := logging.FromContext(c)
+
+	tx, err := database.DBClient.Beginx()
+	if err != nil {
+		logger.Warn("could not rate the session", "source", source, "error", err)
+		return
+	}
+	defer tx.Rollback()
+
+	done := metrics.TimeQuery("RateSession")
+	defer done()
+
+	if _, err := rating.Apply(tx, userID, source, sourceID, at, observations); err != nil {
+		logger.Warn("could not rate the session", "source", source, "error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Warn("could not rate the session", "source", source, "error", err)
+	}
+}
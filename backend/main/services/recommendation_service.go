@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/database"
+	"sight-reading/metrics"
+	"sight-reading/recommend"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRecommendation recommends the :id student's next note game settings
+// from their latest sessions long enough to count. The current settings are
+// the query, the note count defaults to the latest session's. The rules are
+// the class's: a teacher asking gets their own, anyone else those of the
+// student's first teacher with a rule set, or the defaults
+func GetRecommendation(c *gin.Context) {
+	claims, _ := auth.FromContext(c)
+	userID, ok := managedUser(c)
+	if !ok {
+		return
+	}
+
+	rules, rulesTeacherID, ok := studentRules(c, userID, claims.UserID)
+	if !ok {
+		return
+	}
+
+	// the sessions too short to count are left out before the limit, so a few
+	// of them do not hide the older ones that do
+	query := `
+  SELECT
+    total_questions, correct_questions, notes_per_minute,
+    extract(epoch FROM time_length)::int AS seconds
+  FROM note_game_entries
+  WHERE user_id = $1 AND total_questions > 0
+    AND extract(epoch FROM time_length) >= $2
+  ORDER BY created_date DESC NULLS LAST, created_time DESC NULLS LAST, id DESC
+  LIMIT $3
+  `
+
+	var sessions []recommend.Session
+
+	done := metrics.TimeQuery("GetRecentSessions")
+	err := database.DBClient.Select(&sessions, query, userID, rules.MinSeconds, rules.Sessions)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "entries"))
+		return
+	}
+
+	current := recommend.DefaultSettings
+	if len(sessions) > 0 {
+		current.NoteCount = sessions[0].Total
+	}
+	if !practiceSettings(c, &current) {
+		return
+	}
+
+	recommendation, err := recommend.Recommend(sessions, current, rules)
+	if err != nil {
+		if errors.Is(err, recommend.ErrUnknownScale) {
+			_ = c.Error(apperrors.Validation(err.Error()))
+			return
+		}
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+	recommendation.RulesTeacherID = rulesTeacherID
+	c.JSON(http.StatusOK, recommendation)
+}
+
+// GetRecommendationRules is the rule set of the :id teacher's class, the
+// defaults until they set one
+func GetRecommendationRules(c *gin.Context) {
+	teacherID, ok := classTeacher(c)
+	if !ok {
+		return
+	}
+
+	var stored [][]byte
+
+	done := metrics.TimeQuery("GetRecommendationRules")
+	err := database.DBClient.Select(&stored, `SELECT rules FROM recommendation_rules WHERE teacher_id = $1`, teacherID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "recommendation rules"))
+		return
+	}
+
+	rules := recommend.DefaultRules
+	if len(stored) > 0 {
+		if err := json.Unmarshal(stored[0], &rules); err != nil {
+			_ = c.Error(apperrors.Internal(err))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// SetRecommendationRules replaces the rule set of the :id teacher's class,
+// fields left out take the defaults
+func SetRecommendationRules(c *gin.Context) {
+	teacherID, ok := classTeacher(c)
+	if !ok {
+		return
+	}
+
+	rules := recommend.DefaultRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		_ = c.Error(apperrors.Validation("invalid json body"))
+		return
+	}
+	if err := rules.ValidateRecommendationRules(); err != nil {
+		_ = c.Error(apperrors.Validation(err.Error()))
+		return
+	}
+
+	stored, err := json.Marshal(rules)
+	if err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return
+	}
+
+	query := `
+  INSERT INTO recommendation_rules (teacher_id, rules)
+  VALUES ($1, $2)
+  ON CONFLICT (teacher_id) DO UPDATE SET rules = excluded.rules, updated_at = now()
+  `
+
+	done := metrics.TimeQuery("SetRecommendationRules")
+	_, err = database.DBClient.Exec(query, teacherID, stored)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "recommendation rules"))
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// studentRules is the rule set for userID when asked by callerID and the
+// teacher it belongs to, nil for the defaults
+func studentRules(c *gin.Context, userID, callerID int) (dtos.RecommendationRules, *int, bool) {
+	query := `
+  SELECT r.teacher_id, r.rules
+  FROM recommendation_rules r
+  JOIN teacher_to_student ts ON ts.teacher_id = r.teacher_id
+  WHERE ts.student_id = $1
+  ORDER BY r.teacher_id = $2 DESC, r.teacher_id
+  LIMIT 1
+  `
+
+	var found []struct {
+		TeacherID int    `db:"teacher_id"`
+		Rules     []byte `db:"rules"`
+	}
+
+	done := metrics.TimeQuery("GetStudentRules")
+	err := database.DBClient.Select(&found, query, userID, callerID)
+	done()
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "recommendation rules"))
+		return dtos.RecommendationRules{}, nil, false
+	}
+
+	rules := recommend.DefaultRules
+	if len(found) == 0 {
+		return rules, nil, true
+	}
+	if err := json.Unmarshal(found[0].Rules, &rules); err != nil {
+		_ = c.Error(apperrors.Internal(err))
+		return dtos.RecommendationRules{}, nil, false
+	}
+	return rules, &found[0].TeacherID, true
+}
+
+// classTeacher is the :id teacher, when the signed in user may manage their
+// class: the teacher themselves or an admin of their school
+func classTeacher(c *gin.Context) (int, bool) {
+	claims, _ := auth.FromContext(c)
+
+	teacherID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperrors.Validation("id must be a number"))
+		return 0, false
+	}
+
+	query := `
+  SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE id = $1 AND school_id = $2 AND role = 'TEACHER'
+      AND (id = $3 OR $4::text = 'ADMIN')
+  )
+  `
+	var allowed bool
+	err = database.DBClient.Get(&allowed, query, teacherID, claims.SchoolID, claims.UserID, claims.Role)
+	if err != nil {
+		_ = c.Error(apperrors.FromDB(err, "teacher"))
+		return 0, false
+	}
+	if !allowed {
+		_ = c.Error(apperrors.Forbidden("you can only manage your own class, or the classes of your school as an admin"))
+		return 0, false
+	}
+	return teacherID, true
+}
+
+// practiceSettings reads the current settings from the query over the
+// defaults in settings
+func practiceSettings(c *gin.Context, settings *dtos.PracticeSettings) bool {
+	if scale := c.Query("scale"); scale != "" {
+		settings.Scale = scale
+	}
+
+	fields := []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"octave", &settings.Octave, 0, 9},
+		{"octaves", &settings.Octaves, 1, 4},
+		{"note_count", &settings.NoteCount, 1, 200},
+		{"tempo", &settings.Tempo, 20, 300},
+	}
+	for _, field := range fields {
+		value := c.Query(field.name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number < field.min || number > field.max {
+			_ = c.Error(apperrors.Validation(field.name + " must be a number from " + strconv.Itoa(field.min) + " to " + strconv.Itoa(field.max)))
+			return false
+		}
+		*field.value = number
+	}
+	return true
+}
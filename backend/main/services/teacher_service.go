@@ -3,7 +3,9 @@ package services
 import (
 	"net/http"
 	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
 	"sight-reading/database"
+	"sight-reading/metrics"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -14,19 +16,13 @@ func CreateUser(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&reqBody)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   true,
-			"message": "Invalid json body",
-		})
+		_ = c.Error(apperrors.Validation("invalid json body"))
 		return
 	}
 
 	err = reqBody.ValidateUser()
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   reqBody.ValidateUser().Error(),
-			"message": "Information invalid",
-		})
+		_ = c.Error(apperrors.Validation(err.Error()))
 		return
 	}
 
@@ -54,14 +50,15 @@ func CreateUser(c *gin.Context) {
 	// front end
 	//
 	// rows contains all the 'returning values'
+	done := metrics.TimeQuery("CreateUser")
 	rows, err := database.DBClient.NamedQuery(query, reqBody)
+	done()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "The school is most likely not found",
-		})
+		// a missing school comes back as a foreign key violation
+		_ = c.Error(apperrors.FromDB(err, "user"))
 		return
 	}
+	defer rows.Close()
 
 	var teacherValidation dtos.User
 
@@ -71,19 +68,16 @@ func CreateUser(c *gin.Context) {
 	if rows.Next() {
 		err := rows.StructScan(&teacherValidation)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-				"help":  "this is at the database level",
-			})
+			_ = c.Error(apperrors.Internal(err))
 			return
 		}
 	}
 
-	rows.Close()
+	metrics.UsersCreated.WithLabelValues(string(reqBody.Role)).Inc()
 
-	c.JSON(http.StatusCreated, gin.H{
-		"body":   teacherValidation,
-		"status": "teacher created sucessfully",
+	c.JSON(http.StatusCreated, dtos.CreatedUser{
+		Body:   teacherValidation,
+		Status: "teacher created sucessfully",
 	})
 }
 
@@ -100,12 +94,11 @@ func GetStudents(c *gin.Context) {
 
 	var students []dtos.User
 
+	done := metrics.TimeQuery("GetStudents")
 	err := database.DBClient.Select(&students, query)
+	done()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   err.Error(),
-			"message": "not updated",
-		})
+		_ = c.Error(apperrors.FromDB(err, "students"))
 		return
 	}
 	c.JSON(http.StatusOK, students)
@@ -115,10 +108,7 @@ func GetStudent(c *gin.Context) {
 	idSrt := c.Param("id")
 	id, err := strconv.Atoi(idSrt)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   true,
-			"message": "Invalid request body",
-		})
+		_ = c.Error(apperrors.Validation("id must be a number"))
 		return
 	}
 
@@ -132,12 +122,11 @@ func GetStudent(c *gin.Context) {
 
 	var students dtos.User
 
+	done := metrics.TimeQuery("GetStudent")
 	err = database.DBClient.Get(&students, query, id)
+	done()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   err.Error(),
-			"message": "not found",
-		})
+		_ = c.Error(apperrors.FromDB(err, "student"))
 		return
 	}
 
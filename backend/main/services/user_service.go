@@ -3,7 +3,9 @@ package services
 import (
 	"net/http"
 	dtos "sight-reading/DTOs"
+	"sight-reading/apperrors"
 	"sight-reading/database"
+	"sight-reading/metrics"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,19 +15,13 @@ func CreateNoteGameEntry(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&reqBody)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   err.Error(),
-			"message": "Invalid json",
-		})
+		_ = c.Error(apperrors.Validation("invalid json body"))
 		return
 	}
 
 	err = reqBody.ValidateEntry()
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   err.Error(),
-			"message": "Invalid request body for entry",
-		})
+		_ = c.Error(apperrors.Validation(err.Error()))
 		return
 	}
 
@@ -47,11 +43,11 @@ func CreateNoteGameEntry(c *gin.Context) {
   RETURNING id, user_id
   `
 
+	done := metrics.TimeQuery("CreateNoteGameEntry")
 	rows, err := database.DBClient.NamedQuery(query, reqBody)
+	done()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		_ = c.Error(apperrors.FromDB(err, "entry"))
 		return
 	}
 
@@ -61,6 +57,8 @@ func CreateNoteGameEntry(c *gin.Context) {
 	}
 	rows.Close()
 
+	metrics.EntriesRecorded.Inc()
+
 	c.JSON(http.StatusCreated, gin.H{
 		"body":    reqBody,
 		"post_id": entryID,
@@ -77,12 +75,11 @@ func GetEntriesByUserId(c *gin.Context) {
   where entries.user_id = $1
   `
 	var entries []dtos.Entry
+	done := metrics.TimeQuery("GetEntriesByUserId")
 	err := database.DBClient.Select(entries, query, 1)
+	done()
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   err.Error(),
-			"message": "Invalid request body",
-		})
+		_ = c.Error(apperrors.FromDB(err, "entries"))
 		return
 	}
 
@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"sight-reading/abc"
+	"sight-reading/musicxml"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const plough = `X:1
+T:Speed the Plough
+C:Trad.
+M:4/4
+L:1/8
+Q:1/4=120
+K:G
+d|:GABc dedB|^c2c2 =c2d>e|(3fga g2 [GBd]4-|[GBd]2 z2 z4:|
+`
+
+type timedPitch struct {
+	pitch    string
+	onset    float64
+	duration float64
+}
+
+func timedPitches(part *musicxml.Part) []timedPitch {
+	var pitches []timedPitch
+	for _, timed := range part.Timeline() {
+		if timed.Note.Pitch != nil {
+			pitches = append(pitches, timedPitch{timed.Note.Pitch.String(), timed.Onset, timed.Duration})
+		}
+	}
+	return pitches
+}
+
+// NOTE: Happy path
+func TestParseABC(t *testing.T) {
+	score, err := abc.Parse(plough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := score.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if score.Work.Title != "Speed the Plough" || score.Identification.Creators[0].Name != "Trad." {
+		t.Errorf("expected the title and composer, got %+v %+v", score.Work, score.Identification)
+	}
+
+	part := &score.Parts[0]
+	if tempo, ok := part.Tempo(); !ok || tempo != 120 {
+		t.Errorf("expected a tempo of 120, got %v", tempo)
+	}
+	first := part.Measures[0]
+	if first.Number != "0" || first.Implicit != "yes" || first.Attributes().Keys[0].Fifths != 1 {
+		t.Errorf("expected a pickup in G, got measure %s %+v", first.Number, first.Attributes())
+	}
+
+	pitches := timedPitches(part)
+	names := []string{}
+	for _, p := range pitches {
+		names = append(names, p.pitch)
+	}
+	want := []string{
+		"D5",
+		"G4", "A4", "B4", "C5", "D5", "E5", "D5", "B4",
+		// the sharp holds to the end of the bar, the natural cancels it
+		"C#5", "C#5", "C5", "D5", "E5",
+		"F#5", "G5", "A5", "G5", "G4", "B4", "D5",
+		"G4", "B4", "D5",
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+
+	// d>e is a dotted eighth and a sixteenth, (3fga three eighths in a quarter
+	if pitches[12].duration != 0.75 || pitches[13].duration != 0.25 {
+		t.Errorf("expected a broken rhythm, got %+v %+v", pitches[12], pitches[13])
+	}
+	if pitches[14].duration != 1.0/3 || math.Abs(pitches[16].onset-(8.5+2.0/3)) > 1e-9 {
+		t.Errorf("expected a triplet, got %+v %+v", pitches[14], pitches[16])
+	}
+
+	// the tied chord continues into the last measure
+	last := part.Measures[len(part.Measures)-1].Notes()[0]
+	if len(last.Ties) != 1 || last.Ties[0].Type != "stop" {
+		t.Errorf("expected the chord tied over the bar, got %+v", last.Ties)
+	}
+}
+
+func TestABCRoundTrip(t *testing.T) {
+	for _, path := range []string{"testdata/mary.xml", ""} {
+		var score *musicxml.Score
+		var err error
+		if path != "" {
+			score, err = musicxml.ParseFile(path)
+		} else {
+			score, err = abc.Parse(plough)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tune, err := abc.Marshal(score)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := abc.Parse(string(tune))
+		if err != nil {
+			t.Fatalf("%s\n%s", err, tune)
+		}
+
+		if before, after := timedPitches(&score.Parts[0]), timedPitches(&again.Parts[0]); !slices.Equal(before, after) {
+			t.Errorf("the tune changed on the way through ABC\n%s\nbefore %v\nafter %v", tune, before, after)
+		}
+	}
+}
+
+func TestABCKeys(t *testing.T) {
+	cases := map[string]musicxml.Key{
+		"Am":    {Fifths: 0, Mode: "minor"},
+		"D dor": {Fifths: 0, Mode: "dorian"},
+		"Bb":    {Fifths: -2},
+		"F#m":   {Fifths: 3, Mode: "minor"},
+		"Emix":  {Fifths: 3, Mode: "mixolydian"},
+		"none":  {Fifths: 0},
+	}
+	for key, want := range cases {
+		score, err := abc.Parse("X:1\nK:" + key + "\nC|\n")
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if got := score.Parts[0].Measures[0].Attributes().Keys[0]; got != want {
+			t.Errorf("%s: expected %+v, got %+v", key, want, got)
+		}
+
+		tune, _ := abc.Marshal(score)
+		if key != "none" && key != "D dor" && !strings.Contains(string(tune), "K:"+key+"\n") {
+			t.Errorf("%s: expected the key written back, got\n%s", key, tune)
+		}
+	}
+
+	score, err := abc.Parse("X:1\nK:C clef=bass\nC,|\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clef := score.Parts[0].Measures[0].Attributes().Clefs[0]; clef.Sign != "F" {
+		t.Errorf("expected the bass clef, got %+v", clef)
+	}
+}
+
+// NOTE: Sad path
+func TestSadABC(t *testing.T) {
+	for name, tune := range map[string]string{
+		"no key":         "X:1\nT:Nothing\nCDEF|\n",
+		"no notes":       "X:1\nK:C\nz4|\n",
+		"bad character":  "X:1\nK:C\nCD#E|\n",
+		"two voices":     "X:1\nK:C\nV:1\nCDEF|\nV:2\nCDEF|\n",
+		"unknown key":    "X:1\nK:H\nCDEF|\n",
+		"unclosed chord": "X:1\nK:C\n[CEG\n",
+	} {
+		if _, err := abc.Parse(tune); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	var parseErr *abc.ParseError
+	if _, err := abc.Parse("X:1\nK:C\nCDEF|\nCD#E|\n"); !errors.As(err, &parseErr) || parseErr.Line != 4 {
+		t.Errorf("expected an error on line 4, got %v", err)
+	}
+}
+
+// NOTE: Sad path
+func TestSadABCLengths(t *testing.T) {
+	for name, tune := range map[string]string{
+		"long note":          "X:1\nK:C\nA1000000000\n",
+		"overflowing length": "X:1\nK:C\nA99999999999999999999\n",
+		"short note":         "X:1\nK:C\nA/128\n",
+		"many halvings":      "X:1\nK:C\nA////////\n",
+		"long rest":          "X:1\nK:C\nz65 A\n",
+		"long chord":         "X:1\nL:1/1\nK:C\n[CEG]64\n",
+		"many measure rests": "X:1\nM:4/4\nK:C\nZ100000000\n",
+		"overflowing rests":  "X:1\nM:4/4\nK:C\nZ99999999999999999999 A\n",
+		"large tuplet":       "X:1\nK:C\n(99999999999999999999ABC\n",
+	} {
+		var parseErr *abc.ParseError
+		if _, err := abc.Parse(tune); !errors.As(err, &parseErr) {
+			t.Errorf("%s: expected a parse error, got %v", name, err)
+		}
+	}
+
+	// as long as they go, still tied together
+	score, err := abc.Parse("X:1\nM:none\nL:1/8\nK:C\nA64 Z100 B\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 32 quarters of A, then 100 whole measures of rest
+	if pitches := timedPitches(&score.Parts[0]); len(pitches) != 5 || pitches[3].duration != 8 || pitches[4].onset != 432 {
+		t.Fatalf("expected 8 whole notes as four tied breves, then a B after the rests, got %+v", pitches)
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/apperrors"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveError(err error) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(apperrors.Middleware())
+	router.GET("/boom", func(c *gin.Context) {
+		_ = c.Error(err)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	return rec
+}
+
+// NOTE: Happy path
+func TestNotFoundMapsToProblem(t *testing.T) {
+	rec := serveError(apperrors.FromDB(sql.ErrNoRows, "teacher"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Fatalf("expected a problem content type, got %q", ct)
+	}
+
+	var problem apperrors.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Status != http.StatusNotFound || problem.Detail != "teacher not found" {
+		t.Fatalf("unexpected problem body: %+v", problem)
+	}
+}
+
+// NOTE: Sad path
+func TestUnknownErrorsDoNotLeak(t *testing.T) {
+	rec := serveError(errors.New(`pq: relation "entries" does not exist`))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "relation") {
+		t.Fatalf("raw sql error leaked to the client: %s", rec.Body.String())
+	}
+}
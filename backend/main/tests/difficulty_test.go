@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"sight-reading/difficulty"
+	"sight-reading/musicxml"
+	"strings"
+	"testing"
+)
+
+// hardScore is two measures of sixteenths leaping around in G flat major with
+// accidentals, at 160 bpm
+func hardScore() string {
+	pitches := []string{
+		"C4", "A5", "E4", "B5", "D-4", "G5", "F#4", "C6",
+		"A-3", "E5", "G4", "D6", "B3", "F5", "C#5", "A3",
+	}
+
+	var notes strings.Builder
+	for _, name := range pitches {
+		step, alter, octave := name[:1], "0", name[len(name)-1:]
+		switch name[1] {
+		case '#':
+			alter = "1"
+		case '-':
+			alter = "-1"
+		}
+		fmt.Fprintf(&notes, `<note><pitch><step>%s</step><alter>%s</alter><octave>%s</octave></pitch><duration>1</duration><type>16th</type></note>`, step, alter, octave)
+	}
+
+	return `<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name/></score-part></part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>4</divisions><key><fifths>-6</fifths></key></attributes>
+      <direction><sound tempo="160"/></direction>` + notes.String() + `
+    </measure>
+    <measure number="2">` + notes.String() + `</measure>
+  </part>
+</score-partwise>`
+}
+
+// NOTE: Happy path
+func TestDifficultyOrdersExercises(t *testing.T) {
+	mary, err := musicxml.ParseFile("testdata/mary.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	easy, err := difficulty.Rate(mary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score, err := musicxml.ParseBytes([]byte(hardScore()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hard, err := difficulty.Rate(score)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if easy.Level >= 3 {
+		t.Errorf("mary had a little lamb should be a beginner piece, got %v", easy.Level)
+	}
+	if hard.Level <= 7 {
+		t.Errorf("the leaping sixteenths should be expert, got %v", hard.Level)
+	}
+	if len(hard.Features) != 8 || hard.Notes != 32 {
+		t.Fatalf("expected 8 features over 32 notes, got %d over %d", len(hard.Features), hard.Notes)
+	}
+
+	for _, feature := range hard.Features {
+		if feature.Name == "key_signature" && feature.Value != 6 {
+			t.Errorf("expected 6 flats, got %v", feature.Value)
+		}
+		// four sixteenths a beat at 160 beats a minute
+		if feature.Name == "tempo" && feature.Value != 10.67 {
+			t.Errorf("expected 10.67 notes per second, got %v", feature.Value)
+		}
+	}
+}
+
+// NOTE: Sad path
+func TestDifficultyNeedsNotes(t *testing.T) {
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name/></score-part></part-list>
+  <part id="P1"><measure number="1"/></part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := difficulty.Rate(score); !errors.Is(err, difficulty.ErrNoNotes) {
+		t.Fatalf("expected ErrNoNotes, got %v", err)
+	}
+}
+
+// NOTE: Happy path
+func TestDifficultyFollowsKeyChanges(t *testing.T) {
+	// F# in G major, then Bb after the change to F major, neither is an
+	// accidental
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name/></score-part></part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions><key><fifths>1</fifths></key></attributes>
+      <note><pitch><step>F</step><alter>1</alter><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note><pitch><step>G</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+    </measure>
+    <measure number="2">
+      <attributes><key><fifths>-1</fifths></key></attributes>
+      <note><pitch><step>B</step><alter>-1</alter><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note><pitch><step>F</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+    </measure>
+  </part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := difficulty.Rate(score)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feature := range report.Features {
+		if feature.Name == "accidentals" && feature.Value != 0 {
+			t.Errorf("expected no accidentals across the key change, got %v", feature.Value)
+		}
+		if feature.Name == "key_signature" && feature.Value != 1 {
+			t.Errorf("expected the first key's sharp, got %v", feature.Value)
+		}
+	}
+}
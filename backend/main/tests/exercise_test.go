@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/controllers"
+	"sight-reading/database"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// NOTE: Happy path
+func TestExerciseMetadataNormalizesTags(t *testing.T) {
+	title := "Scales in F"
+	tags := []string{"Scales, major", " scales ", "Warm Up"}
+	metadata := &dtos.ExerciseMetadata{Title: &title, Tags: &tags}
+
+	if err := metadata.ValidateExerciseMetadata(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"scales", "major", "warm up"}; !slices.Equal(*metadata.Tags, want) {
+		t.Fatalf("expected %v, got %v", want, *metadata.Tags)
+	}
+}
+
+// NOTE: Sad path
+func TestSadExerciseMetadata(t *testing.T) {
+	title := ""
+	composer := strings.Repeat("a", 300)
+	tags := []string{strings.Repeat("long", 10)}
+	metadata := &dtos.ExerciseMetadata{Title: &title, Composer: &composer, Tags: &tags}
+
+	err := metadata.ValidateExerciseMetadata()
+	if err == nil {
+		t.Fatal("expected the metadata to be rejected")
+	}
+	for _, want := range []string{"Title", "Composer", "each tag"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	if err := (&dtos.AssignmentRequest{}).ValidateAssignment(); err == nil {
+		t.Fatal("an assignment needs students")
+	}
+}
+
+var (
+	teacherClaims = auth.Claims{UserID: 7, Role: dtos.Teacher, SchoolID: 3}
+	studentClaims = auth.Claims{UserID: 9, Role: dtos.Student, SchoolID: 3}
+)
+
+// exerciseRouter serves the exercise routes on a mocked database
+func exerciseRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	return mockedRouter(t, controllers.SetupExerciseRoutes)
+}
+
+// mockedRouter serves the routes setup registers on a mocked database, the
+// expectations have to be met by the end of the test
+func mockedRouter(t *testing.T, setup func(*gin.Engine)) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DBClient
+	database.DBClient = sqlx.NewDb(db, "postgres")
+	t.Cleanup(func() {
+		database.DBClient = previous
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	gin.SetMode(gin.TestMode)
+	auth.SetSecret("mocked router test")
+	router := gin.New()
+	router.Use(apperrors.Middleware(), auth.Middleware())
+	setup(router)
+	return router, mock
+}
+
+func signedInRequest(t *testing.T, router *gin.Engine, claims auth.Claims, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := auth.Issue(claims, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// visibleTo are the arguments the visibility clause reads, in its order
+func visibleTo(claims auth.Claims, more ...driver.Value) []driver.Value {
+	return append([]driver.Value{claims.SchoolID, claims.UserID, string(claims.Role)}, more...)
+}
+
+// NOTE: Happy path
+func TestSearchExercisesEscapesWildcards(t *testing.T) {
+	router, mock := exerciseRouter(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("(shared AND $3::text <> 'STUDENT') OR uploaded_by = $2 OR $3::text = 'ADMIN'")).
+		WithArgs(visibleTo(teacherClaims, `%100\%\_ done\\%`, `alto\_sax`)...).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	rec := signedInRequest(t, router, teacherClaims, http.MethodGet, "/exercises?q=100%25_%20done%5C&instrument=alto_sax", "")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("expected no exercises, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+// NOTE: Sad path
+func TestGetExerciseNeedsVisibilityOrAnAssignment(t *testing.T) {
+	router, mock := exerciseRouter(t)
+
+	// shared exercises are for staff, a student only reads what they were
+	// assigned
+	visibleOrAssigned := regexp.QuoteMeta("(shared AND $3::text <> 'STUDENT')") + "(?s).*" +
+		regexp.QuoteMeta("SELECT exercise_id FROM exercise_assignments WHERE student_id = $2")
+	mock.ExpectQuery(visibleOrAssigned).
+		WithArgs(visibleTo(studentClaims, 5)...).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if rec := signedInRequest(t, router, studentClaims, http.MethodGet, "/exercises/5", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an exercise the student cannot see, got %d", rec.Code)
+	}
+}
+
+// NOTE: Sad path
+func TestAssignExerciseChecksTheExerciseAndTheStudents(t *testing.T) {
+	router, mock := exerciseRouter(t)
+	visible := regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM exercises WHERE id = $4 AND")
+	students := regexp.QuoteMeta("SELECT student_id FROM teacher_to_student WHERE teacher_id = $4")
+	body := `{"student_ids": [11, 12, 11]}`
+
+	mock.ExpectQuery(visible).WithArgs(visibleTo(teacherClaims, 5)...).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	if rec := signedInRequest(t, router, teacherClaims, http.MethodPost, "/exercises/5/assignments", body); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an exercise the teacher cannot see, got %d", rec.Code)
+	}
+
+	// 12 is someone else's student
+	mock.ExpectQuery(visible).WithArgs(visibleTo(teacherClaims, 5)...).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(students).WithArgs("{11,12,11}", teacherClaims.SchoolID, string(teacherClaims.Role), teacherClaims.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	if rec := signedInRequest(t, router, teacherClaims, http.MethodPost, "/exercises/5/assignments", body); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for another teacher's student, got %d", rec.Code)
+	}
+
+	mock.ExpectQuery(visible).WithArgs(visibleTo(teacherClaims, 5)...).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(students).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO exercise_assignments")).WithArgs(5, "{11,12,11}", teacherClaims.UserID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	rec := signedInRequest(t, router, teacherClaims, http.MethodPost, "/exercises/5/assignments", body)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"assigned":2`) {
+		t.Fatalf("expected two students assigned, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+// NOTE: Sad path
+func TestUploadClipsLongScoreMetadata(t *testing.T) {
+	router, mock := exerciseRouter(t)
+	title := strings.Repeat("Étude ", 60)
+	clipped := string([]rune(title)[:255])
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO exercises")).
+		WithArgs(teacherClaims.SchoolID, teacherClaims.UserID, clipped, "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(5, clipped))
+
+	token, err := auth.Issue(teacherClaims, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"abc": {"X:1\nT:" + title + "\nK:C\nCDEF|\n"}}
+	req := httptest.NewRequest(http.MethodPost, "/exercises", strings.NewReader(form.Encode()))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected the upload stored with its title clipped, got %d %s", rec.Code, rec.Body)
+	}
+}
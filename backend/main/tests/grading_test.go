@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"errors"
+	"sight-reading/grading"
+	"sight-reading/midi"
+	"sight-reading/musicxml"
+	"slices"
+	"testing"
+)
+
+// maryPerformance is mary played perfectly at tempo, read back from the
+// exported file the way an uploaded recording is
+func maryPerformance(t *testing.T, tempo float64) (*musicxml.Score, []midi.Note) {
+	t.Helper()
+	score, err := musicxml.ParseFile("testdata/mary.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := midi.Export(score, midi.Options{Tempo: tempo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	played, err := midi.ReadBytes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return score, played
+}
+
+// NOTE: Happy path
+func TestReadMIDIBack(t *testing.T) {
+	_, played := maryPerformance(t, 120)
+
+	var pitches []int
+	for _, note := range played {
+		pitches = append(pitches, note.Pitch)
+	}
+	// E D C D E E E and the C E G chord
+	if want := []int{64, 62, 60, 62, 64, 64, 64, 60, 64, 67}; !slices.Equal(pitches, want) {
+		t.Fatalf("expected %v, got %v", want, pitches)
+	}
+	// eighth notes at 120 last a quarter of a second
+	if played[1].Start != 0.25 || played[0].End != 0.25 {
+		t.Errorf("expected the second note at 0.25s, got %+v", played[:2])
+	}
+}
+
+func TestGradePerfectPerformanceAtAnyTempo(t *testing.T) {
+	for _, tempo := range []float64{60, 100, 144} {
+		score, played := maryPerformance(t, tempo)
+
+		result, err := grading.Grade(&score.Parts[0], played, grading.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Score != 1 || result.Correct != 10 || len(result.Missed)+len(result.Extra)+len(result.Wrong) != 0 {
+			t.Errorf("%v: expected a perfect grade, got %+v", tempo, result)
+		}
+		if result.Tempo != tempo {
+			t.Errorf("expected the tempo %v to be found, got %v", tempo, result.Tempo)
+		}
+	}
+}
+
+func TestGradeFindsMissedExtraAndWrongNotes(t *testing.T) {
+	score, played := maryPerformance(t, 120)
+
+	// the C is skipped, the D after it is almost an eighth late, the second
+	// to last E is an F and an extra A is played after the last E
+	played = slices.Delete(played, 2, 3)
+	played[2].Start += 0.2
+	played[4].Pitch = 65
+	played = slices.Insert(played, 6, midi.Note{Pitch: 69, Start: 1.6, End: 1.7})
+
+	result, err := grading.Grade(&score.Parts[0], played, grading.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Missed) != 1 || result.Missed[0].Pitch != "C4" || result.Missed[0].Beat != 2 {
+		t.Errorf("expected the C on beat 2 missed, got %+v", result.Missed)
+	}
+	if len(result.Extra) != 1 || result.Extra[0].Pitch != "A4" {
+		t.Errorf("expected the A extra, got %+v", result.Extra)
+	}
+	if len(result.Wrong) != 1 || result.Wrong[0].Expected.Pitch != "E4" || result.Wrong[0].Played.Pitch != "F4" {
+		t.Errorf("expected an F4 for an E4, got %+v", result.Wrong)
+	}
+	// 8 right of 10 expected and 1 extra, 8 of 10 in time
+	if result.PitchAccuracy != 0.727 || result.RhythmAccuracy != 0.8 {
+		t.Errorf("expected 0.727 pitch and 0.8 rhythm, got %+v", result)
+	}
+}
+
+// NOTE: Sad path
+func TestSadGrading(t *testing.T) {
+	if _, err := midi.ReadBytes([]byte("MThd but not really")); !errors.Is(err, midi.ErrNotMIDI) {
+		t.Errorf("expected not a midi file, got %v", err)
+	}
+
+	score, _ := maryPerformance(t, 120)
+	if _, err := grading.Grade(&score.Parts[0], nil, grading.Options{}); !errors.Is(err, grading.ErrNothingPlayed) {
+		t.Errorf("expected nothing played, got %v", err)
+	}
+
+	// the whole exercise in 60 ms, then once at 2 quarters a minute
+	_, played := maryPerformance(t, 120)
+	for i := range played {
+		played[i].Start, played[i].End = 0, 0.01
+		if i >= 7 {
+			played[i].Start, played[i].End = 0.06, 0.07
+		}
+	}
+	if _, err := grading.Grade(&score.Parts[0], played, grading.Options{}); !errors.Is(err, grading.ErrTempo) {
+		t.Errorf("expected a cluster to be too fast, got %v", err)
+	}
+	_, played = maryPerformance(t, 2)
+	if _, err := grading.Grade(&score.Parts[0], played, grading.Options{}); !errors.Is(err, grading.ErrTempo) {
+		t.Errorf("expected 2 bpm to be too slow, got %v", err)
+	}
+}
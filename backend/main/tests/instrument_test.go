@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"regexp"
+	"sight-reading/auth"
+	"sight-reading/controllers"
+	"sight-reading/music"
+	"sight-reading/musicxml"
+	"strings"
+	"testing"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// NOTE: Happy path
+func TestValidateSetInstruments(t *testing.T) {
+	req := dtos.SetInstrumentsRequest{Instruments: []dtos.InstrumentChoice{
+		{Slug: "bb-clarinet", Primary: true},
+		{Slug: "piano"},
+	}}
+	if err := req.ValidateSetInstruments(); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing at all clears the instruments of a user
+	empty := dtos.SetInstrumentsRequest{}
+	if err := empty.ValidateSetInstruments(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// NOTE: Sad path
+func TestSadValidateSetInstruments(t *testing.T) {
+	cases := map[string][]dtos.InstrumentChoice{
+		"listed twice": {{Slug: "flute"}, {Slug: "flute"}},
+		"only one":     {{Slug: "flute", Primary: true}, {Slug: "oboe", Primary: true}},
+		"mark one":     {{Slug: "flute"}, {Slug: "oboe"}},
+		"needs a slug": {{Slug: ""}},
+		"at most 10":   make([]dtos.InstrumentChoice, 11),
+	}
+	for i := range cases["at most 10"] {
+		cases["at most 10"][i].Slug = strings.Repeat("x", i+1)
+	}
+
+	for message, instruments := range cases {
+		req := dtos.SetInstrumentsRequest{Instruments: instruments}
+		err := req.ValidateSetInstruments()
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %q, got %v", message, err)
+		}
+	}
+}
+
+var adminClaims = auth.Claims{UserID: 1, Role: dtos.Admin, SchoolID: 4}
+
+// NOTE: Happy path
+func TestManagedUserAllowsSelfAndOwnStudents(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupInstrumentRoutes)
+	instruments := regexp.QuoteMeta("FROM user_instruments ui")
+
+	// yourself without asking the database
+	mock.ExpectQuery(instruments).WithArgs(studentClaims.UserID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if rec := signedInRequest(t, router, studentClaims, http.MethodGet, "/users/9/instruments", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected a student to read their own instruments, got %d %s", rec.Code, rec.Body)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT student_id FROM teacher_to_student WHERE teacher_id = $4")).
+		WithArgs(9, teacherClaims.SchoolID, string(teacherClaims.Role), teacherClaims.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(instruments).WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if rec := signedInRequest(t, router, teacherClaims, http.MethodGet, "/users/9/instruments", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected a teacher to read their student's instruments, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+// NOTE: Sad path
+func TestManagedUserForbidsOthers(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupInstrumentRoutes)
+	allowed := regexp.QuoteMeta("WHERE id = $1 AND school_id = $2")
+
+	for _, claims := range []auth.Claims{teacherClaims, adminClaims} {
+		// another teacher's student, or a student of another school
+		mock.ExpectQuery(allowed).
+			WithArgs(12, claims.SchoolID, string(claims.Role), claims.UserID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		if rec := signedInRequest(t, router, claims, http.MethodPut, "/users/12/instruments", `{"instruments": []}`); rec.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", claims.Role, rec.Code)
+		}
+	}
+}
+
+// scoreGenerator serves mary.xml and keeps the note game request it got
+type scoreGenerator struct {
+	noteGame *dtos.NoteGameRequest
+}
+
+func (g *scoreGenerator) Mary(_ context.Context, _ dtos.MaryRequest) ([]byte, error) {
+	return os.ReadFile("testdata/mary.xml")
+}
+
+func (g *scoreGenerator) Random(ctx context.Context, _ dtos.RandomRequest) ([]byte, error) {
+	return g.Mary(ctx, dtos.MaryRequest{})
+}
+
+func (g *scoreGenerator) NoteGame(_ context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error) {
+	g.noteGame = &req
+	return dtos.NoteGame{NoteName: "C", NoteOctave: "3"}, nil
+}
+
+func withGenerator(t *testing.T) *scoreGenerator {
+	t.Helper()
+	generator := &scoreGenerator{}
+	previous := music.Service
+	music.Service = generator
+	t.Cleanup(func() { music.Service = previous })
+	return generator
+}
+
+func instrumentRows(slug, clef, transpose, low, high string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "slug", "name", "family", "clef", "transpose", "range_low", "range_high", "program"}).
+		AddRow(1, slug, slug, "strings", clef, transpose, low, high, 0)
+}
+
+// NOTE: Happy path
+func TestMusicDefaultsToTheInstrument(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupMusicRoutes)
+	generator := withGenerator(t)
+	bySlug := regexp.QuoteMeta("WHERE i.slug = $1")
+	served := regexp.QuoteMeta("INSERT INTO served_exercises")
+
+	mock.ExpectQuery(bySlug).WithArgs("cello").WillReturnRows(instrumentRows("cello", "bass", "P1", "C2", "C6"))
+	mock.ExpectExec(served).WillReturnResult(sqlmock.NewResult(1, 1))
+	rec := signedInRequest(t, router, studentClaims, http.MethodPost, "/music/note-game?instrument=cello", `{"scale": "C", "octave": "3"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a note game, got %d %s", rec.Code, rec.Body)
+	}
+	if req := generator.noteGame; req == nil || req.Clef != "bass" || req.RangeLow != "C2" || req.RangeHigh != "C6" {
+		t.Fatalf("expected the cello's clef and range, got %+v", req)
+	}
+
+	// the primary instrument when the request names none
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY ui.is_primary DESC")).WithArgs(studentClaims.UserID).
+		WillReturnRows(instrumentRows("bb-clarinet", "treble", "-M2", "E3", "C7"))
+	mock.ExpectExec(served).WillReturnResult(sqlmock.NewResult(1, 1))
+	rec = signedInRequest(t, router, studentClaims, http.MethodPost, "/music/mary", `{"tonic": "C", "octave": "4"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected mary, got %d %s", rec.Code, rec.Body)
+	}
+	score, err := musicxml.ParseBytes(rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributes := score.Parts[0].Measures[0].Attributes(); len(attributes.Transpose) != 1 {
+		t.Fatalf("expected mary written for the clarinet, got %+v", attributes)
+	}
+}
+
+// NOTE: Sad path
+func TestMusicStopsOnAnUnknownInstrument(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupMusicRoutes)
+	generator := withGenerator(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE i.slug = $1")).WithArgs("kazoo").WillReturnError(sql.ErrNoRows)
+	rec := signedInRequest(t, router, studentClaims, http.MethodPost, "/music/note-game?instrument=kazoo", `{"scale": "C", "octave": "4"}`)
+	if rec.Code != http.StatusNotFound || generator.noteGame != nil {
+		t.Fatalf("expected 404 before generating, got %d with %+v", rec.Code, generator.noteGame)
+	}
+}
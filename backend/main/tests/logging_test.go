@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/logging"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logging.RequestID())
+	router.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, logging.RequestIDFromContext(c))
+	})
+	return router
+}
+
+// NOTE: Happy path
+func TestRequestIDIsPropagated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(logging.RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+
+	newRequestIDRouter().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(logging.RequestIDHeader); got != "abc-123" {
+		t.Fatalf("expected the request id to be echoed back, got %q", got)
+	}
+	if rec.Body.String() != "abc-123" {
+		t.Fatalf("expected the handler to see the request id, got %q", rec.Body.String())
+	}
+}
+
+// NOTE: Sad path
+func TestRequestIDIsGeneratedWhenMissing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	rec := httptest.NewRecorder()
+
+	newRequestIDRouter().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(logging.RequestIDHeader); len(got) != 32 {
+		t.Fatalf("expected a generated request id, got %q", got)
+	}
+}
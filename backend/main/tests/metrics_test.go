@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/controllers"
+	"sight-reading/metrics"
+	"strconv"
+	"strings"
+	"testing"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrape reads the value of a series from the exposition format, 0 when it
+// has not been recorded yet
+func scrape(t *testing.T, series string) float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router := gin.New()
+	router.GET("/metrics", metrics.Handler())
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), series+" "); ok {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return number
+		}
+	}
+	return 0
+}
+
+// NOTE: Happy path
+func TestMetricsMiddlewareCountsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.GET("/metrics-test/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	route := `tremolo_http_requests_total{method="GET",route="/metrics-test/:id",status="204"}`
+	unmatched := `tremolo_http_requests_total{method="GET",route="unmatched",status="404"}`
+	before, beforeUnmatched := scrape(t, route), scrape(t, unmatched)
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// the route template is the label, not the path
+	if got := scrape(t, route) - before; got != 2 {
+		t.Fatalf("expected 2 requests on the route, got %v", got)
+	}
+	if got := scrape(t, unmatched) - beforeUnmatched; got != 1 {
+		t.Fatalf("expected 1 unmatched request, got %v", got)
+	}
+	if scrape(t, `tremolo_http_request_duration_seconds_count{method="GET",route="/metrics-test/:id"}`) < 2 {
+		t.Fatal("expected the latency of the route to be observed")
+	}
+}
+
+// NOTE: Sad path
+func TestMetricsCountAuthFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.SetSecret("metrics test")
+	router := gin.New()
+	router.Use(apperrors.Middleware(), auth.Middleware())
+	router.GET("/private", auth.Require(dtos.Student), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	invalid := `tremolo_auth_failures_total{reason="invalid"}`
+	missing := `tremolo_auth_failures_total{reason="missing"}`
+	beforeInvalid, beforeMissing := scrape(t, invalid), scrape(t, missing)
+
+	for _, header := range []string{"Bearer forged.token", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/private", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", rec.Code)
+		}
+	}
+
+	if scrape(t, invalid)-beforeInvalid != 1 || scrape(t, missing)-beforeMissing != 1 {
+		t.Fatal("expected one invalid and one missing token counted")
+	}
+}
+
+// NOTE: Happy path
+func TestMetricsCountRecordedEntries(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupMusicRoutes)
+	series := "tremolo_note_game_entries_recorded_total"
+	before := scrape(t, series)
+
+	expectNoteAnswers(mock, 1, []string{"C4"}, studentClaims.UserID, 2.0, 1, 1, 30)
+	body := `{"answers": [{"pitch": "C4", "answer": "C", "response_ms": 2000}]}`
+	if rec := signedInRequest(t, router, studentClaims, http.MethodPost, "/music/note-game/answers", body); rec.Code != http.StatusCreated {
+		t.Fatalf("expected the answers recorded, got %d %s", rec.Code, rec.Body)
+	}
+
+	if got := scrape(t, series) - before; got != 1 {
+		t.Fatalf("expected one entry counted, got %v", got)
+	}
+}
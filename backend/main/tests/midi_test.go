@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sight-reading/midi"
+	"sight-reading/musicxml"
+	"testing"
+)
+
+type midiEvent struct {
+	tick int
+	data []byte
+}
+
+// readMIDI splits a file into its tracks of absolute timed events, just
+// enough of the format to check what the exporter writes
+func readMIDI(t *testing.T, file []byte) (format, ppq int, tracks [][]midiEvent) {
+	t.Helper()
+	if string(file[:4]) != "MThd" {
+		t.Fatal("missing MThd")
+	}
+	format = int(binary.BigEndian.Uint16(file[8:]))
+	count := int(binary.BigEndian.Uint16(file[10:]))
+	ppq = int(binary.BigEndian.Uint16(file[12:]))
+
+	rest := file[14:]
+	for i := 0; i < count; i++ {
+		if string(rest[:4]) != "MTrk" {
+			t.Fatalf("track %d: missing MTrk", i)
+		}
+		length := int(binary.BigEndian.Uint32(rest[4:]))
+		body := rest[8 : 8+length]
+		rest = rest[8+length:]
+
+		var events []midiEvent
+		tick := 0
+		for len(body) > 0 {
+			delta := 0
+			for {
+				b := body[0]
+				body = body[1:]
+				delta = delta<<7 | int(b&0x7F)
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			tick += delta
+
+			size := 3
+			switch {
+			case body[0] == 0xFF:
+				size = 3 + int(body[2])
+			case body[0]&0xF0 == 0xC0:
+				size = 2
+			}
+			events = append(events, midiEvent{tick: tick, data: body[:size]})
+			body = body[size:]
+		}
+		tracks = append(tracks, events)
+	}
+	return format, ppq, tracks
+}
+
+// NOTE: Happy path
+func TestExportMaryToMIDI(t *testing.T) {
+	score, err := musicxml.ParseFile("testdata/mary.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := midi.Export(score, midi.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format, ppq, tracks := readMIDI(t, file)
+	if format != 1 || ppq != midi.PPQ || len(tracks) != 2 {
+		t.Fatalf("expected format 1 with a conductor and a part track, got format %d, %d tracks", format, len(tracks))
+	}
+
+	var tempo, timeSignature bool
+	for _, e := range tracks[0] {
+		if bytes.HasPrefix(e.data, []byte{0xFF, 0x51, 3}) {
+			tempo = bytes.Equal(e.data[3:], []byte{0x09, 0x27, 0xC0})
+		}
+		if bytes.HasPrefix(e.data, []byte{0xFF, 0x58, 4}) {
+			timeSignature = e.data[3] == 4 && e.data[4] == 2
+		}
+	}
+	if !tempo || !timeSignature {
+		t.Fatalf("expected 100 bpm in 4/4, got tempo %v time %v", tempo, timeSignature)
+	}
+
+	var ons []midiEvent
+	for _, e := range tracks[1] {
+		if e.data[0]&0xF0 == 0x90 {
+			ons = append(ons, e)
+		}
+	}
+	if len(ons) != 10 {
+		t.Fatalf("expected 10 notes, got %d", len(ons))
+	}
+	// E4 D4 C4 ..., the closing chord starts on beat 4
+	if ons[0].data[1] != 64 || ons[1].data[1] != 62 || ons[1].tick != midi.PPQ/2 || ons[9].tick != 4*midi.PPQ {
+		t.Fatalf("unexpected notes %v", ons)
+	}
+}
+
+func TestMIDIJoinsTiesAndOverridesTheProgram(t *testing.T) {
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name>Flute</part-name><midi-instrument id="I1"><midi-program>74</midi-program></midi-instrument></score-part></part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions></attributes>
+      <note><pitch><step>C</step><octave>4</octave></pitch><duration>2</duration><tie type="start"/></note>
+      <note><pitch><step>C</step><octave>4</octave></pitch><duration>2</duration><tie type="stop"/></note>
+    </measure>
+  </part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		program *int
+		want    byte
+	}{{nil, 73}, {new(int), 0}} {
+		file, err := midi.Export(score, midi.Options{Program: c.program, Tempo: 60})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, tracks := readMIDI(t, file)
+
+		var program byte
+		var ons, offs []midiEvent
+		for _, e := range tracks[1] {
+			switch e.data[0] & 0xF0 {
+			case 0xC0:
+				program = e.data[1]
+			case 0x90:
+				ons = append(ons, e)
+			case 0x80:
+				offs = append(offs, e)
+			}
+		}
+		if program != c.want {
+			t.Errorf("expected program %d, got %d", c.want, program)
+		}
+		if len(ons) != 1 || len(offs) != 1 || offs[0].tick != 4*midi.PPQ {
+			t.Fatalf("expected one tied whole note, got %v %v", ons, offs)
+		}
+	}
+}
+
+// NOTE: Sad path
+func TestMIDIClampsTempoAndBackups(t *testing.T) {
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name>Piano</part-name></score-part></part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions></attributes>
+      <sound tempo="1"/>
+      <note><pitch><step>C</step><octave>4</octave></pitch><duration>4</duration></note>
+      <backup><duration>8</duration></backup>
+      <note><pitch><step>E</step><octave>4</octave></pitch><duration>6</duration></note>
+    </measure>
+  </part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := midi.Export(score, midi.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, tracks := readMIDI(t, file)
+
+	// 1 bpm does not fit the file, it plays at the slowest tempo
+	var tempo []byte
+	for _, e := range tracks[0] {
+		if bytes.HasPrefix(e.data, []byte{0xFF, 0x51, 3}) {
+			tempo = e.data[3:]
+		}
+	}
+	micros := 60_000_000 / midi.MinTempo
+	if !bytes.Equal(tempo, []byte{byte(micros >> 16), byte(micros >> 8), byte(micros)}) {
+		t.Fatalf("expected %d bpm, got %v", midi.MinTempo, tempo)
+	}
+
+	// the E backed up to before the start starts with the C
+	for _, e := range tracks[1] {
+		if e.tick < 0 || e.tick > 4*midi.PPQ {
+			t.Fatalf("expected every event within the measure, got %v", tracks[1])
+		}
+	}
+}
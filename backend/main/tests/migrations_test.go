@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"sight-reading/database"
+	"testing"
+)
+
+func TestMigrationsAreContiguous(t *testing.T) {
+	migrations, err := database.LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations were embedded")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("expected migration %d, found %04d_%s", i+1, migration.Version, migration.Name)
+		}
+		if migration.Down == "" {
+			t.Errorf("migration %04d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
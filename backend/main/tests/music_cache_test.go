@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sight-reading/music"
+	"strings"
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+)
+
+// countingGenerator stands in for the music service
+type countingGenerator struct {
+	calls int
+}
+
+func (g *countingGenerator) Mary(_ context.Context, req dtos.MaryRequest) ([]byte, error) {
+	g.calls++
+	return []byte("<score-partwise tonic=\"" + req.Tonic + "\"/>"), nil
+}
+
+func (g *countingGenerator) Random(_ context.Context, req dtos.RandomRequest) ([]byte, error) {
+	g.calls++
+	return []byte("<score-partwise/>"), nil
+}
+
+func (g *countingGenerator) NoteGame(_ context.Context, req dtos.NoteGameRequest) (dtos.NoteGame, error) {
+	g.calls++
+	return dtos.NoteGame{GeneratedXML: "<score-partwise/>", NoteName: "C", NoteOctave: req.Octave}, nil
+}
+
+// NOTE: Happy path
+func TestCachedGeneratorServesRepeats(t *testing.T) {
+	generator := &countingGenerator{}
+	cached := music.NewCached(generator, music.NewLRU(1<<20), 1)
+
+	for i := 0; i < 30; i++ {
+		if _, err := cached.Mary(context.Background(), dtos.MaryRequest{Tonic: "C", Octave: "4"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if generator.calls != 1 {
+		t.Fatalf("expected one generation for 30 identical requests, got %d", generator.calls)
+	}
+
+	cached.Mary(context.Background(), dtos.MaryRequest{Tonic: "G", Octave: "4"})
+	if generator.calls != 2 {
+		t.Fatalf("other parameters should be generated, got %d calls", generator.calls)
+	}
+}
+
+func TestLRUEvictsTheOldest(t *testing.T) {
+	lru := music.NewLRU(10)
+	ctx := context.Background()
+
+	lru.Set(ctx, "a", []byte("aaaa"))
+	lru.Set(ctx, "b", []byte("bbbb"))
+	lru.Get(ctx, "a")
+	lru.Set(ctx, "c", []byte("cccc"))
+
+	if _, ok, _ := lru.Get(ctx, "b"); ok {
+		t.Fatal("b was least recently used and should have been evicted")
+	}
+	if _, ok, _ := lru.Get(ctx, "a"); !ok {
+		t.Fatal("a was used recently and should still be cached")
+	}
+}
+
+func TestDiskStoreRoundTrip(t *testing.T) {
+	store, err := music.NewDiskStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := strings.Repeat("ab", 32)
+
+	if _, ok, _ := store.Get(ctx, key); ok {
+		t.Fatal("empty store should miss")
+	}
+	if err := store.Set(ctx, key, []byte("<score/>")); err != nil {
+		t.Fatal(err)
+	}
+	body, ok, err := store.Get(ctx, key)
+	if err != nil || !ok || string(body) != "<score/>" {
+		t.Fatalf("expected the stored exercise back, got %q %v %v", body, ok, err)
+	}
+}
+
+// NOTE: Sad path
+func TestDiskStoreExpires(t *testing.T) {
+	dir := t.TempDir()
+	store, err := music.NewDiskStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	old, fresh := strings.Repeat("ab", 32), strings.Repeat("cd", 32)
+
+	for _, key := range []string{old, fresh} {
+		if err := store.Set(ctx, key, []byte("<score/>")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored := filepath.Join(dir, old[:2], old)
+	aged := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stored, aged, aged); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := store.Get(ctx, old); ok {
+		t.Fatal("an exercise older than the ttl should miss")
+	}
+	removed, err := store.Sweep(ctx)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected the sweep to remove one exercise, got %d %v", removed, err)
+	}
+	if _, err := os.Stat(stored); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the expired file removed, got %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, fresh); !ok {
+		t.Fatal("a fresh exercise should survive the sweep")
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/controllers"
+	"sight-reading/music"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+)
+
+// NOTE: Happy path
+func TestMusicClientRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("expected the service token, got %q", r.Header.Get("Authorization"))
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"generatedXml": "<score/>", "noteName": "C", "noteOctave": "4"}`))
+	}))
+	defer server.Close()
+
+	client := music.NewClient(music.ClientOptions{
+		BaseURL: server.URL,
+		Token:   "secret",
+		Retries: 1,
+		Backoff: time.Millisecond,
+	})
+
+	noteGame, err := client.NoteGame(context.Background(), dtos.NoteGameRequest{Scale: "C", Octave: "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noteGame.NoteName != "C" || calls.Load() != 2 {
+		t.Fatalf("expected C after a retry, got %+v after %d calls", noteGame, calls.Load())
+	}
+}
+
+// NOTE: Sad path
+func TestMusicClientDoesNotRetryBadRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "unknown tonic", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := music.NewClient(music.ClientOptions{BaseURL: server.URL, Retries: 3, Backoff: time.Millisecond})
+
+	_, err := client.Mary(context.Background(), dtos.MaryRequest{Tonic: "H", Octave: "4"})
+	var requestErr *music.RequestError
+	if !errors.As(err, &requestErr) || requestErr.Body != "unknown tonic" {
+		t.Fatalf("expected a request error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("a 400 should not be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestMusicClientOpensTheCircuit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := music.NewClient(music.ClientOptions{
+		BaseURL:          server.URL,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Random(context.Background(), dtos.RandomRequest{}); err == nil {
+			t.Fatal("expected the call to fail")
+		}
+	}
+
+	_, err := client.Random(context.Background(), dtos.RandomRequest{})
+	if !errors.Is(err, music.ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to be open, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("an open circuit should not call the service, got %d calls", calls.Load())
+	}
+}
+
+// NOTE: Sad path
+func TestMusicClientIgnoresCallersThatGaveUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+			w.Write([]byte("<score/>"))
+		}
+	}))
+	defer server.Close()
+
+	client := music.NewClient(music.ClientOptions{BaseURL: server.URL, BreakerThreshold: 1, BreakerCooldown: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := client.Random(ctx, dtos.RandomRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+
+	// an impatient caller does not open the circuit for everyone else
+	if _, err := client.Random(context.Background(), dtos.RandomRequest{}); err != nil {
+		t.Fatalf("expected the circuit closed, got %v", err)
+	}
+}
+
+// NOTE: Sad path
+func TestMusicServiceAnswersAreNotShown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Traceback: KeyError 'H' in /srv/music/views.py", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	router, _ := mockedRouter(t, controllers.SetupMusicRoutes)
+	previous := music.Service
+	music.Service = music.NewClient(music.ClientOptions{BaseURL: server.URL})
+	t.Cleanup(func() { music.Service = previous })
+
+	rec := signedInRequest(t, router, studentClaims, http.MethodPost, "/music/mary", `{"tonic": "H", "octave": "4"}`)
+	if rec.Code != http.StatusUnprocessableEntity || strings.Contains(rec.Body.String(), "Traceback") {
+		t.Fatalf("expected a 422 without the service's answer, got %d %s", rec.Code, rec.Body)
+	}
+}
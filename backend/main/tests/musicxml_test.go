@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"reflect"
+	"sight-reading/musicxml"
+	"strings"
+	"testing"
+)
+
+// NOTE: Happy path
+func TestParseMusicXML(t *testing.T) {
+	score, err := musicxml.ParseFile("testdata/mary.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := score.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if score.Version != "3.1" || len(score.Parts) != 1 {
+		t.Fatalf("expected one part of a 3.1 score, got %q with %d parts", score.Version, len(score.Parts))
+	}
+
+	part := score.Parts[0]
+	attributes := part.Measures[0].Attributes()
+	if attributes == nil || attributes.Divisions != 10080 || attributes.Times[0].Beats != "4" {
+		t.Fatalf("unexpected attributes %+v", attributes)
+	}
+
+	timeline := part.Timeline()
+	if len(timeline) != 10 {
+		t.Fatalf("expected 10 notes, got %d", len(timeline))
+	}
+
+	// measure 2 is a C major chord, the chord tones start with the C
+	last := timeline[len(timeline)-1]
+	if last.Duration != 1 || last.Note.Pitch.String() != "G4" || last.Note.Pitch.MIDI() != 67 {
+		t.Fatalf("unexpected last note %+v %v", last, last.Note.Pitch)
+	}
+	for _, note := range timeline[7:] {
+		if note.Onset != 4 {
+			t.Fatalf("expected the chord on beat 4, %v is on %v", note.Note.Pitch, note.Onset)
+		}
+	}
+}
+
+func TestMusicXMLRoundTrip(t *testing.T) {
+	score, err := musicxml.ParseFile("testdata/mary.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := musicxml.Marshal(score)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := musicxml.ParseBytes(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(score, again) {
+		t.Fatalf("score changed in the round trip:\n%s", encoded)
+	}
+	if !strings.Contains(string(encoded), `<barline location="right">`) {
+		t.Fatalf("barline was lost:\n%s", encoded)
+	}
+}
+
+// NOTE: Sad path
+func TestMusicXMLValidateFindsProblems(t *testing.T) {
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name/></score-part></part-list>
+  <part id="P2">
+    <measure number="1">
+      <note><pitch><step>H</step><octave>4</octave></pitch><duration>1</duration></note>
+    </measure>
+  </part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = score.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"not in the part list", `invalid step "H"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	if _, err := musicxml.ParseBytes([]byte(`<score-timewise/>`)); err == nil {
+		t.Fatal("expected score-timewise to be rejected")
+	}
+}
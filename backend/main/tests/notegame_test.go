@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/music"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+	"slices"
+	"testing"
+
+	dtos "sight-reading/DTOs"
+)
+
+// NOTE: Happy path
+func TestLocalNoteGameStaysInTheScale(t *testing.T) {
+	local := music.NewLocal(rand.New(rand.NewPCG(1, 2)))
+	scale := []string{"E-", "F", "G", "A-", "B-", "C", "D"}
+
+	for i := 0; i < 50; i++ {
+		noteGame, err := local.NoteGame(context.Background(), dtos.NoteGameRequest{Scale: "E-", Octave: "4"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(scale, noteGame.NoteName) {
+			t.Fatalf("%s is not in E flat major", noteGame.NoteName)
+		}
+
+		score, err := musicxml.ParseBytes([]byte(noteGame.GeneratedXML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := score.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		note := score.Parts[0].Measures[0].Notes()[0]
+		if fromMusicXML(t, note.Pitch).Name() != noteGame.NoteName {
+			t.Fatalf("the xml has %v but the answer is %s", note.Pitch, noteGame.NoteName)
+		}
+		if score.Parts[0].Measures[0].Attributes().Keys[0].Fifths != -3 {
+			t.Fatal("expected the E flat major key signature")
+		}
+	}
+}
+
+func TestLocalNoteGameLedgerLines(t *testing.T) {
+	local := music.NewLocal(rand.New(rand.NewPCG(3, 4)))
+	none := 0
+
+	for i := 0; i < 50; i++ {
+		noteGame, err := local.NoteGame(context.Background(), dtos.NoteGameRequest{
+			Scale: "C", Octave: "4", Octaves: 2, Clef: "treble", MaxLedgerLines: &none,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if noteGame.NoteOctave == "4" && noteGame.NoteName == "C" {
+			t.Fatal("C4 needs a ledger line on the treble clef")
+		}
+	}
+}
+
+func TestLocalNoteGameStaysInTheInstrumentRange(t *testing.T) {
+	local := music.NewLocal(rand.New(rand.NewPCG(5, 6)))
+	low, high := theory.Pitch{Step: 'G', Octave: 3}.MIDI(), theory.Pitch{Step: 'D', Octave: 4}.MIDI()
+
+	for i := 0; i < 50; i++ {
+		noteGame, err := local.NoteGame(context.Background(), dtos.NoteGameRequest{
+			Scale: "C", Octave: "3", Octaves: 3, RangeLow: "G3", RangeHigh: "D4",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pitch, err := theory.ParsePitch(noteGame.NoteName + noteGame.NoteOctave)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pitch.MIDI() < low || pitch.MIDI() > high {
+			t.Fatalf("%s is outside G3 to D4", pitch)
+		}
+	}
+}
+
+func TestFallbackGeneratesLocallyWhenTheServiceIsDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	generator := music.NewFallback(music.NewClient(music.ClientOptions{BaseURL: server.URL}), music.NewLocal(nil))
+
+	noteGame, err := generator.NoteGame(context.Background(), dtos.NoteGameRequest{Scale: "G", Octave: "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noteGame.GeneratedXML == "" || noteGame.NoteName == "" {
+		t.Fatalf("expected a local question, got %+v", noteGame)
+	}
+}
+
+// NOTE: Sad path
+func TestLocalNoteGameRejectsBadRequests(t *testing.T) {
+	local := music.NewLocal(nil)
+
+	for _, req := range []dtos.NoteGameRequest{
+		{Scale: "H", Octave: "4"},
+		{Scale: "C", Octave: "four"},
+		{Scale: "G#", Octave: "4"},
+		{Scale: "C", Octave: "7", Clef: "bass", MaxLedgerLines: new(int)},
+		{Scale: "C", Octave: "4", RangeLow: "C6"},
+		{Scale: "C", Octave: "4", RangeHigh: "low"},
+	} {
+		_, err := local.NoteGame(context.Background(), req)
+		var requestErr *music.RequestError
+		if !errors.As(err, &requestErr) {
+			t.Errorf("expected %+v to be rejected, got %v", req, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/controllers"
+	"slices"
+	"strings"
+	"testing"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registeredRoutes() map[string]bool {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	controllers.SetupRoutes(router)
+
+	routes := map[string]bool{}
+	for _, route := range router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	return routes
+}
+
+// NOTE: Happy path
+func TestEveryRouteIsDocumented(t *testing.T) {
+	documented := map[string]bool{}
+	for _, route := range controllers.DocumentedRoutes() {
+		documented[route.Method+" "+route.Path] = true
+	}
+
+	registered := registeredRoutes()
+	for route := range registered {
+		if !documented[route] {
+			t.Errorf("%s is registered but missing from controllers.DocumentedRoutes", route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			t.Errorf("%s is documented but no longer registered", route)
+		}
+	}
+}
+
+func TestSpecContainsEveryRoute(t *testing.T) {
+	doc := controllers.OpenAPIDocument()
+
+	for route := range registeredRoutes() {
+		method, path, _ := strings.Cut(route, " ")
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+
+		item, ok := doc.Paths[strings.Join(segments, "/")]
+		if !ok || !item.Has(method) {
+			t.Errorf("%s is missing from the generated spec", route)
+		}
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpecCarriesValidationConstraints(t *testing.T) {
+	user := controllers.OpenAPIDocument().Components.Schemas["User"]
+	if user == nil {
+		t.Fatal("User schema was not generated")
+	}
+
+	firstName := user.Properties["first_name"]
+	if firstName == nil || firstName.MaxLength == nil || *firstName.MaxLength != 255 {
+		t.Fatalf("expected first_name to carry the len255 constraint, got %+v", firstName)
+	}
+	if len(user.Properties["role"].Enum) == 0 {
+		t.Fatal("expected role to be an enum")
+	}
+
+	required := strings.Join(user.Required, ",")
+	if !strings.Contains(required, "email") || !strings.Contains(required, "school_id") {
+		t.Fatalf("expected email and school_id to be required, got %v", user.Required)
+	}
+}
+
+// NOTE: Sad path
+func TestSpecMarksTheRoutesThatNeedAToken(t *testing.T) {
+	router, _ := mockedRouter(t, controllers.SetupRoutes)
+	doc := controllers.OpenAPIDocument()
+	if scheme := doc.Components.SecuritySchemes["bearerAuth"]; scheme == nil || scheme.Scheme != "bearer" {
+		t.Fatalf("expected a bearer scheme, got %+v", scheme)
+	}
+
+	for _, route := range controllers.DocumentedRoutes() {
+		path := strings.ReplaceAll(route.Path, ":id", "1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(route.Method, path, nil))
+
+		// a route behind auth.Require turns away a request without a token
+		// before anything else, the spec has to say it needs one
+		if needsToken := rec.Code == http.StatusUnauthorized; needsToken != route.Auth {
+			t.Errorf("%s %s answers %d without a token but is documented with Auth %v", route.Method, route.Path, rec.Code, route.Auth)
+		}
+
+		item := doc.Paths[strings.NewReplacer(":id", "{id}").Replace(route.Path)]
+		if op := item.Operation(route.Method); op == nil || (len(op.Security) > 0) != route.Auth {
+			t.Errorf("%s %s security does not match its Auth", route.Method, route.Path)
+		}
+	}
+}
+
+// NOTE: Happy path
+func TestRequestSchemasMatchTheirValidation(t *testing.T) {
+	doc := controllers.OpenAPIDocument()
+
+	answers := doc.Components.Schemas["NoteAnswersRequest"]
+	if answers == nil {
+		t.Fatal("NoteAnswersRequest schema was not generated")
+	}
+	if clefs := answers.Properties["clef"].Enum; !slices.Equal(clefs, []any{"treble", "bass", "alto", "tenor"}) {
+		t.Errorf("expected the clef enum of the oneof rule, got %v", clefs)
+	}
+	if scale := answers.Properties["scale"]; scale.MaxLength == nil || *scale.MaxLength != 8 {
+		t.Errorf("expected scale to carry max=8, got %+v", scale)
+	}
+	list := answers.Properties["answers"]
+	if list.MinItems == nil || *list.MinItems != 1 || list.MaxItems == nil || *list.MaxItems != 200 {
+		t.Errorf("expected answers to carry min=1 and max=200 as item counts, got %+v", list)
+	}
+	if !slices.Contains(answers.Required, "answers") || slices.Contains(answers.Required, "clef") {
+		t.Errorf("expected only answers required, got %v", answers.Required)
+	}
+
+	// the rules after dive are for each item, not the list
+	tags := doc.Components.Schemas["ExerciseMetadata"].Properties["tags"]
+	if *tags.MaxItems != 20 || *tags.Items.MinLength != 1 || *tags.Items.MaxLength != 32 {
+		t.Errorf("expected 20 tags of 1 to 32 characters, got %+v items %+v", tags, tags.Items)
+	}
+
+	// and the dto turns away what the schema does
+	request := dtos.NoteAnswersRequest{Clef: "soprano", Scale: "Cmajorscale", Answers: []dtos.NoteAnswer{}}
+	err := request.ValidateNoteAnswers()
+	for _, field := range []string{"Clef", "Scale", "Answers"} {
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s rejected, got %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sight-reading/controllers"
+	"sight-reading/practice"
+	"strings"
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// NOTE: Happy path
+func TestStreakCountsDaysInARow(t *testing.T) {
+	days := []string{"2024-09-01", "2024-09-02", "2024-09-03", "2024-09-04", "2024-09-07", "2024-09-08", "2024-09-09"}
+
+	streak, err := practice.Streak(days, "2024-09-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// yesterday still counts, today can be practiced yet
+	if streak.Current != 3 || streak.Longest != 4 || streak.PracticedToday {
+		t.Fatalf("expected a current streak of 3 and a longest of 4, got %+v", streak)
+	}
+	if streak.LastPracticed == nil || *streak.LastPracticed != "2024-09-09" {
+		t.Fatalf("expected the last practice on the 9th, got %v", streak.LastPracticed)
+	}
+
+	streak, err = practice.Streak(append(days, "2024-09-10"), "2024-09-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streak.Current != 4 || streak.Longest != 4 || !streak.PracticedToday {
+		t.Fatalf("expected today to extend the streak to 4, got %+v", streak)
+	}
+}
+
+// NOTE: Sad path
+func TestStreakIsBrokenByAMissedDay(t *testing.T) {
+	streak, err := practice.Streak([]string{"2024-02-28", "2024-02-29", "2024-03-01"}, "2024-03-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the leap day keeps the run together, the 2nd breaks the streak
+	if streak.Current != 0 || streak.Longest != 3 {
+		t.Fatalf("expected a broken streak with a longest of 3, got %+v", streak)
+	}
+
+	streak, err = practice.Streak(nil, "2024-03-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streak.Current != 0 || streak.Longest != 0 || streak.LastPracticed != nil {
+		t.Fatalf("expected no streak, got %+v", streak)
+	}
+
+	if _, err := practice.Streak([]string{"yesterday"}, "2024-03-03"); err == nil {
+		t.Fatal("expected an invalid date")
+	}
+}
+
+// NOTE: Happy path
+func TestGoalProgress(t *testing.T) {
+	minutes, questions := 10, 50
+	goal := &dtos.PracticeGoal{DailyMinutes: &minutes, DailyQuestions: &questions}
+	practiced := []practice.Day{
+		{Date: "2024-09-09", Seconds: 900, Questions: 60},
+		{Date: "2024-09-10", Seconds: 300, Questions: 40},
+	}
+
+	progress, err := practice.Progress(practiced, goal, "2024-09-10", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 3 || progress[0].Date != "2024-09-08" || progress[2].Date != "2024-09-10" {
+		t.Fatalf("expected the 8th to the 10th, got %+v", progress)
+	}
+	if progress[0].Percent != 0 || progress[0].Met {
+		t.Fatalf("expected no practice on the 8th, got %+v", progress[0])
+	}
+	if progress[1].Percent != 100 || !progress[1].Met || progress[1].Minutes != 15 {
+		t.Fatalf("expected the goal met on the 9th, got %+v", progress[1])
+	}
+	// 5 of 10 minutes is further from met than 40 of 50 questions
+	if progress[2].Percent != 50 || progress[2].Met {
+		t.Fatalf("expected today half way, got %+v", progress[2])
+	}
+}
+
+// NOTE: Sad path
+func TestGoalValidation(t *testing.T) {
+	var empty dtos.GoalRequest
+	if err := empty.ValidateGoal(); err == nil || !strings.Contains(err.Error(), "daily_minutes") {
+		t.Fatalf("expected a goal to need minutes or questions, got %v", err)
+	}
+
+	minutes := 0
+	zero := dtos.GoalRequest{DailyMinutes: &minutes}
+	if err := zero.ValidateGoal(); err == nil || !strings.Contains(err.Error(), "DailyMinutes") {
+		t.Fatalf("expected 0 minutes to be rejected, got %v", err)
+	}
+
+	if practice.ValidTimezone("Mars/Olympus_Mons") || practice.ValidTimezone("Local") {
+		t.Fatal("expected unknown timezones to be rejected")
+	}
+	if !practice.ValidTimezone("America/Chicago") {
+		t.Fatal("expected America/Chicago to be a timezone")
+	}
+}
+
+// expectNoteAnswers expects a note game session of answers answers asking
+// notes to be recorded, entry are the arguments of its entry
+func expectNoteAnswers(mock sqlmock.Sqlmock, answers int, notes []string, entry ...driver.Value) {
+	now := time.Now()
+	mock.ExpectBegin()
+	for range answers {
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO note_game_answers")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "answered_at"}).AddRow(1, now))
+	}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO note_game_entries")).
+		WithArgs(entry...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM note_reviews")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	for _, pitch := range notes {
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO note_reviews")).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"user_id", "pitch", "clef", "ease", "interval_days", "repetitions", "lapses", "reviews", "due_at", "reviewed_at",
+			}).AddRow(studentClaims.UserID, pitch, "treble", 2.5, 1, 1, 0, 1, now, now))
+	}
+	mock.ExpectCommit()
+	// rating the session is best effort
+	mock.ExpectBegin().WillReturnError(errors.New("no ratings in this test"))
+}
+
+// NOTE: Happy path
+func TestNoteAnswersRecordAnEntry(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupMusicRoutes)
+	body := `{"answers": [
+    {"pitch": "C4", "answer": "C", "response_ms": 2000},
+    {"pitch": "D4", "answer": "E", "response_ms": 2000},
+    {"pitch": "C4", "answer": "C", "response_ms": 2000}
+  ]}`
+
+	// three notes in six seconds, two of them right
+	expectNoteAnswers(mock, 3, []string{"C4", "D4"}, studentClaims.UserID, 6.0, 3, 2, 30)
+
+	rec := signedInRequest(t, router, studentClaims, http.MethodPost, "/music/note-game/answers", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected the answers recorded, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+// NOTE: Happy path
+func TestStreakCountsDaysInTheSchoolTimezone(t *testing.T) {
+	router, mock := mockedRouter(t, controllers.SetupPracticeRoutes)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT (now() AT TIME ZONE s.timezone)::date::text AS today, s.timezone")).
+		WithArgs(studentClaims.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"today", "timezone"}).AddRow("2024-09-10", "America/Chicago"))
+	// the database's clock is moved to the school's before taking the date
+	mock.ExpectQuery(regexp.QuoteMeta("AT TIME ZONE current_setting('TimeZone') AT TIME ZONE s.timezone)::date")).
+		WithArgs(studentClaims.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"day"}).AddRow("2024-09-08").AddRow("2024-09-09").AddRow("2024-09-10"))
+
+	rec := signedInRequest(t, router, studentClaims, http.MethodGet, "/users/9/streak", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the streak, got %d %s", rec.Code, rec.Body)
+	}
+	var streak dtos.Streak
+	if err := json.Unmarshal(rec.Body.Bytes(), &streak); err != nil {
+		t.Fatal(err)
+	}
+	if streak.Current != 3 || !streak.PracticedToday || streak.Timezone != "America/Chicago" {
+		t.Fatalf("expected three days in a row in Chicago, got %+v", streak)
+	}
+}
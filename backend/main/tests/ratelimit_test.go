@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sight-reading/apperrors"
+	"sight-reading/auth"
+	"sight-reading/ratelimit"
+	"strconv"
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NOTE: Happy path
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("30/m:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit.Rate != 0.5 || limit.Burst != 10 {
+		t.Fatalf("unexpected limit %+v", limit)
+	}
+
+	if _, err := ratelimit.ParseLimit("ten per minute"); err == nil {
+		t.Fatal("expected an error for a malformed limit")
+	}
+}
+
+func TestLimiterRefuses(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Limit{Rate: 1, Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("ip:1.2.3.4"); !ok {
+			t.Fatalf("request %d should be inside the burst", i)
+		}
+	}
+
+	ok, retryAfter := limiter.Allow("ip:1.2.3.4")
+	if ok || retryAfter <= 0 {
+		t.Fatalf("expected the third request to be refused, got ok=%v retry=%v", ok, retryAfter)
+	}
+
+	if ok, _ := limiter.Allow("ip:5.6.7.8"); !ok {
+		t.Fatal("another ip should have its own bucket")
+	}
+}
+
+// NOTE: Sad path
+func TestSignupIsRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(apperrors.Middleware())
+	router.POST("/user", ratelimit.For(ratelimit.Signup), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	// the signup burst is small, well under 50 in any sane config
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 50; i++ {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/user", nil))
+		if rec.Code == http.StatusTooManyRequests {
+			break
+		}
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the burst is spent, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+}
+
+// NOTE: Sad path
+func TestTokenGuessingIsRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.SetSecret("rate limit test")
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(apperrors.Middleware(), ratelimit.Failures(ratelimit.Auth), auth.Middleware())
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(remote, token, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = remote
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// a new X-Forwarded-For every time is still the same client
+	code := 0
+	for i := 0; i < 50 && code != http.StatusTooManyRequests; i++ {
+		code = request("192.0.2.1:1234", "guess", "198.51.100."+strconv.Itoa(i))
+	}
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the failures are spent, got %d", code)
+	}
+
+	token, err := auth.Issue(auth.Claims{UserID: 1, Role: dtos.Student}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := request("192.0.2.1:1234", token, ""); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the guessing ip to stay refused, got %d", code)
+	}
+	if code := request("192.0.2.1:1234", "", ""); code != http.StatusOK {
+		t.Fatalf("expected requests without a token to pass, got %d", code)
+	}
+	// signed in requests do not count
+	for i := 0; i < 50; i++ {
+		if code := request("192.0.2.2:1234", token, ""); code != http.StatusOK {
+			t.Fatalf("expected valid tokens to pass, got %d on request %d", code, i)
+		}
+	}
+}
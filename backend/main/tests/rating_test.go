@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"math"
+	"sight-reading/grading"
+	"sight-reading/rating"
+	"sight-reading/theory"
+	"testing"
+)
+
+func session(dimension string, level float64, right, wrong int) []rating.Observation {
+	var observations []rating.Observation
+	for i := 0; i < right+wrong; i++ {
+		score := 0.0
+		if i < right {
+			score = 1
+		}
+		observations = append(observations, rating.Observation{Dimension: dimension, Level: level, Score: score, Weight: 1})
+	}
+	return observations
+}
+
+// NOTE: Happy path
+func TestHardQuestionsMoveRatingsMore(t *testing.T) {
+	student := rating.New(rating.TrebleNotes)
+
+	easy, _ := student.Update(session(rating.TrebleNotes, 2, 9, 1))
+	hard, _ := student.Update(session(rating.TrebleNotes, 9, 9, 1))
+	if !(hard.Rating > easy.Rating) {
+		t.Fatalf("expected 90%% on hard notes to rate above 90%% on easy ones, got %v and %v", hard.Rating, easy.Rating)
+	}
+	if easy.Rating >= rating.Initial+10 {
+		t.Fatalf("expected 90%% on easy notes to be about what was expected, got %v", easy.Rating)
+	}
+
+	missedHard, _ := student.Update(session(rating.TrebleNotes, 9, 0, 10))
+	missedEasy, _ := student.Update(session(rating.TrebleNotes, 2, 0, 10))
+	if !(missedEasy.Rating < missedHard.Rating && missedHard.Rating < rating.Initial) {
+		t.Fatalf("expected missing easy notes to cost more than missing hard ones, got %v and %v", missedEasy.Rating, missedHard.Rating)
+	}
+	if hard.Sessions != 1 {
+		t.Fatalf("expected one session, got %d", hard.Sessions)
+	}
+}
+
+// NOTE: Happy path
+func TestRatingsSettleAfterTheFirstSessions(t *testing.T) {
+	student := rating.New(rating.Rhythm)
+	var moves []float64
+	for i := 0; i < 8; i++ {
+		next, _ := student.Update(session(rating.Rhythm, 5.5, 10, 0))
+		moves = append(moves, next.Rating-student.Rating)
+		student = next
+	}
+	// provisional sessions use twice the K, the step down shows at the sixth
+	if moves[5] > moves[4]*0.6 {
+		t.Fatalf("expected the sixth session to move the rating far less, got %v", moves)
+	}
+	if math.Abs(rating.Expected(student.Rating, student.Rating)-0.5) > 1e-9 {
+		t.Fatal("expected an even match to be expected at half")
+	}
+}
+
+// NOTE: Sad path
+func TestUpdateWithNothingToRate(t *testing.T) {
+	student := rating.New(rating.BassNotes)
+	if _, ok := student.Update(session(rating.TrebleNotes, 5, 3, 0)); ok {
+		t.Fatal("expected treble answers not to rate bass notes")
+	}
+	if _, ok := student.Update(nil); ok {
+		t.Fatal("expected an empty session not to rate")
+	}
+}
+
+// NOTE: Happy path
+func TestNoteObservations(t *testing.T) {
+	dMajor := 2
+	f, _ := theory.ParsePitch("F#4")
+	c6, _ := theory.ParsePitch("C6")
+	g, _ := theory.ParsePitch("G3")
+
+	observations := rating.NoteObservations([]rating.NoteAnswer{
+		{Pitch: f, Clef: theory.Treble, Fifths: &dMajor, Correct: true},
+		{Pitch: c6, Clef: theory.Treble, Fifths: &dMajor, Correct: false},
+		{Pitch: g, Clef: theory.Alto, Correct: true},
+	})
+
+	counts := map[string]int{}
+	for _, observation := range observations {
+		counts[observation.Dimension]++
+	}
+	// F# in D reads the key signature, C# above the staff is C natural here
+	// so also the key signature and an accidental
+	if counts[rating.TrebleNotes] != 2 || counts[rating.KeySignatures] != 2 || len(observations) != 4 {
+		t.Fatalf("unexpected observations %+v", observations)
+	}
+	if observations[0].Level != 1 || observations[0].Score != 1 {
+		t.Fatalf("expected F#4 in D on the staff to be level 1, got %+v", observations[0])
+	}
+	if observations[2].Level != 1+1.5*2+2 || observations[2].Score != 0 {
+		t.Fatalf("expected C6 two ledger lines up with a natural to be level 6, got %+v", observations[2])
+	}
+}
+
+// NOTE: Sad path
+func TestPerformanceObservationsOfAnEmptyResult(t *testing.T) {
+	if observations := rating.PerformanceObservations(4, grading.Result{}); len(observations) != 0 {
+		t.Fatalf("expected nothing to rate, got %+v", observations)
+	}
+
+	observations := rating.PerformanceObservations(4, grading.Result{Expected: 12, RhythmAccuracy: 0.75})
+	if len(observations) != 1 || observations[0].Dimension != rating.Rhythm || observations[0].Weight != 12 {
+		t.Fatalf("expected one rhythm observation of 12 notes, got %+v", observations)
+	}
+}
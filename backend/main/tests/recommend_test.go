@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sight-reading/controllers"
+	"sight-reading/recommend"
+	"strings"
+	"testing"
+
+	dtos "sight-reading/DTOs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func sessions(n, total, correct, npm, seconds int) []recommend.Session {
+	var sessions []recommend.Session
+	for i := 0; i < n; i++ {
+		sessions = append(sessions, recommend.Session{Total: total, Correct: correct, NPM: npm, Seconds: seconds})
+	}
+	return sessions
+}
+
+func changeOf(t *testing.T, recommendation dtos.Recommendation, setting string) dtos.SettingChange {
+	t.Helper()
+	for _, change := range recommendation.Changes {
+		if change.Setting == setting {
+			if change.Reason == "" {
+				t.Fatalf("expected a reason for %s", setting)
+			}
+			return change
+		}
+	}
+	t.Fatalf("no recommendation for %s", setting)
+	return dtos.SettingChange{}
+}
+
+// NOTE: Happy path
+func TestFluentStudentsGetAHarderKey(t *testing.T) {
+	recommendation, err := recommend.Recommend(sessions(5, 20, 19, 40, 60), recommend.DefaultSettings, recommend.DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recommendation.Recommended.Scale != "G" || changeOf(t, recommendation, recommend.Scale).Change != recommend.Up {
+		t.Fatalf("expected C major to move up to G, got %+v", recommendation.Recommended)
+	}
+	// 60 second sessions are under the 120 second target
+	if recommendation.Recommended.NoteCount != 15 || changeOf(t, recommendation, recommend.NoteCount).Change != recommend.Up {
+		t.Fatalf("expected 5 more notes, got %+v", recommendation.Recommended)
+	}
+	if changeOf(t, recommendation, recommend.Tempo).Change != recommend.Keep {
+		t.Fatal("expected the tempo to stay")
+	}
+	if recommendation.Accuracy != 0.95 || recommendation.Sessions != 5 {
+		t.Fatalf("unexpected summary %+v", recommendation)
+	}
+	if len(recommendation.Changes) != 4 {
+		t.Fatalf("expected a recommendation per setting, got %+v", recommendation.Changes)
+	}
+}
+
+// NOTE: Happy path
+func TestAccurateButSlowStudentsGetAFasterTempo(t *testing.T) {
+	current := recommend.DefaultSettings
+	current.Scale = "B-"
+	recommendation, err := recommend.Recommend(sessions(4, 20, 19, 12, 300), current, recommend.DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tempo := changeOf(t, recommendation, recommend.Tempo)
+	if recommendation.Recommended.Tempo != 70 || tempo.From != "60" || tempo.To != "70" {
+		t.Fatalf("expected 60 to 70 bpm, got %+v", tempo)
+	}
+	if !strings.Contains(tempo.Reason, "12 notes per minute") {
+		t.Fatalf("expected the reason to give the speed, got %q", tempo.Reason)
+	}
+	if recommendation.Recommended.Scale != "B-" || recommendation.Recommended.NoteCount != 10 {
+		t.Fatalf("expected the key and note count to stay, got %+v", recommendation.Recommended)
+	}
+}
+
+// NOTE: Happy path
+func TestStrugglingStudentsSlowDownThenEaseTheKey(t *testing.T) {
+	rules := recommend.DefaultRules
+	current := recommend.DefaultSettings
+	current.Scale = "D"
+
+	recommendation, err := recommend.Recommend(sessions(5, 20, 10, 10, 200), current, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recommendation.Recommended.Tempo != 50 || recommendation.Recommended.Scale != "D" {
+		t.Fatalf("expected to slow down first, got %+v", recommendation.Recommended)
+	}
+
+	current.Tempo = rules.MinTempo
+	recommendation, err = recommend.Recommend(sessions(5, 20, 10, 10, 200), current, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recommendation.Recommended.Scale != "F" || changeOf(t, recommendation, recommend.Scale).Change != recommend.Down {
+		t.Fatalf("expected D major to ease to F, got %+v", recommendation.Recommended)
+	}
+}
+
+// NOTE: Sad path
+func TestTooFewSessionsKeepTheSettings(t *testing.T) {
+	// the short sessions are left out
+	history := append(sessions(2, 20, 20, 50, 100), sessions(5, 3, 3, 50, 10)...)
+	recommendation, err := recommend.Recommend(history, recommend.DefaultSettings, recommend.DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recommendation.Recommended != recommend.DefaultSettings || recommendation.Sessions != 2 {
+		t.Fatalf("expected nothing to change, got %+v", recommendation)
+	}
+	for _, change := range recommendation.Changes {
+		if change.Change != recommend.Keep || !strings.Contains(change.Reason, "only 2 of the 3") {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	}
+
+	current := recommend.DefaultSettings
+	current.Scale = "H"
+	if _, err := recommend.Recommend(nil, current, recommend.DefaultRules); !errors.Is(err, recommend.ErrUnknownScale) {
+		t.Fatalf("expected an unknown scale, got %v", err)
+	}
+}
+
+// NOTE: Sad path
+func TestRecommendationRulesValidation(t *testing.T) {
+	rules := recommend.DefaultRules
+	if err := rules.ValidateRecommendationRules(); err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+
+	rules.DemoteAccuracy = 0.95
+	rules.MinSessions = 10
+	err := rules.ValidateRecommendationRules()
+	if err == nil || !strings.Contains(err.Error(), "PromoteAccuracy") || !strings.Contains(err.Error(), "MinSessions") {
+		t.Fatalf("expected the promote accuracy and min sessions to be rejected, got %v", err)
+	}
+}
+
+// recorded is a query argument that keeps what it was
+type recorded struct {
+	value driver.Value
+}
+
+func (r *recorded) Match(value driver.Value) bool {
+	r.value = value
+	return true
+}
+
+// NOTE: Happy path
+func TestRecordedSessionChangesTheRecommendation(t *testing.T) {
+	router, mock := mockedRouter(t, func(router *gin.Engine) {
+		controllers.SetupMusicRoutes(router)
+		controllers.SetupRecommendationRoutes(router)
+	})
+
+	// the class recommends after a single session
+	rules := recommend.DefaultRules
+	rules.Sessions, rules.MinSessions = 1, 1
+	stored, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rulesQuery := regexp.QuoteMeta("FROM recommendation_rules r")
+	sessionsQuery := regexp.QuoteMeta("FROM note_game_entries")
+
+	recommendation := func() dtos.Recommendation {
+		t.Helper()
+		rec := signedInRequest(t, router, studentClaims, http.MethodGet, "/users/9/recommendation", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected a recommendation, got %d %s", rec.Code, rec.Body)
+		}
+		var recommendation dtos.Recommendation
+		if err := json.Unmarshal(rec.Body.Bytes(), &recommendation); err != nil {
+			t.Fatal(err)
+		}
+		return recommendation
+	}
+
+	mock.ExpectQuery(rulesQuery).WillReturnRows(sqlmock.NewRows([]string{"teacher_id", "rules"}).AddRow(7, stored))
+	mock.ExpectQuery(sessionsQuery).WithArgs(studentClaims.UserID, rules.MinSeconds, rules.Sessions).
+		WillReturnRows(sqlmock.NewRows([]string{"total_questions"}))
+	if before := recommendation(); before.Sessions != 0 || before.Recommended.Scale != "C" {
+		t.Fatalf("expected no change without sessions, got %+v", before)
+	}
+
+	// twenty right answers, two seconds each
+	answers := strings.TrimSuffix(strings.Repeat(`{"pitch": "C4", "answer": "C", "response_ms": 2000},`, 20), ",")
+	seconds, total, correct, npm := &recorded{}, &recorded{}, &recorded{}, &recorded{}
+	expectNoteAnswers(mock, 20, []string{"C4"}, studentClaims.UserID, seconds, total, correct, npm)
+	if rec := signedInRequest(t, router, studentClaims, http.MethodPost, "/music/note-game/answers", `{"answers": [`+answers+`]}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected the answers recorded, got %d %s", rec.Code, rec.Body)
+	}
+
+	// the entry comes back the way the sessions query reads it
+	mock.ExpectQuery(rulesQuery).WillReturnRows(sqlmock.NewRows([]string{"teacher_id", "rules"}).AddRow(7, stored))
+	mock.ExpectQuery(sessionsQuery).WithArgs(studentClaims.UserID, rules.MinSeconds, rules.Sessions).
+		WillReturnRows(sqlmock.NewRows([]string{"total_questions", "correct_questions", "notes_per_minute", "seconds"}).
+			AddRow(total.value, correct.value, npm.value, int(seconds.value.(float64))))
+	after := recommendation()
+	if after.Sessions != 1 || after.Recommended.Scale != "G" {
+		t.Fatalf("expected the session to move the student on to G, got %+v", after)
+	}
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"math/rand/v2"
+	"sight-reading/music"
+	"sight-reading/repetition"
+	"sight-reading/theory"
+	"testing"
+	"time"
+
+	dtos "sight-reading/DTOs"
+)
+
+var reviewTime = time.Date(2024, 9, 2, 16, 0, 0, 0, time.UTC)
+
+// NOTE: Happy path
+func TestReviewSpacesOutRightAnswers(t *testing.T) {
+	card := repetition.NewCard(reviewTime)
+
+	var intervals []float64
+	for i := 0; i < 4; i++ {
+		card = card.Review(5, card.Due)
+		intervals = append(intervals, card.Interval)
+	}
+	// 1 day, 6 days, then times the ease, which grows by 0.1 every time
+	if intervals[0] != 1 || intervals[1] != 6 || intervals[2] != 16.8 || intervals[3] != 48.72 {
+		t.Fatalf("expected 1, 6, 16.8, 48.72 days, got %v", intervals)
+	}
+	if card.Ease != 2.9 {
+		t.Fatalf("expected the ease to reach 2.9, got %v", card.Ease)
+	}
+	if !card.Due.After(reviewTime.AddDate(0, 2, 0)) {
+		t.Fatalf("expected the note to be due in over two months, got %v", card.Due)
+	}
+}
+
+// NOTE: Sad path
+func TestReviewBringsMissedNotesBack(t *testing.T) {
+	card := repetition.NewCard(reviewTime)
+	card = card.Review(5, reviewTime)
+	card = card.Review(5, card.Due)
+
+	now := card.Due
+	card = card.Review(repetition.Quality(false, time.Second), now)
+	if card.Repetitions != 0 || card.Lapses != 1 || card.Interval != 0 {
+		t.Fatalf("expected a missed note to start over, got %+v", card)
+	}
+	if !card.Due.Equal(now.Add(repetition.Relearn)) {
+		t.Fatalf("expected it due again in the next session, got %v", card.Due)
+	}
+
+	for i := 0; i < 10; i++ {
+		card = card.Review(0, card.Due)
+	}
+	if card.Ease != repetition.MinEase {
+		t.Fatalf("expected the ease to stop at %v, got %v", repetition.MinEase, card.Ease)
+	}
+}
+
+// NOTE: Happy path
+func TestQualityRewardsQuickAnswers(t *testing.T) {
+	cases := []struct {
+		correct  bool
+		response time.Duration
+		want     int
+	}{
+		{true, time.Second, 5},
+		{true, 4 * time.Second, 4},
+		{true, 9 * time.Second, 3},
+		{false, time.Second, 1},
+	}
+	for _, c := range cases {
+		if got := repetition.Quality(c.correct, c.response); got != c.want {
+			t.Errorf("Quality(%v, %v) = %d, want %d", c.correct, c.response, got, c.want)
+		}
+	}
+}
+
+// NOTE: Happy path
+func TestPlanPutsDueNotesFirst(t *testing.T) {
+	notes := []string{"C4", "D4", "E4", "F4", "G4", "A4", "B4"}
+	missed := repetition.NewCard(reviewTime).Review(1, reviewTime.Add(-time.Hour))
+	known := repetition.NewCard(reviewTime).Review(5, reviewTime.Add(-time.Hour))
+	cards := map[string]repetition.Card{"E4": missed, "G4": known}
+
+	plan := repetition.Plan(notes, cards, 6, 2, reviewTime, rand.New(rand.NewPCG(1, 2)))
+	if len(plan) != 6 {
+		t.Fatalf("expected 6 questions, got %d", len(plan))
+	}
+
+	reasons := map[string]int{}
+	asked := map[string]int{}
+	for _, choice := range plan {
+		reasons[choice.Reason]++
+		asked[choice.Note]++
+	}
+	// E4 is due, two new notes, G4 is due tomorrow and E4 fills the rest
+	if asked["E4"] != 3 || asked["C4"] != 1 || asked["D4"] != 1 || asked["G4"] != 1 {
+		t.Fatalf("unexpected questions %v", asked)
+	}
+	if reasons[repetition.Due] != 3 || reasons[repetition.New] != 2 || reasons[repetition.Ahead] != 1 {
+		t.Fatalf("unexpected reasons %v", reasons)
+	}
+}
+
+// NOTE: Sad path
+func TestPlanWithNothingToAsk(t *testing.T) {
+	if plan := repetition.Plan(nil, nil, 10, 5, reviewTime, nil); len(plan) != 0 {
+		t.Fatalf("expected no questions, got %v", plan)
+	}
+	if plan := repetition.Plan([]string{"C4"}, nil, 10, 0, reviewTime, nil); len(plan) != 0 {
+		t.Fatalf("expected no questions when no new notes are allowed, got %v", plan)
+	}
+}
+
+// NOTE: Happy path
+func TestQuestionForAScheduledNote(t *testing.T) {
+	options, err := music.ReadNoteGameRequest(dtos.NoteGameRequest{Scale: "G", Octave: "2", Octaves: 2, Clef: "bass", RangeHigh: "C4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pitch := range options.Candidates {
+		if pitch.MIDI() > 60 {
+			t.Fatalf("%v is above the range", pitch)
+		}
+	}
+
+	pitch, _ := theory.ParsePitch("F3")
+	noteGame, err := music.Question(pitch, options.Fifths, options.Clef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noteGame.NoteName != "F" || noteGame.NoteOctave != "3" {
+		t.Fatalf("expected F3, got %s%s", noteGame.NoteName, noteGame.NoteOctave)
+	}
+
+	altered := music.Altered(options.Candidates[0])
+	if len(altered) == 0 || altered[0].Step != options.Candidates[0].Step {
+		t.Fatalf("expected the same letter raised or lowered, got %v", altered)
+	}
+}
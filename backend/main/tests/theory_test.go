@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"sight-reading/abc"
+	"sight-reading/difficulty"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+	"sight-reading/transpose"
+	"strings"
+	"testing"
+)
+
+// NOTE: Happy path
+func TestMajorScaleSpelling(t *testing.T) {
+	cases := map[string]string{
+		"C4":  "C4 D4 E4 F4 G4 A4 B4",
+		"F4":  "F4 G4 A4 B-4 C5 D5 E5",
+		"B-3": "B-3 C4 D4 E-4 F4 G4 A4",
+		"G-4": "G-4 A-4 B-4 C-5 D-5 E-5 F5",
+		"B4":  "B4 C#5 D#5 E5 F#5 G#5 A#5",
+	}
+
+	for tonic, want := range cases {
+		pitch, err := theory.ParsePitch(tonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, degree := range theory.MajorScale(pitch) {
+			got = append(got, degree.String())
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("%s major: expected %s, got %s", tonic, want, strings.Join(got, " "))
+		}
+	}
+}
+
+func TestKeyFifths(t *testing.T) {
+	cases := map[string]int{"C": 0, "G": 1, "B": 5, "F": -1, "E-": -3, "Gb": -6, "C#": 7}
+	for name, want := range cases {
+		pitch, _ := theory.ParsePitch(name)
+		fifths, err := theory.KeyFifths(pitch)
+		if err != nil || fifths != want {
+			t.Errorf("%s: expected %d fifths, got %d (%v)", name, want, fifths, err)
+		}
+	}
+
+	if theory.KeyAlter(-2, 'E') != -1 || theory.KeyAlter(-2, 'A') != 0 || theory.KeyAlter(3, 'G') != 1 {
+		t.Error("wrong key signature alters")
+	}
+}
+
+func TestLedgerLines(t *testing.T) {
+	cases := []struct {
+		clef  theory.Clef
+		pitch string
+		want  int
+	}{
+		{theory.Treble, "C4", 1},
+		{theory.Treble, "D4", 0},
+		{theory.Treble, "G5", 0},
+		{theory.Treble, "A5", 1},
+		{theory.Treble, "C6", 2},
+		{theory.Bass, "C4", 1},
+		{theory.Bass, "E2", 1},
+		{theory.Alto, "C4", 0},
+	}
+
+	for _, c := range cases {
+		pitch, _ := theory.ParsePitch(c.pitch)
+		if got := c.clef.LedgerLines(pitch); got != c.want {
+			t.Errorf("%s on %s clef: expected %d ledger lines, got %d", c.pitch, c.clef.Sign, c.want, got)
+		}
+	}
+}
+
+// NOTE: Happy path
+func TestKeyName(t *testing.T) {
+	cases := []struct {
+		fifths int
+		mode   string
+		want   string
+	}{
+		{0, "", "C major"},
+		{-3, "major", "E- major"},
+		{4, "minor", "C# minor"},
+		{-1, "minor", "D minor"},
+		{3, "minor", "F# minor"},
+	}
+	for _, c := range cases {
+		if got := theory.KeyName(c.fifths, c.mode); got != c.want {
+			t.Errorf("KeyName(%d, %q) = %s, want %s", c.fifths, c.mode, got, c.want)
+		}
+	}
+}
+
+// NOTE: Sad path
+func TestParsePitchRejectsNonsense(t *testing.T) {
+	for _, name := range []string{"", "H4", "C#x", "C###"} {
+		if _, err := theory.ParsePitch(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+// NOTE: Sad path
+func TestFromMusicXMLRejectsBadSteps(t *testing.T) {
+	for _, step := range []string{"", "H", "CD"} {
+		if _, err := theory.FromMusicXML(musicxml.Pitch{Step: step, Octave: 4}); err == nil {
+			t.Errorf("expected step %q to be rejected", step)
+		}
+	}
+
+	// a score nobody validated is an error, not a panic
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name/></score-part></part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions></attributes>
+      <note><pitch><octave>4</octave></pitch><duration>1</duration></note>
+    </measure>
+  </part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := difficulty.Rate(score); err == nil {
+		t.Error("expected rating to fail")
+	}
+	if _, err := abc.Marshal(score); err == nil {
+		t.Error("expected writing ABC to fail")
+	}
+	if err := transpose.ByInterval(score, theory.Interval{Diatonic: 1, Semitones: 2}); err == nil {
+		t.Error("expected transposing to fail")
+	}
+}
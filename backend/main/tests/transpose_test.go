@@ -0,0 +1,161 @@
+package tests
+
+import (
+	dtos "sight-reading/DTOs"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+	"sight-reading/transpose"
+	"testing"
+)
+
+func fromMusicXML(t *testing.T, pitch *musicxml.Pitch) theory.Pitch {
+	t.Helper()
+	parsed, err := theory.FromMusicXML(*pitch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func writtenPitches(t *testing.T, part *musicxml.Part) []string {
+	t.Helper()
+	var pitches []string
+	for _, timed := range part.Timeline() {
+		if timed.Note.Pitch != nil {
+			pitches = append(pitches, fromMusicXML(t, timed.Note.Pitch).String())
+		}
+	}
+	return pitches
+}
+
+func soundingPitches(part *musicxml.Part) []int {
+	var pitches []int
+	for _, timed := range part.Timeline() {
+		if midi, ok := timed.Sounding(); ok {
+			pitches = append(pitches, midi)
+		}
+	}
+	return pitches
+}
+
+// NOTE: Happy path
+func TestParseInterval(t *testing.T) {
+	cases := map[string]theory.Interval{
+		"P1":  {Diatonic: 0, Semitones: 0},
+		"M2":  {Diatonic: 1, Semitones: 2},
+		"m3":  {Diatonic: 2, Semitones: 3},
+		"A4":  {Diatonic: 3, Semitones: 6},
+		"d5":  {Diatonic: 4, Semitones: 6},
+		"-P5": {Diatonic: -4, Semitones: -7},
+		"M9":  {Diatonic: 8, Semitones: 14},
+		"-M6": {Diatonic: -5, Semitones: -9},
+	}
+	for name, want := range cases {
+		got, err := theory.ParseInterval(name)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %+v, got %+v (%v)", name, want, got, err)
+		}
+	}
+}
+
+func TestTransposeForClarinetAndAltoSax(t *testing.T) {
+	cases := []struct {
+		instrument dtos.Instrument
+		fifths     int
+		first      string
+		transpose  musicxml.Transpose
+	}{
+		{dtos.Instrument{Slug: "bb-clarinet", Transpose: "-M2", Clef: "treble"}, 2, "F#4", musicxml.Transpose{Diatonic: -1, Chromatic: -2}},
+		{dtos.Instrument{Slug: "eb-alto-sax", Transpose: "-M6", Clef: "treble"}, 3, "C#5", musicxml.Transpose{Diatonic: -5, Chromatic: -9}},
+		{dtos.Instrument{Slug: "f-horn", Transpose: "-P5", Clef: "treble"}, 1, "B4", musicxml.Transpose{Diatonic: -4, Chromatic: -7}},
+	}
+
+	for _, c := range cases {
+		score, err := musicxml.ParseFile("testdata/mary.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		concert := soundingPitches(&score.Parts[0])
+
+		if err := transpose.ToInstrument(score, c.instrument); err != nil {
+			t.Fatal(err)
+		}
+
+		part := &score.Parts[0]
+		attributes := part.Measures[0].Attributes()
+		if attributes.Keys[0].Fifths != c.fifths || attributes.Transpose[0] != c.transpose {
+			t.Errorf("%s: expected %d fifths and %+v, got %+v", c.instrument.Slug, c.fifths, c.transpose, attributes)
+		}
+		if written := writtenPitches(t, part); written[0] != c.first {
+			t.Errorf("%s: expected the first note written as %s, got %s", c.instrument.Slug, c.first, written[0])
+		}
+
+		sounding := soundingPitches(part)
+		for i := range concert {
+			if sounding[i] != concert[i] {
+				t.Fatalf("%s: note %d sounds %d instead of %d", c.instrument.Slug, i, sounding[i], concert[i])
+			}
+		}
+
+		// and back to concert pitch
+		concertPitch := dtos.Instrument{Slug: "concert", Transpose: "P1"}
+		if err := transpose.ToInstrument(score, concertPitch); err != nil {
+			t.Fatal(err)
+		}
+		if written := writtenPitches(t, part); written[0] != "E4" || len(part.Measures[0].Attributes().Transpose) != 0 {
+			t.Errorf("%s: expected E4 back at concert pitch, got %s", c.instrument.Slug, written[0])
+		}
+	}
+}
+
+func TestTransposeRespellsRemoteKeys(t *testing.T) {
+	score, err := musicxml.ParseBytes([]byte(`<score-partwise version="3.1">
+  <part-list><score-part id="P1"><part-name/></score-part></part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions><key><fifths>6</fifths></key></attributes>
+      <note><pitch><step>F</step><alter>1</alter><octave>4</octave></pitch><duration>1</duration></note>
+      <note><pitch><step>E</step><octave>4</octave></pitch><duration>1</duration><accidental>natural</accidental></note>
+    </measure>
+  </part>
+</score-partwise>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	majorSecond, _ := theory.ParseInterval("M2")
+	if err := transpose.ByInterval(score, majorSecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// F# major up a major second is G# major, eight sharps, so A flat
+	measure := score.Parts[0].Measures[0]
+	notes := measure.Notes()
+	if fifths := measure.Attributes().Keys[0].Fifths; fifths != -4 {
+		t.Fatalf("expected A flat major, got %d fifths", fifths)
+	}
+	if got := fromMusicXML(t, notes[0].Pitch).String(); got != "A-4" || notes[0].Accidental != "" {
+		t.Errorf("expected A-4 without an accidental, got %s %q", got, notes[0].Accidental)
+	}
+	if got := fromMusicXML(t, notes[1].Pitch).String(); got != "G-4" || notes[1].Accidental != "flat" {
+		t.Errorf("expected G-4 with a flat, got %s %q", got, notes[1].Accidental)
+	}
+}
+
+// NOTE: Sad path
+func TestSadIntervalsAndInstruments(t *testing.T) {
+	for _, name := range []string{"", "M5", "P3", "X2", "M0", "M"} {
+		if _, err := theory.ParseInterval(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+
+	score, err := musicxml.ParseFile("testdata/mary.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kazoo := dtos.Instrument{Slug: "kazoo", Transpose: "up a bit"}
+	if err := transpose.ToInstrument(score, kazoo); err == nil {
+		t.Error("expected a bad transposition to be rejected")
+	}
+}
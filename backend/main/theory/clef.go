@@ -0,0 +1,68 @@
+package theory
+
+import (
+	"fmt"
+	"strings"
+
+	"sight-reading/musicxml"
+)
+
+// Clef places a reference pitch on a staff line, lines count from the bottom
+type Clef struct {
+	Sign string
+	Line int
+}
+
+var (
+	Treble = Clef{Sign: "G", Line: 2}
+	Bass   = Clef{Sign: "F", Line: 4}
+	Alto   = Clef{Sign: "C", Line: 3}
+	Tenor  = Clef{Sign: "C", Line: 4}
+)
+
+func ParseClef(name string) (Clef, error) {
+	switch strings.ToLower(name) {
+	case "", "treble":
+		return Treble, nil
+	case "bass":
+		return Bass, nil
+	case "alto":
+		return Alto, nil
+	case "tenor":
+		return Tenor, nil
+	}
+	return Clef{}, fmt.Errorf("unknown clef %q, expected treble, bass, alto or tenor", name)
+}
+
+func (clef Clef) reference() Pitch {
+	switch clef.Sign {
+	case "F":
+		return Pitch{Step: 'F', Octave: 3}
+	case "C":
+		return Pitch{Step: 'C', Octave: 4}
+	}
+	return Pitch{Step: 'G', Octave: 4}
+}
+
+// StaffPosition is how many lines and spaces pitch is above the bottom line,
+// 0 to 8 is on the staff
+func (clef Clef) StaffPosition(pitch Pitch) int {
+	bottom := clef.reference().Diatonic() - 2*(clef.Line-1)
+	return pitch.Diatonic() - bottom
+}
+
+// LedgerLines is how many ledger lines pitch needs on this clef
+func (clef Clef) LedgerLines(pitch Pitch) int {
+	position := clef.StaffPosition(pitch)
+	switch {
+	case position > 8:
+		return (position - 8) / 2
+	case position < 0:
+		return -position / 2
+	}
+	return 0
+}
+
+func (clef Clef) MusicXML() musicxml.Clef {
+	return musicxml.Clef{Sign: clef.Sign, Line: clef.Line}
+}
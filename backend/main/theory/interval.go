@@ -0,0 +1,77 @@
+package theory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Interval counts both letter names (Diatonic, a third is 2) and half steps,
+// so transposing by it spells the result. Negative intervals go down
+type Interval struct {
+	Diatonic  int
+	Semitones int
+}
+
+var Unison = Interval{}
+
+// ParseInterval reads quality and number, "M2", "P5", "m3", "A4", "d5",
+// compound ones like "M9" and a leading "-" to go down, "-M2"
+func ParseInterval(name string) (Interval, error) {
+	down := strings.HasPrefix(name, "-")
+	text := strings.TrimPrefix(name, "-")
+	if len(text) < 2 {
+		return Interval{}, fmt.Errorf("invalid interval %q, expected a quality and a number like M2", name)
+	}
+
+	quality := text[0]
+	number, err := strconv.Atoi(text[1:])
+	if err != nil || number < 1 || number > 22 {
+		return Interval{}, fmt.Errorf("invalid interval %q, the number must be from 1 to 22", name)
+	}
+
+	simple := (number - 1) % 7
+	octaves := (number - 1) / 7
+	semitones := majorSteps[simple] + 12*octaves
+
+	perfect := simple == 0 || simple == 3 || simple == 4
+	switch {
+	case quality == 'P' && perfect, quality == 'M' && !perfect:
+	case quality == 'm' && !perfect:
+		semitones--
+	case quality == 'A':
+		semitones++
+	case quality == 'd' && perfect:
+		semitones--
+	case quality == 'd':
+		semitones -= 2
+	default:
+		return Interval{}, fmt.Errorf("invalid interval %q, %c%d does not exist", name, quality, simple+1)
+	}
+
+	interval := Interval{Diatonic: number - 1, Semitones: semitones}
+	if down {
+		interval = interval.Negate()
+	}
+	return interval, nil
+}
+
+func (interval Interval) Negate() Interval {
+	return Interval{Diatonic: -interval.Diatonic, Semitones: -interval.Semitones}
+}
+
+func (interval Interval) Sub(other Interval) Interval {
+	return Interval{Diatonic: interval.Diatonic - other.Diatonic, Semitones: interval.Semitones - other.Semitones}
+}
+
+// Fifths is how far the interval moves a key around the circle of fifths,
+// a major second up adds two sharps
+func (interval Interval) Fifths() int {
+	c := Pitch{Step: 'C', Octave: 4}
+	moved := c.Transpose(interval.Diatonic, interval.Semitones)
+	return map[byte]int{'C': 0, 'G': 1, 'D': 2, 'A': 3, 'E': 4, 'B': 5, 'F': -1}[moved.Step] + 7*moved.Alter
+}
+
+func (pitch Pitch) TransposeBy(interval Interval) Pitch {
+	return pitch.Transpose(interval.Diatonic, interval.Semitones)
+}
@@ -0,0 +1,139 @@
+// Package theory is the bit of music theory the backend needs to spell,
+// generate and check exercises without the python service
+package theory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sight-reading/musicxml"
+)
+
+const steps = "CDEFGAB"
+
+var stepSemitones = [7]int{0, 2, 4, 5, 7, 9, 11}
+
+// Pitch is a spelled pitch, F#4 and Gb4 are different pitches with the same
+// MIDI number. Alter is in half steps
+type Pitch struct {
+	Step   byte
+	Alter  int
+	Octave int
+}
+
+// ParsePitch reads the names the frontend and music21 use: "C", "F#4",
+// "B-3". A missing octave is 4. "b" is also accepted as a flat, "Bb4"
+func ParsePitch(name string) (Pitch, error) {
+	if name == "" {
+		return Pitch{}, fmt.Errorf("empty pitch name")
+	}
+
+	pitch := Pitch{Step: name[0] &^ 0x20, Octave: 4}
+	if strings.IndexByte(steps, pitch.Step) < 0 {
+		return Pitch{}, fmt.Errorf("invalid pitch %q, %c is not a note name", name, name[0])
+	}
+
+	rest := name[1:]
+accidentals:
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '#':
+			pitch.Alter++
+		case '-', 'b':
+			pitch.Alter--
+		default:
+			break accidentals
+		}
+		rest = rest[1:]
+	}
+
+	if rest != "" {
+		octave, err := strconv.Atoi(rest)
+		if err != nil {
+			return Pitch{}, fmt.Errorf("invalid pitch %q, %q is not an octave", name, rest)
+		}
+		pitch.Octave = octave
+	}
+	if pitch.Alter < -2 || pitch.Alter > 2 {
+		return Pitch{}, fmt.Errorf("invalid pitch %q, at most a double sharp or flat", name)
+	}
+	return pitch, nil
+}
+
+func (pitch Pitch) stepIndex() int {
+	return strings.IndexByte(steps, pitch.Step)
+}
+
+// Name is the music21 name without the octave, "B-", "F#", the same names
+// the note game answers with
+func (pitch Pitch) Name() string {
+	accidental := ""
+	if pitch.Alter > 0 {
+		accidental = strings.Repeat("#", pitch.Alter)
+	} else if pitch.Alter < 0 {
+		accidental = strings.Repeat("-", -pitch.Alter)
+	}
+	return string(pitch.Step) + accidental
+}
+
+func (pitch Pitch) String() string {
+	return pitch.Name() + strconv.Itoa(pitch.Octave)
+}
+
+// MIDI is the midi note number, C4 is 60
+func (pitch Pitch) MIDI() int {
+	return (pitch.Octave+1)*12 + stepSemitones[pitch.stepIndex()] + pitch.Alter
+}
+
+// Diatonic counts letter names from C0, it is the vertical position on a
+// staff regardless of accidentals
+func (pitch Pitch) Diatonic() int {
+	return pitch.Octave*7 + pitch.stepIndex()
+}
+
+// Transpose moves the pitch by a number of letter names and half steps, a
+// major third up is Transpose(2, 4). The letter decides the spelling, the
+// half steps decide the alter
+func (pitch Pitch) Transpose(diatonic, semitones int) Pitch {
+	target := pitch.Diatonic() + diatonic
+	transposed := Pitch{Step: steps[mod(target, 7)], Octave: floorDiv(target, 7)}
+	transposed.Alter = pitch.MIDI() + semitones - transposed.MIDI()
+	return transposed
+}
+
+func (pitch Pitch) MusicXML() *musicxml.Pitch {
+	return &musicxml.Pitch{Step: string(pitch.Step), Alter: float64(pitch.Alter), Octave: pitch.Octave}
+}
+
+// FromMusicXML reads a MusicXML pitch, its step has to be a letter name even
+// when the score was never validated
+func FromMusicXML(pitch musicxml.Pitch) (Pitch, error) {
+	if len(pitch.Step) != 1 || strings.IndexByte(steps, pitch.Step[0]) < 0 {
+		return Pitch{}, fmt.Errorf("invalid step %q", pitch.Step)
+	}
+	return Pitch{Step: pitch.Step[0], Alter: int(pitch.Alter), Octave: pitch.Octave}, nil
+}
+
+// FromMIDI spells a midi note number with sharps, a keyboard does not say
+// which spelling was meant
+func FromMIDI(midi int) Pitch {
+	pitch := Pitch{Octave: floorDiv(midi, 12) - 1}
+	semitone := mod(midi, 12)
+	for i := len(stepSemitones) - 1; i >= 0; i-- {
+		if stepSemitones[i] <= semitone {
+			pitch.Step = steps[i]
+			pitch.Alter = semitone - stepSemitones[i]
+			break
+		}
+	}
+	return pitch
+}
+
+func mod(a, b int) int {
+	return ((a % b) + b) % b
+}
+
+func floorDiv(a, b int) int {
+	return (a - mod(a, b)) / b
+}
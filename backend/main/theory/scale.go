@@ -0,0 +1,58 @@
+package theory
+
+import "fmt"
+
+var majorSteps = [7]int{0, 2, 4, 5, 7, 9, 11}
+
+// MajorScale is the seven degrees of the major scale up from tonic, spelled
+// with one of each letter
+func MajorScale(tonic Pitch) []Pitch {
+	scale := make([]Pitch, 7)
+	for degree := range scale {
+		scale[degree] = tonic.Transpose(degree, majorSteps[degree])
+	}
+	return scale
+}
+
+// KeyFifths is the key signature of the major key on tonic, sharps are
+// positive and flats negative. Keys past seven, like G#, have none
+func KeyFifths(tonic Pitch) (int, error) {
+	// each fifth up adds a sharp, C is 0, the alter moves 7 fifths
+	fifths := map[byte]int{'C': 0, 'G': 1, 'D': 2, 'A': 3, 'E': 4, 'B': 5, 'F': -1}[tonic.Step] + 7*tonic.Alter
+	if fifths < -7 || fifths > 7 {
+		return 0, fmt.Errorf("%s major has no key signature", tonic.Name())
+	}
+	return fifths, nil
+}
+
+// KeyAlter is the alter the key signature gives step
+func KeyAlter(fifths int, step byte) int {
+	const sharpOrder, flatOrder = "FCGDAEB", "BEADGCF"
+
+	if fifths > 0 {
+		for i := 0; i < fifths; i++ {
+			if sharpOrder[i] == step {
+				return 1
+			}
+		}
+	}
+	for i := 0; i < -fifths; i++ {
+		if flatOrder[i] == step {
+			return -1
+		}
+	}
+	return 0
+}
+
+// KeyName names a key signature the way the rest of the api spells pitches,
+// "E- major", "C# minor"
+func KeyName(fifths int, mode string) string {
+	c := Pitch{Step: 'C', Octave: 4}
+	tonic := c.Transpose(4*fifths, 7*fifths)
+	if mode == "minor" {
+		tonic = tonic.Transpose(-2, -3)
+	} else {
+		mode = "major"
+	}
+	return tonic.Name() + " " + mode
+}
@@ -0,0 +1,238 @@
+// Package transpose rewrites MusicXML into another key or for a transposing
+// instrument. Pitches are moved by intervals, not half steps, so a B flat
+// clarinet part of a piece in E flat comes out in F, not E sharp
+package transpose
+
+import (
+	"fmt"
+	"sight-reading/musicxml"
+	"sight-reading/theory"
+
+	dtos "sight-reading/DTOs"
+)
+
+// ByInterval moves every written pitch and key signature of score by
+// interval, leaving the parts' transpositions alone
+func ByInterval(score *musicxml.Score, interval theory.Interval) error {
+	for i := range score.Parts {
+		if err := part(&score.Parts[i], interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToInstrument rewrites score, read as written for the instruments its parts
+// already declare (concert pitch when they declare none), so it is written
+// for instrument. The sounding pitches do not change
+func ToInstrument(score *musicxml.Score, instrument dtos.Instrument) error {
+	target, err := theory.ParseInterval(instrument.Transpose)
+	if err != nil {
+		return fmt.Errorf("instrument %s: %w", instrument.Slug, err)
+	}
+
+	for i := range score.Parts {
+		p := &score.Parts[i]
+
+		// written = sounding - target, and sounding = written + current
+		current := currentTransposition(p)
+		if err := part(p, current.Sub(target)); err != nil {
+			return err
+		}
+		setTransposition(p, target)
+		setClef(p, instrument.Clef)
+	}
+
+	for i := range score.PartList.ScoreParts {
+		scorePart := &score.PartList.ScoreParts[i]
+		scorePart.Name = instrument.Name
+		if scorePart.Midi != nil {
+			scorePart.Midi.Program = instrument.Program + 1
+		}
+	}
+	return nil
+}
+
+// part transposes one part, respelling the interval enharmonically when the
+// keys it lands in would be too far round the circle of fifths
+func part(p *musicxml.Part, interval theory.Interval) error {
+	interval = spellInterval(p, interval)
+	shift := interval.Fifths()
+
+	// no key signature means C, which is only right as long as it stays C
+	if !hasKey(p) && shift != 0 && len(p.Measures) > 0 {
+		firstAttributes(p).Keys = []musicxml.Key{{}}
+	}
+
+	fifths := 0
+	for m := range p.Measures {
+		measure := &p.Measures[m]
+
+		// the alters in effect, by letter and octave, accidentals last until
+		// the end of the measure
+		inEffect := map[int]int{}
+
+		for _, element := range measure.Elements {
+			switch element := element.(type) {
+			case *musicxml.Attributes:
+				for k := range element.Keys {
+					element.Keys[k].Fifths += shift
+					fifths = element.Keys[k].Fifths
+				}
+			case *musicxml.Note:
+				if element.Pitch == nil {
+					continue
+				}
+
+				written, err := theory.FromMusicXML(*element.Pitch)
+				if err != nil {
+					return fmt.Errorf("part %s measure %s: %w", p.ID, measure.Number, err)
+				}
+				pitch := simplify(written.TransposeBy(interval))
+				*element.Pitch = *pitch.MusicXML()
+
+				expected, ok := inEffect[pitch.Diatonic()]
+				if !ok {
+					expected = theory.KeyAlter(fifths, pitch.Step)
+				}
+				// courtesy accidentals stay courtesy accidentals
+				if pitch.Alter != expected || element.Accidental != "" {
+					element.Accidental = accidentalNames[pitch.Alter]
+				}
+				inEffect[pitch.Diatonic()] = pitch.Alter
+			}
+		}
+	}
+	return nil
+}
+
+var accidentalNames = map[int]string{-2: "flat-flat", -1: "flat", 0: "natural", 1: "sharp", 2: "double-sharp"}
+
+// spellInterval keeps interval unless it takes the part past six sharps or
+// flats and the enharmonic interval (a diminished third for a major second)
+// lands on fewer
+func spellInterval(p *musicxml.Part, interval theory.Interval) theory.Interval {
+	keys := []int{}
+	for m := range p.Measures {
+		if attributes := p.Measures[m].Attributes(); attributes != nil {
+			for _, key := range attributes.Keys {
+				keys = append(keys, key.Fifths)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		keys = append(keys, 0)
+	}
+
+	furthest := func(candidate theory.Interval) int {
+		most := 0
+		for _, fifths := range keys {
+			most = max(most, abs(fifths+candidate.Fifths()))
+		}
+		return most
+	}
+
+	best := interval
+	if furthest(best) <= 6 {
+		return best
+	}
+	for _, candidate := range []theory.Interval{
+		{Diatonic: interval.Diatonic + 1, Semitones: interval.Semitones},
+		{Diatonic: interval.Diatonic - 1, Semitones: interval.Semitones},
+	} {
+		if furthest(candidate) < furthest(best) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// simplify respells pitches beyond a double sharp or flat on the next letter
+func simplify(pitch theory.Pitch) theory.Pitch {
+	for pitch.Alter > 2 {
+		pitch = pitch.Transpose(1, 0)
+	}
+	for pitch.Alter < -2 {
+		pitch = pitch.Transpose(-1, 0)
+	}
+	return pitch
+}
+
+func currentTransposition(p *musicxml.Part) theory.Interval {
+	for m := range p.Measures {
+		if attributes := p.Measures[m].Attributes(); attributes != nil && len(attributes.Transpose) > 0 {
+			transpose := attributes.Transpose[0]
+			return theory.Interval{
+				Diatonic:  transpose.Diatonic + 7*transpose.OctaveChange,
+				Semitones: transpose.Semitones(),
+			}
+		}
+	}
+	return theory.Unison
+}
+
+// setTransposition replaces every transpose element of the part with one for
+// interval in the first attributes, none at all for concert pitch
+func setTransposition(p *musicxml.Part, interval theory.Interval) {
+	for m := range p.Measures {
+		if attributes := p.Measures[m].Attributes(); attributes != nil {
+			attributes.Transpose = nil
+		}
+	}
+	if interval == theory.Unison || len(p.Measures) == 0 {
+		return
+	}
+
+	// MusicXML keeps octaves out of diatonic and chromatic
+	octaves := interval.Diatonic / 7
+	transpose := musicxml.Transpose{
+		Diatonic:     interval.Diatonic - 7*octaves,
+		Chromatic:    interval.Semitones - 12*octaves,
+		OctaveChange: octaves,
+	}
+
+	firstAttributes(p).Transpose = []musicxml.Transpose{transpose}
+}
+
+// setClef puts the instrument's clef at the start of single staff parts,
+// grand staff parts keep theirs
+func setClef(p *musicxml.Part, name string) {
+	clef, err := theory.ParseClef(name)
+	if err != nil || name == "" || len(p.Measures) == 0 {
+		return
+	}
+
+	attributes := firstAttributes(p)
+	if attributes.Staves > 1 || len(attributes.Clefs) > 1 {
+		return
+	}
+	attributes.Clefs = []musicxml.Clef{clef.MusicXML()}
+}
+
+func hasKey(p *musicxml.Part) bool {
+	for m := range p.Measures {
+		if attributes := p.Measures[m].Attributes(); attributes != nil && len(attributes.Keys) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// firstAttributes is the attributes of the first measure, added when there
+// are none
+func firstAttributes(p *musicxml.Part) *musicxml.Attributes {
+	first := &p.Measures[0]
+	attributes := first.Attributes()
+	if attributes == nil {
+		attributes = &musicxml.Attributes{}
+		first.Elements = append([]musicxml.Element{attributes}, first.Elements...)
+	}
+	return attributes
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
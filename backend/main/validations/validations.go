@@ -29,7 +29,7 @@ func VarChar255Length(fl validator.FieldLevel) bool {
 // youtube custom validation
 func UserRole(fl validator.FieldLevel) bool {
 	switch fl.Field().String() {
-	case "TEACHER", "STUDENT", "ADMIN":
+	case "TEACHER", "STUDENT", "PARENT", "ADMIN":
 		return true
 	}
 